@@ -0,0 +1,211 @@
+package pq_types
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// JSONCompression identifies compression codec of CompressedJSON.
+// Its value is stored in the header of compressed data.
+type JSONCompression byte
+
+// JSONGzip is gzip compression, the only one built in. Package has no dependencies except lib/pq,
+// so other codecs should be registered with RegisterJSONCompression, for example:
+//
+//	pq_types.RegisterJSONCompression('z',
+//		func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+//		func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+//	)
+const JSONGzip JSONCompression = 'g'
+
+// DefaultCompressedJSONMaxSize is used by CompressedJSON.Scan if MaxSize is zero.
+const DefaultCompressedJSONMaxSize = 64 << 20
+
+// String implements fmt.Stringer for better output and logging.
+func (c JSONCompression) String() string {
+	switch c {
+	case JSONGzip:
+		return "gzip"
+	default:
+		return fmt.Sprintf("JSONCompression(%#02x)", byte(c))
+	}
+}
+
+type jsonCodec struct {
+	compress   func(w io.Writer) (io.WriteCloser, error)
+	decompress func(r io.Reader) (io.Reader, error)
+}
+
+var (
+	jsonCodecsM sync.RWMutex
+	jsonCodecs  = map[JSONCompression]jsonCodec{
+		JSONGzip: {
+			compress:   func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+			decompress: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+	}
+)
+
+// RegisterJSONCompression registers compression codec c for CompressedJSON.
+// Returned io.WriteCloser is closed by CompressedJSON.Value, returned io.Reader is closed by CompressedJSON.Scan
+// if it implements io.Closer.
+func RegisterJSONCompression(c JSONCompression, compress func(w io.Writer) (io.WriteCloser, error), decompress func(r io.Reader) (io.Reader, error)) {
+	jsonCodecsM.Lock()
+	jsonCodecs[c] = jsonCodec{compress: compress, decompress: decompress}
+	jsonCodecsM.Unlock()
+}
+
+func getJSONCodec(c JSONCompression) (jsonCodec, bool) {
+	jsonCodecsM.RLock()
+	codec, ok := jsonCodecs[c]
+	jsonCodecsM.RUnlock()
+	return codec, ok
+}
+
+// compressedJSONMagic starts header of compressed data. It is followed by JSONCompression byte.
+// JSON text can't start with zero byte, so uncompressed data is recognized too.
+const compressedJSONMagic = "\x00PQJ"
+
+// CompressedJSON is a JSONText which is stored compressed, compatible with PostgreSQL's bytea.
+// Compressed data starts with a small header identifying the codec. Data without that header
+// is treated as uncompressed JSON, so existing rows keep working.
+type CompressedJSON struct {
+	JSONText JSONText
+
+	// Compression is a codec used by Value. Zero value means JSONGzip.
+	Compression JSONCompression
+
+	// JSON shorter than MinSize bytes is stored uncompressed by Value.
+	MinSize int
+
+	// Scan returns an error if decompressed JSON is longer than MaxSize bytes,
+	// so small value can't expand to exhaust memory. Zero value means DefaultCompressedJSONMaxSize.
+	MaxSize int
+}
+
+// String implements fmt.Stringer for better output and logging.
+func (j CompressedJSON) String() string {
+	return j.JSONText.String()
+}
+
+// MarshalJSON returns j.JSONText as the JSON encoding of j.
+func (j CompressedJSON) MarshalJSON() ([]byte, error) {
+	return j.JSONText.MarshalJSON()
+}
+
+// UnmarshalJSON sets j.JSONText to a copy of data.
+func (j *CompressedJSON) UnmarshalJSON(data []byte) error {
+	if j == nil {
+		return errors.New("CompressedJSON.UnmarshalJSON: on nil pointer")
+	}
+	return j.JSONText.UnmarshalJSON(data)
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It validates j.JSONText like JSONText.Value does and returns it compressed with j.Compression.
+func (j CompressedJSON) Value() (driver.Value, error) {
+	v, err := j.JSONText.Value()
+	if v == nil || err != nil {
+		return v, err
+	}
+	if len(j.JSONText) < j.MinSize {
+		return v, nil
+	}
+
+	c := j.Compression
+	if c == 0 {
+		c = JSONGzip
+	}
+	codec, ok := getJSONCodec(c)
+	if !ok {
+		return nil, fmt.Errorf("CompressedJSON.Value: %s compression is not registered", c)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(compressedJSONMagic)
+	buf.WriteByte(byte(c))
+	w, err := codec.compress(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("CompressedJSON.Value: %s", err)
+	}
+	if _, err = w.Write(j.JSONText); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("CompressedJSON.Value: %s", err)
+	}
+	if err = w.Close(); err != nil {
+		return nil, fmt.Errorf("CompressedJSON.Value: %s", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It decompresses value and stores it in j.JSONText. j.Compression, j.MinSize and j.MaxSize are not changed.
+// jsonb binary format version byte is stripped. No validation is done.
+func (j *CompressedJSON) Scan(value interface{}) error {
+	if value == nil {
+		j.JSONText = nil
+		return nil
+	}
+
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("CompressedJSON.Scan: expected []byte or string, got %T (%q)", value, value)
+	}
+
+	if !bytes.HasPrefix(b, []byte(compressedJSONMagic)) {
+		return j.JSONText.Scan(b)
+	}
+
+	if len(b) == len(compressedJSONMagic) {
+		return fmt.Errorf("CompressedJSON.Scan: truncated header")
+	}
+	c := JSONCompression(b[len(compressedJSONMagic)])
+	codec, ok := getJSONCodec(c)
+	if !ok {
+		return fmt.Errorf("CompressedJSON.Scan: %s compression is not registered", c)
+	}
+
+	r, err := codec.decompress(bytes.NewReader(b[len(compressedJSONMagic)+1:]))
+	if err != nil {
+		return fmt.Errorf("CompressedJSON.Scan: %s", err)
+	}
+	if closer, ok := r.(io.Closer); ok {
+		defer closer.Close()
+	}
+	maxSize := j.MaxSize
+	if maxSize == 0 {
+		maxSize = DefaultCompressedJSONMaxSize
+	}
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return fmt.Errorf("CompressedJSON.Scan: %s", err)
+	}
+	if len(data) > maxSize {
+		return fmt.Errorf("CompressedJSON.Scan: decompressed data is longer than %d bytes", maxSize)
+	}
+
+	j.JSONText = JSONText(trimJSONBVersion(data))
+	return nil
+}
+
+// check interfaces
+var (
+	_ fmt.Stringer     = JSONCompression(0)
+	_ json.Marshaler   = CompressedJSON{}
+	_ json.Unmarshaler = &CompressedJSON{}
+	_ driver.Valuer    = CompressedJSON{}
+	_ sql.Scanner      = &CompressedJSON{}
+	_ fmt.Stringer     = CompressedJSON{}
+)
@@ -0,0 +1,110 @@
+package pq_types
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (s *TypesSuite) TestCompressedJSONScanValue(c *C) {
+	large := JSONText(`[` + strings.Repeat(`{"foo": "bar"},`, 1000) + `{}]`)
+
+	type testData struct {
+		j      CompressedJSON
+		header []byte
+	}
+	for _, d := range []testData{
+		{CompressedJSON{JSONText: large}, []byte("\x00PQJg")},
+		{CompressedJSON{JSONText: large, Compression: JSONGzip, MinSize: len(large)}, []byte("\x00PQJg")},
+		{CompressedJSON{JSONText: large, MinSize: len(large) + 1}, []byte("[{")},
+		{CompressedJSON{JSONText: JSONText(`{}`), MinSize: 1024}, []byte("{}")},
+	} {
+		v, err := d.j.Value()
+		c.Assert(err, IsNil)
+		b := v.([]byte)
+		c.Check(bytes.HasPrefix(b, d.header), Equals, true, Commentf("%q", b[:len(d.header)]))
+		if d.header[0] == 0 {
+			c.Check(len(b) < len(large)/10, Equals, true, Commentf("%d", len(b)))
+		}
+
+		j := CompressedJSON{JSONText: JSONText(`{"foo": "bar"}`), Compression: 'i', MinSize: 10, MaxSize: len(large)}
+		c.Check(j.Scan(b), IsNil)
+		c.Check(j, DeepEquals, CompressedJSON{JSONText: d.j.JSONText, Compression: 'i', MinSize: 10, MaxSize: len(large)})
+	}
+
+	// uncompressed data
+	var j CompressedJSON
+	c.Check(j.Scan(`{"foo": "bar"}`), IsNil)
+	c.Check(j.JSONText, DeepEquals, JSONText(`{"foo": "bar"}`))
+	c.Check(j.Scan(nil), IsNil)
+	c.Check(j.JSONText, IsNil)
+
+	v, err := j.Value()
+	c.Check(err, IsNil)
+	c.Check(v, IsNil)
+
+	// invalid data
+	_, err = CompressedJSON{JSONText: JSONText(`{`)}.Value()
+	c.Check(err, ErrorMatches, `unexpected end of JSON input`)
+	_, err = CompressedJSON{JSONText: JSONText(`{}`), Compression: 'x'}.Value()
+	c.Check(err, ErrorMatches, `CompressedJSON.Value: JSONCompression\(0x78\) compression is not registered`)
+	c.Check(j.Scan([]byte("\x00PQJ")), ErrorMatches, `CompressedJSON.Scan: truncated header`)
+	c.Check(j.Scan([]byte("\x00PQJgfoo")), ErrorMatches, `CompressedJSON.Scan: unexpected EOF`)
+	c.Check(j.Scan([]byte("\x00PQJyfoo")), ErrorMatches, `CompressedJSON.Scan: JSONCompression\(0x79\) compression is not registered`)
+
+	// decompressed size limit
+	v, err = CompressedJSON{JSONText: large}.Value()
+	c.Assert(err, IsNil)
+	limited := CompressedJSON{MaxSize: len(large) - 1}
+	c.Check(limited.Scan(v), ErrorMatches, fmt.Sprintf(`CompressedJSON.Scan: decompressed data is longer than %d bytes`, len(large)-1))
+	c.Check(limited.JSONText, IsNil)
+
+	// custom codec
+	RegisterJSONCompression('i',
+		func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil },
+		func(r io.Reader) (io.Reader, error) { return r, nil },
+	)
+	v, err = CompressedJSON{JSONText: JSONText(`{}`), Compression: 'i'}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte("\x00PQJi{}"))
+	c.Check(j.Scan(v), IsNil)
+	c.Check(j.JSONText, DeepEquals, JSONText(`{}`))
+}
+
+func (s *TypesSuite) TestCompressedJSON(c *C) {
+	for _, j := range []CompressedJSON{
+		{},
+		{JSONText: JSONText(`{"foo": "bar"}`)},
+		{JSONText: JSONText(`[` + strings.Repeat(`"foo",`, 10000) + `"bar"]`)},
+		{JSONText: JSONText(`{"foo": "bar"}`), MinSize: 100},
+	} {
+		s.SetUpTest(c)
+
+		_, err := s.db.Exec("INSERT INTO pq_types (compressed_json) VALUES($1)", j)
+		c.Assert(err, IsNil)
+
+		j1 := CompressedJSON{JSONText: JSONText(`{}`), MinSize: j.MinSize}
+		err = s.db.QueryRow("SELECT compressed_json FROM pq_types").Scan(&j1)
+		c.Check(err, IsNil)
+		c.Check(j1, DeepEquals, j)
+	}
+
+	// existing uncompressed rows
+	s.SetUpTest(c)
+	_, err := s.db.Exec("INSERT INTO pq_types (compressed_json) VALUES(convert_to($1, 'UTF8'))", `{"foo": "bar"}`)
+	c.Assert(err, IsNil)
+
+	var j1 CompressedJSON
+	err = s.db.QueryRow("SELECT compressed_json FROM pq_types").Scan(&j1)
+	c.Check(err, IsNil)
+	c.Check(j1.JSONText, DeepEquals, JSONText(`{"foo": "bar"}`))
+}
@@ -0,0 +1,820 @@
+package pq_types
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSchema is a compiled JSON Schema. It supports a subset of draft 2020-12:
+// boolean schemas, type, enum, const, numeric, string, array and object validation keywords,
+// allOf, anyOf, oneOf, not, if/then/else, dependentRequired, dependentSchemas,
+// and $ref to local JSON pointers (including $defs).
+// Other keywords, including format, are ignored as annotations.
+type JSONSchema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// CompileJSONSchema parses and checks schema.
+// Recursive references are allowed only through array items or object properties:
+// reference cycles applied to the same value, like {"$ref": "#"}, are rejected.
+func CompileJSONSchema(schema JSONText) (*JSONSchema, error) {
+	root, err := decodeJSONB(schema, "CompileJSONSchema")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &JSONSchema{
+		root:     root,
+		patterns: make(map[string]*regexp.Regexp),
+	}
+	inPlace := make(map[string][]string)
+	if err = s.compile(root, "", make(map[string]bool), inPlace); err != nil {
+		return nil, err
+	}
+	if err = checkJSONSchemaCycles(inPlace); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustCompileJSONSchema is like CompileJSONSchema but panics on error.
+// It simplifies initialization of global variables.
+func MustCompileJSONSchema(schema JSONText) *JSONSchema {
+	s, err := CompileJSONSchema(schema)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// compile checks schema keywords and precompiles regular expressions.
+// Pointers of already compiled subschemas are tracked in compiled to handle recursive references.
+// Pointers of subschemas applied to the same instance (including $ref targets) are added to inPlace.
+func (s *JSONSchema) compile(schema interface{}, ptr string, compiled map[string]bool, inPlace map[string][]string) error {
+	if compiled[ptr] {
+		return nil
+	}
+	compiled[ptr] = true
+
+	switch schema := schema.(type) {
+	case bool:
+		return nil
+	case map[string]interface{}:
+		for k, v := range schema {
+			kptr := ptr + "/" + jsonPointerEscape(k)
+			switch k {
+			case "pattern":
+				p, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("CompileJSONSchema: %s: expected string", kptr)
+				}
+				if err := s.compilePattern(p, kptr); err != nil {
+					return err
+				}
+
+			case "patternProperties":
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("CompileJSONSchema: %s: expected object", kptr)
+				}
+				for p, sub := range m {
+					pptr := kptr + "/" + jsonPointerEscape(p)
+					if err := s.compilePattern(p, pptr); err != nil {
+						return err
+					}
+					if err := s.compile(sub, pptr, compiled, inPlace); err != nil {
+						return err
+					}
+				}
+
+			case "$ref":
+				ref, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("CompileJSONSchema: %s: expected string", kptr)
+				}
+				target, err := s.resolve(ref)
+				if err != nil {
+					return fmt.Errorf("CompileJSONSchema: %s: %s", kptr, err)
+				}
+				inPlace[ptr] = append(inPlace[ptr], ref[1:])
+				if err = s.compile(target, ref[1:], compiled, inPlace); err != nil {
+					return err
+				}
+
+			case "properties", "$defs", "definitions", "dependentSchemas":
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("CompileJSONSchema: %s: expected object", kptr)
+				}
+				for name, sub := range m {
+					sptr := kptr + "/" + jsonPointerEscape(name)
+					if k == "dependentSchemas" {
+						inPlace[ptr] = append(inPlace[ptr], sptr)
+					}
+					if err := s.compile(sub, sptr, compiled, inPlace); err != nil {
+						return err
+					}
+				}
+
+			case "allOf", "anyOf", "oneOf", "prefixItems":
+				a, ok := v.([]interface{})
+				if !ok || (len(a) == 0 && k != "prefixItems") {
+					return fmt.Errorf("CompileJSONSchema: %s: expected non-empty array", kptr)
+				}
+				for i, sub := range a {
+					sptr := kptr + "/" + strconv.Itoa(i)
+					if k != "prefixItems" {
+						inPlace[ptr] = append(inPlace[ptr], sptr)
+					}
+					if err := s.compile(sub, sptr, compiled, inPlace); err != nil {
+						return err
+					}
+				}
+
+			case "items", "contains", "additionalProperties", "propertyNames", "not", "if", "then", "else":
+				switch k {
+				case "not", "if", "then", "else":
+					inPlace[ptr] = append(inPlace[ptr], kptr)
+				}
+				if err := s.compile(v, kptr, compiled, inPlace); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("CompileJSONSchema: %s: expected object or boolean schema, got %s", jsonPointerOrRoot(ptr), jsonTypeOf(schema))
+	}
+}
+
+// checkJSONSchemaCycles returns error if some subschema is applied to the same instance
+// through a chain of $ref and in-place applicators which returns back to it, so validation would never end.
+func checkJSONSchemaCycles(inPlace map[string][]string) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var visit func(ptr string) error
+	visit = func(ptr string) error {
+		switch state[ptr] {
+		case visiting:
+			return fmt.Errorf("CompileJSONSchema: %s: $ref cycle without descending into array items or object properties",
+				jsonPointerOrRoot(ptr))
+		case done:
+			return nil
+		}
+		state[ptr] = visiting
+		for _, next := range inPlace[ptr] {
+			if err := visit(next); err != nil {
+				return err
+			}
+		}
+		state[ptr] = done
+		return nil
+	}
+
+	ptrs := make([]string, 0, len(inPlace))
+	for ptr := range inPlace {
+		ptrs = append(ptrs, ptr)
+	}
+	sort.Strings(ptrs)
+	for _, ptr := range ptrs {
+		if err := visit(ptr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JSONSchema) compilePattern(p, ptr string) error {
+	if _, ok := s.patterns[p]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return fmt.Errorf("CompileJSONSchema: %s: %s", ptr, err)
+	}
+	s.patterns[p] = re
+	return nil
+}
+
+// resolve returns subschema for local reference like "#/$defs/foo".
+func (s *JSONSchema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local references are supported, got %q", ref)
+	}
+
+	cur := s.root
+	ptr := ref[1:]
+	if ptr == "" {
+		return cur, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q", ptr)
+	}
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		tok = strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			v, ok := c[tok]
+			if !ok {
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+			cur = v
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("reference %q not found", ref)
+			}
+			cur = c[i]
+		default:
+			return nil, fmt.Errorf("reference %q not found", ref)
+		}
+	}
+	return cur, nil
+}
+
+// JSONSchemaViolation describes a single failed JSON Schema assertion.
+type JSONSchemaViolation struct {
+	InstanceLocation string // JSON pointer to the invalid value in the document, "" for the root
+	KeywordLocation  string // JSON pointer to the failed keyword in the schema
+	Message          string
+}
+
+// String implements fmt.Stringer for better output and logging.
+func (v JSONSchemaViolation) String() string {
+	return fmt.Sprintf("%s: %s", jsonPointerOrRoot(v.InstanceLocation), v.Message)
+}
+
+// JSONSchemaError is returned by JSONSchema.Validate when document doesn't match the schema.
+type JSONSchemaError struct {
+	Violations []JSONSchemaViolation
+}
+
+// Error implements error interface.
+func (e *JSONSchemaError) Error() string {
+	s := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		s[i] = v.String()
+	}
+	return "JSON Schema validation failed: " + strings.Join(s, "; ")
+}
+
+// Validate checks j against the schema.
+// It returns *JSONSchemaError if j doesn't match the schema, or other error if j is not valid JSON.
+func (s *JSONSchema) Validate(j JSONText) error {
+	inst, err := decodeJSONB(j, "JSONSchema.Validate")
+	if err != nil {
+		return err
+	}
+
+	var violations []JSONSchemaViolation
+	s.validate(s.root, inst, "", "", &violations)
+	if len(violations) > 0 {
+		return &JSONSchemaError{Violations: violations}
+	}
+	return nil
+}
+
+// valid returns true if inst matches schema, without collecting violations.
+func (s *JSONSchema) valid(schema, inst interface{}) bool {
+	var violations []JSONSchemaViolation
+	s.validate(schema, inst, "", "", &violations)
+	return len(violations) == 0
+}
+
+func (s *JSONSchema) validate(schema, inst interface{}, instPtr, kwPtr string, violations *[]JSONSchemaViolation) {
+	fail := func(keyword, format string, args ...interface{}) {
+		*violations = append(*violations, JSONSchemaViolation{
+			InstanceLocation: instPtr,
+			KeywordLocation:  kwPtr + "/" + keyword,
+			Message:          fmt.Sprintf(format, args...),
+		})
+	}
+
+	var sch map[string]interface{}
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			*violations = append(*violations, JSONSchemaViolation{
+				InstanceLocation: instPtr,
+				KeywordLocation:  kwPtr,
+				Message:          "not allowed",
+			})
+		}
+		return
+	case map[string]interface{}:
+		sch = schema
+	default:
+		return
+	}
+
+	// keywords are evaluated in a fixed order for stable output
+	keywords := make([]string, 0, len(sch))
+	for k := range sch {
+		keywords = append(keywords, k)
+	}
+	sort.Strings(keywords)
+
+	for _, k := range keywords {
+		v := sch[k]
+		switch k {
+		case "$ref":
+			sub, err := s.resolve(v.(string))
+			if err != nil {
+				fail(k, "%s", err)
+				continue
+			}
+			s.validate(sub, inst, instPtr, kwPtr+"/$ref", violations)
+
+		case "type":
+			var types []string
+			switch t := v.(type) {
+			case string:
+				types = []string{t}
+			case []interface{}:
+				for _, e := range t {
+					if e, ok := e.(string); ok {
+						types = append(types, e)
+					}
+				}
+			}
+			var ok bool
+			for _, t := range types {
+				if jsonHasType(inst, t) {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				fail(k, "expected %s, got %s", strings.Join(types, " or "), jsonTypeOf(inst))
+			}
+
+		case "enum":
+			a, _ := v.([]interface{})
+			var ok bool
+			for _, e := range a {
+				if jsonEqual(inst, e) {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				fail(k, "value is not one of enumerated values")
+			}
+
+		case "const":
+			if !jsonEqual(inst, v) {
+				fail(k, "value does not match constant")
+			}
+
+		case "multipleOf", "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum":
+			n, ok := jsonRat(inst)
+			limit, okLimit := jsonRat(v)
+			if !ok || !okLimit {
+				continue
+			}
+			switch k {
+			case "multipleOf":
+				if limit.Sign() <= 0 {
+					continue
+				}
+				if !new(big.Rat).Quo(n, limit).IsInt() {
+					fail(k, "%s is not a multiple of %s", inst, v)
+				}
+			case "maximum":
+				if n.Cmp(limit) > 0 {
+					fail(k, "%s is greater than %s", inst, v)
+				}
+			case "exclusiveMaximum":
+				if n.Cmp(limit) >= 0 {
+					fail(k, "%s is greater than or equal to %s", inst, v)
+				}
+			case "minimum":
+				if n.Cmp(limit) < 0 {
+					fail(k, "%s is less than %s", inst, v)
+				}
+			case "exclusiveMinimum":
+				if n.Cmp(limit) <= 0 {
+					fail(k, "%s is less than or equal to %s", inst, v)
+				}
+			}
+
+		case "maxLength", "minLength":
+			str, ok := inst.(string)
+			limit, okLimit := jsonInt(v)
+			if !ok || !okLimit {
+				continue
+			}
+			l := utf8.RuneCountInString(str)
+			if k == "maxLength" && l > limit {
+				fail(k, "length %d is greater than %d", l, limit)
+			}
+			if k == "minLength" && l < limit {
+				fail(k, "length %d is less than %d", l, limit)
+			}
+
+		case "pattern":
+			str, ok := inst.(string)
+			if !ok {
+				continue
+			}
+			if !s.patterns[v.(string)].MatchString(str) {
+				fail(k, "%q does not match pattern %q", str, v)
+			}
+
+		case "prefixItems", "items":
+			a, ok := inst.([]interface{})
+			if !ok {
+				continue
+			}
+			if k == "prefixItems" {
+				prefix, _ := v.([]interface{})
+				for i := 0; i < len(prefix) && i < len(a); i++ {
+					s.validate(prefix[i], a[i], instPtr+"/"+strconv.Itoa(i), kwPtr+"/prefixItems/"+strconv.Itoa(i), violations)
+				}
+				continue
+			}
+			var start int
+			if prefix, ok := sch["prefixItems"].([]interface{}); ok {
+				start = len(prefix)
+			}
+			for i := start; i < len(a); i++ {
+				s.validate(v, a[i], instPtr+"/"+strconv.Itoa(i), kwPtr+"/items", violations)
+			}
+
+		case "contains":
+			a, ok := inst.([]interface{})
+			if !ok {
+				continue
+			}
+			var matched int
+			for _, e := range a {
+				if s.valid(v, e) {
+					matched++
+				}
+			}
+			min := 1
+			if m, ok := jsonInt(sch["minContains"]); ok {
+				min = m
+			}
+			if matched < min {
+				fail(k, "array contains %d matching items, expected at least %d", matched, min)
+			}
+			if max, ok := jsonInt(sch["maxContains"]); ok && matched > max {
+				fail("maxContains", "array contains %d matching items, expected at most %d", matched, max)
+			}
+
+		case "maxItems", "minItems":
+			a, ok := inst.([]interface{})
+			limit, okLimit := jsonInt(v)
+			if !ok || !okLimit {
+				continue
+			}
+			if k == "maxItems" && len(a) > limit {
+				fail(k, "array has %d items, expected at most %d", len(a), limit)
+			}
+			if k == "minItems" && len(a) < limit {
+				fail(k, "array has %d items, expected at least %d", len(a), limit)
+			}
+
+		case "uniqueItems":
+			a, ok := inst.([]interface{})
+			if !ok || v != true {
+				continue
+			}
+		unique:
+			for i := range a {
+				for j := i + 1; j < len(a); j++ {
+					if jsonEqual(a[i], a[j]) {
+						fail(k, "items %d and %d are equal", i, j)
+						break unique
+					}
+				}
+			}
+
+		case "properties", "patternProperties", "additionalProperties":
+			o, ok := inst.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, name := range sortedKeys(o) {
+				namePtr := instPtr + "/" + jsonPointerEscape(name)
+				switch k {
+				case "properties":
+					if sub, ok := v.(map[string]interface{})[name]; ok {
+						s.validate(sub, o[name], namePtr, kwPtr+"/properties/"+jsonPointerEscape(name), violations)
+					}
+				case "patternProperties":
+					for p, sub := range v.(map[string]interface{}) {
+						if s.patterns[p].MatchString(name) {
+							s.validate(sub, o[name], namePtr, kwPtr+"/patternProperties/"+jsonPointerEscape(p), violations)
+						}
+					}
+				case "additionalProperties":
+					if props, ok := sch["properties"].(map[string]interface{}); ok {
+						if _, ok = props[name]; ok {
+							continue
+						}
+					}
+					var matched bool
+					if pp, ok := sch["patternProperties"].(map[string]interface{}); ok {
+						for p := range pp {
+							if s.patterns[p].MatchString(name) {
+								matched = true
+								break
+							}
+						}
+					}
+					if !matched {
+						s.validate(v, o[name], namePtr, kwPtr+"/additionalProperties", violations)
+					}
+				}
+			}
+
+		case "required":
+			o, ok := inst.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			a, _ := v.([]interface{})
+			for _, name := range a {
+				if name, ok := name.(string); ok {
+					if _, ok = o[name]; !ok {
+						fail(k, "missing required property %q", name)
+					}
+				}
+			}
+
+		case "maxProperties", "minProperties":
+			o, ok := inst.(map[string]interface{})
+			limit, okLimit := jsonInt(v)
+			if !ok || !okLimit {
+				continue
+			}
+			if k == "maxProperties" && len(o) > limit {
+				fail(k, "object has %d properties, expected at most %d", len(o), limit)
+			}
+			if k == "minProperties" && len(o) < limit {
+				fail(k, "object has %d properties, expected at least %d", len(o), limit)
+			}
+
+		case "dependentRequired":
+			o, ok := inst.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			deps, _ := v.(map[string]interface{})
+			for _, name := range sortedKeys(deps) {
+				if _, ok := o[name]; !ok {
+					continue
+				}
+				a, _ := deps[name].([]interface{})
+				for _, dep := range a {
+					if dep, ok := dep.(string); ok {
+						if _, ok = o[dep]; !ok {
+							fail(k, "property %q is required by %q", dep, name)
+						}
+					}
+				}
+			}
+
+		case "dependentSchemas":
+			o, ok := inst.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			deps, _ := v.(map[string]interface{})
+			for _, name := range sortedKeys(deps) {
+				if _, ok := o[name]; ok {
+					s.validate(deps[name], inst, instPtr, kwPtr+"/dependentSchemas/"+jsonPointerEscape(name), violations)
+				}
+			}
+
+		case "propertyNames":
+			o, ok := inst.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, name := range sortedKeys(o) {
+				if !s.valid(v, name) {
+					fail(k, "invalid property name %q", name)
+				}
+			}
+
+		case "allOf":
+			a, _ := v.([]interface{})
+			for i, sub := range a {
+				s.validate(sub, inst, instPtr, kwPtr+"/allOf/"+strconv.Itoa(i), violations)
+			}
+
+		case "anyOf", "oneOf":
+			a, _ := v.([]interface{})
+			var matched int
+			for _, sub := range a {
+				if s.valid(sub, inst) {
+					matched++
+				}
+			}
+			if k == "anyOf" && matched == 0 {
+				fail(k, "value does not match any schema")
+			}
+			if k == "oneOf" && matched != 1 {
+				fail(k, "value matches %d schemas, expected exactly one", matched)
+			}
+
+		case "not":
+			if s.valid(v, inst) {
+				fail(k, "value must not match schema")
+			}
+
+		case "if":
+			if s.valid(v, inst) {
+				if then, ok := sch["then"]; ok {
+					s.validate(then, inst, instPtr, kwPtr+"/then", violations)
+				}
+			} else {
+				if els, ok := sch["else"]; ok {
+					s.validate(els, inst, instPtr, kwPtr+"/else", violations)
+				}
+			}
+		}
+	}
+}
+
+// SchemaJSON is a JSONText that is validated against Schema before it is written to the database.
+// Nil Schema disables validation.
+type SchemaJSON struct {
+	JSONText JSONText
+	Schema   *JSONSchema
+}
+
+// String implements fmt.Stringer for better output and logging.
+func (j SchemaJSON) String() string {
+	return j.JSONText.String()
+}
+
+// MarshalJSON returns j.JSONText as the JSON encoding of j.
+func (j SchemaJSON) MarshalJSON() ([]byte, error) {
+	return j.JSONText.MarshalJSON()
+}
+
+// UnmarshalJSON sets j.JSONText to a copy of data. No validation is done.
+func (j *SchemaJSON) UnmarshalJSON(data []byte) error {
+	if j == nil {
+		return errors.New("SchemaJSON.UnmarshalJSON: on nil pointer")
+	}
+	return j.JSONText.UnmarshalJSON(data)
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It validates j.JSONText against j.Schema and returns *JSONSchemaError if it doesn't match.
+func (j SchemaJSON) Value() (driver.Value, error) {
+	if j.JSONText == nil {
+		return nil, nil
+	}
+	if j.Schema != nil {
+		if err := j.Schema.Validate(j.JSONText); err != nil {
+			return nil, err
+		}
+	}
+	return j.JSONText.Value()
+}
+
+// Scan implements database/sql Scanner interface.
+// It stores value in j.JSONText. No validation is done.
+func (j *SchemaJSON) Scan(value interface{}) error {
+	return j.JSONText.Scan(value)
+}
+
+// jsonTypeOf returns JSON Schema type name of decoded JSON value.
+func jsonTypeOf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if r, ok := jsonRat(v); ok && r.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func jsonHasType(v interface{}, t string) bool {
+	actual := jsonTypeOf(v)
+	return actual == t || (t == "number" && actual == "integer")
+}
+
+// jsonEqual compares decoded JSON values. Numbers are compared by value.
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, ae := range a {
+			be, ok := b[k]
+			if !ok || !jsonEqual(ae, be) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return jsonbScalarEqual(a, b)
+	}
+}
+
+func jsonRat(v interface{}) (*big.Rat, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(string(n))
+}
+
+func jsonInt(v interface{}) (int, bool) {
+	r, ok := jsonRat(v)
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return int(r.Num().Int64()), true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func jsonPointerEscape(s string) string {
+	if !strings.ContainsAny(s, "~/") {
+		return s
+	}
+	var b bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '~':
+			b.WriteString("~0")
+		case '/':
+			b.WriteString("~1")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func jsonPointerOrRoot(ptr string) string {
+	if ptr == "" {
+		return "(root)"
+	}
+	return ptr
+}
+
+// check interfaces
+var (
+	_ error            = &JSONSchemaError{}
+	_ fmt.Stringer     = JSONSchemaViolation{}
+	_ json.Marshaler   = SchemaJSON{}
+	_ json.Unmarshaler = &SchemaJSON{}
+	_ driver.Valuer    = SchemaJSON{}
+	_ sql.Scanner      = &SchemaJSON{}
+	_ fmt.Stringer     = SchemaJSON{}
+)
@@ -0,0 +1,160 @@
+package pq_types
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+)
+
+var testJSONSchema = MustCompileJSONSchema(JSONText(`{
+	"$defs": {
+		"tag": {"type": "string", "pattern": "^[a-z]+$", "maxLength": 8}
+	},
+	"type": "object",
+	"required": ["id", "name"],
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"name": {"type": "string", "minLength": 1},
+		"price": {"type": "number", "exclusiveMinimum": 0, "multipleOf": 0.01},
+		"currency": {"type": "string"},
+		"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "uniqueItems": true, "maxItems": 3},
+		"kind": {"enum": ["a", "b", null]},
+		"point": {"type": "array", "prefixItems": [{"type": "number"}, {"type": "number"}], "items": false}
+	},
+	"patternProperties": {"^x-": true},
+	"additionalProperties": false,
+	"dependentRequired": {"price": ["currency"]},
+	"if": {"properties": {"kind": {"const": "b"}}, "required": ["kind"]},
+	"then": {"required": ["tags"]}
+}`))
+
+func (s *TypesSuite) TestJSONSchemaValidate(c *C) {
+	type testData struct {
+		j          JSONText
+		violations []JSONSchemaViolation
+	}
+
+	for _, d := range []testData{
+		{JSONText(`{"id": 1, "name": "foo"}`), nil},
+		{JSONText(`{"id": 1.0, "name": "foo", "price": 9.99, "currency": "RUB", "x-foo": {}}`), nil},
+		{JSONText(`{"id": 1, "name": "foo", "kind": "b", "tags": ["a", "b"], "point": [1, 2]}`), nil},
+		{JSONText(`[]`), []JSONSchemaViolation{
+			{"", "/type", "expected object, got array"},
+		}},
+		{JSONText(`{"id": 0, "name": ""}`), []JSONSchemaViolation{
+			{"/id", "/properties/id/minimum", "0 is less than 1"},
+			{"/name", "/properties/name/minLength", "length 0 is less than 1"},
+		}},
+		{JSONText(`{"id": 1.5, "price": 1.001}`), []JSONSchemaViolation{
+			{"", "/dependentRequired", `property "currency" is required by "price"`},
+			{"/id", "/properties/id/type", "expected integer, got number"},
+			{"/price", "/properties/price/multipleOf", "1.001 is not a multiple of 0.01"},
+			{"", "/required", `missing required property "name"`},
+		}},
+		{JSONText(`{"id": 1, "name": "foo", "kind": "b", "foo": 1}`), []JSONSchemaViolation{
+			{"/foo", "/additionalProperties", "not allowed"},
+			{"", "/then/required", `missing required property "tags"`},
+		}},
+		{JSONText(`{"id": 1, "name": "foo", "kind": "c", "tags": ["a", "B", "a", "toolongtag"], "point": [1, 2, 3]}`), []JSONSchemaViolation{
+			{"/kind", "/properties/kind/enum", "value is not one of enumerated values"},
+			{"/point/2", "/properties/point/items", "not allowed"},
+			{"/tags/1", "/properties/tags/items/$ref/pattern", `"B" does not match pattern "^[a-z]+$"`},
+			{"/tags/3", "/properties/tags/items/$ref/maxLength", "length 10 is greater than 8"},
+			{"/tags", "/properties/tags/maxItems", "array has 4 items, expected at most 3"},
+			{"/tags", "/properties/tags/uniqueItems", "items 0 and 2 are equal"},
+		}},
+	} {
+		err := testJSONSchema.Validate(d.j)
+		if d.violations == nil {
+			c.Check(err, IsNil, Commentf("%s", d.j))
+			continue
+		}
+
+		var schemaErr *JSONSchemaError
+		c.Assert(errors.As(err, &schemaErr), Equals, true, Commentf("%s: %v", d.j, err))
+		c.Check(schemaErr.Violations, DeepEquals, d.violations, Commentf("%s", d.j))
+	}
+
+	c.Check(testJSONSchema.Validate(JSONText(`{"id": 1`)), ErrorMatches, `JSONSchema.Validate: unexpected EOF`)
+}
+
+func (s *TypesSuite) TestJSONSchemaCombinators(c *C) {
+	schema := MustCompileJSONSchema(JSONText(`{
+		"$defs": {
+			"node": {
+				"type": "object",
+				"properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}},
+				"propertyNames": {"enum": ["name", "children"]}
+			}
+		},
+		"anyOf": [{"$ref": "#/$defs/node"}, {"type": "string"}],
+		"oneOf": [{"type": "string"}, {"type": "object", "minProperties": 1}],
+		"not": {"const": "forbidden"},
+		"contains": {"type": "integer"}
+	}`))
+
+	c.Check(schema.Validate(JSONText(`"foo"`)), IsNil)
+	c.Check(schema.Validate(JSONText(`{"name": "root", "children": [{"children": []}]}`)), IsNil)
+	c.Check(schema.Validate(JSONText(`"forbidden"`)), ErrorMatches,
+		`JSON Schema validation failed: \(root\): value must not match schema`)
+	c.Check(schema.Validate(JSONText(`{}`)), ErrorMatches,
+		`JSON Schema validation failed: \(root\): value matches 0 schemas, expected exactly one`)
+	c.Check(schema.Validate(JSONText(`{"name": "root", "children": [{"foo": 1}]}`)), ErrorMatches,
+		`JSON Schema validation failed: \(root\): value does not match any schema`)
+	c.Check(schema.Validate(JSONText(`[1.5]`)), ErrorMatches,
+		`JSON Schema validation failed: \(root\): value does not match any schema; \(root\): array contains 0 matching items, expected at least 1; `+
+			`\(root\): value matches 0 schemas, expected exactly one`)
+
+	for schema, msg := range map[string]string{
+		`[]`:                           `CompileJSONSchema: \(root\): expected object or boolean schema, got array`,
+		`{"pattern": "("}`:             "CompileJSONSchema: /pattern: error parsing regexp: .*",
+		`{"$ref": "#/$defs/missing"}`:  `CompileJSONSchema: /\$ref: reference "#/\$defs/missing" not found`,
+		`{"$ref": "http://x/y.json"}`:  `CompileJSONSchema: /\$ref: only local references are supported, got "http://x/y.json"`,
+		`{"anyOf": []}`:                `CompileJSONSchema: /anyOf: expected non-empty array`,
+		`{"items": {"not": 1}}`:        `CompileJSONSchema: /items/not: expected object or boolean schema, got integer`,
+		`{"properties": {"a/b": "x"}}`: `CompileJSONSchema: /properties/a~1b: expected object or boolean schema, got string`,
+		`{"$ref": "#"}`:                `CompileJSONSchema: \(root\): \$ref cycle without descending into array items or object properties`,
+		`{"allOf": [{"$ref": "#"}]}`:   `CompileJSONSchema: \(root\): \$ref cycle without descending into array items or object properties`,
+		`{"$defs": {"a": {"$ref": "#/$defs/b"}, "b": {"$ref": "#/$defs/a"}}}`: `CompileJSONSchema: /\$defs/a: \$ref cycle ` +
+			`without descending into array items or object properties`,
+	} {
+		_, err := CompileJSONSchema(JSONText(schema))
+		c.Check(err, ErrorMatches, msg)
+	}
+}
+
+func (s *TypesSuite) TestSchemaJSON(c *C) {
+	v, err := SchemaJSON{Schema: testJSONSchema}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, IsNil)
+
+	v, err = SchemaJSON{JSONText: JSONText(`{"id": 1, "name": "foo"}`), Schema: testJSONSchema}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`{"id": 1, "name": "foo"}`))
+
+	v, err = SchemaJSON{JSONText: JSONText(`{"id": 1}`)}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`{"id": 1}`))
+
+	_, err = SchemaJSON{JSONText: JSONText(`{"id": 1}`), Schema: testJSONSchema}.Value()
+	c.Check(err, DeepEquals, &JSONSchemaError{Violations: []JSONSchemaViolation{
+		{"", "/required", `missing required property "name"`},
+	}})
+
+	if s.skipJSONB {
+		return
+	}
+
+	s.SetUpTest(c)
+	_, err = s.db.Exec("INSERT INTO pq_types (jsontext_jsonb) VALUES($1)", SchemaJSON{JSONText: JSONText(`{"id": 1}`), Schema: testJSONSchema})
+	c.Check(err, ErrorMatches, `sql: converting .*: JSON Schema validation failed: \(root\): missing required property "name"`)
+
+	j := SchemaJSON{JSONText: JSONText(`{"id": 1, "name": "foo"}`), Schema: testJSONSchema}
+	_, err = s.db.Exec("INSERT INTO pq_types (jsontext_jsonb) VALUES($1)", j)
+	c.Assert(err, IsNil)
+
+	j1 := SchemaJSON{Schema: testJSONSchema}
+	err = s.db.QueryRow("SELECT jsontext_jsonb FROM pq_types").Scan(&j1)
+	c.Check(err, IsNil)
+	c.Check(j1, DeepEquals, j)
+}
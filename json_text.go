@@ -52,8 +52,20 @@ func (j JSONText) Value() (driver.Value, error) {
 	return []byte(j), nil
 }
 
+// BinaryValue returns j in jsonb binary format: version byte followed by JSON text.
+// It is useful for drivers and COPY implementations sending parameters in binary format.
+// It performs the same validation as Value.
+// Such value must not be sent as a text parameter: PostgreSQL rejects the version byte in text jsonb input.
+func (j JSONText) BinaryValue() (driver.Value, error) {
+	v, err := j.Value()
+	if v == nil || err != nil {
+		return v, err
+	}
+	return append([]byte{jsonbBinaryVersion}, j...), nil
+}
+
 // Scan implements database/sql Scanner interface.
-// It store value in *j. No validation is done.
+// It store value in *j. jsonb binary format version byte is stripped. No validation is done.
 func (j *JSONText) Scan(value interface{}) error {
 	if value == nil {
 		*j = nil
@@ -70,10 +82,36 @@ func (j *JSONText) Scan(value interface{}) error {
 		return fmt.Errorf("JSONText.Scan: expected []byte or string, got %T (%q)", value, value)
 	}
 
-	*j = JSONText(append((*j)[0:0], b...))
+	*j = JSONText(append((*j)[0:0], trimJSONBVersion(b)...))
 	return nil
 }
 
+// jsonbBinaryVersion is the only supported version of jsonb binary format.
+const jsonbBinaryVersion = 1
+
+// trimJSONBVersion strips jsonb binary format version byte.
+// JSON text can't start with that byte, so it is safe for text format too.
+func trimJSONBVersion(b []byte) []byte {
+	if len(b) > 0 && b[0] == jsonbBinaryVersion {
+		return b[1:]
+	}
+	return b
+}
+
+// JSONBBinary is a JSONText which Value returns in jsonb binary format. See JSONText.BinaryValue.
+//
+// It works only with connections sending []byte parameters in binary format,
+// like lib/pq with binary_parameters=yes in connection string. With default text parameters
+// PostgreSQL rejects the value with "invalid input syntax for type json"; use JSONText there.
+type JSONBBinary struct {
+	JSONText
+}
+
+// Value implements database/sql/driver Valuer interface.
+func (j JSONBBinary) Value() (driver.Value, error) {
+	return j.JSONText.BinaryValue()
+}
+
 // check interfaces
 var (
 	_ json.Marshaler   = JSONText{}
@@ -82,4 +120,10 @@ var (
 	_ sql.Scanner      = &JSONText{}
 	_ fmt.Stringer     = JSONText{}
 	_ fmt.Stringer     = &JSONText{}
+
+	_ json.Marshaler   = JSONBBinary{}
+	_ json.Unmarshaler = &JSONBBinary{}
+	_ driver.Valuer    = JSONBBinary{}
+	_ sql.Scanner      = &JSONBBinary{}
+	_ fmt.Stringer     = JSONBBinary{}
 )
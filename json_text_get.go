@@ -0,0 +1,309 @@
+package pq_types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Get returns a value at path without decoding the whole document.
+// Path elements are object keys or array indexes. Returned JSONText shares memory with j.
+// It returns nil JSONText without error if path is not found (JSON null is returned as `null`).
+// Only the parts of j preceding the value are scanned, and they are not fully validated.
+func (j JSONText) Get(path ...string) (JSONText, error) {
+	cur := bytes.TrimSpace(j)
+	if len(cur) == 0 {
+		return nil, nil
+	}
+
+	for _, elem := range path {
+		var found []byte
+		var err error
+		switch cur[0] {
+		case '{':
+			err = jsonEach(cur, func(_ int, key, value []byte) (bool, error) {
+				ok, err := jsonKeyEqual(key, elem)
+				if ok {
+					found = value
+				}
+				return ok, err
+			})
+		case '[':
+			index, convErr := strconv.Atoi(elem)
+			if convErr != nil || index < 0 {
+				return nil, nil
+			}
+			err = jsonEach(cur, func(i int, _, value []byte) (bool, error) {
+				if i == index {
+					found = value
+					return true, nil
+				}
+				return false, nil
+			})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("JSONText.Get: %s", err)
+		}
+		if found == nil {
+			return nil, nil
+		}
+		cur = found
+	}
+
+	return JSONText(cur), nil
+}
+
+// GetString returns a string at path. See Get for details.
+// It returns an error if path is not found or value is not a string.
+func (j JSONText) GetString(path ...string) (string, error) {
+	v, err := j.get("JSONText.GetString", path)
+	if err != nil {
+		return "", err
+	}
+	if v[0] != '"' {
+		return "", fmt.Errorf("JSONText.GetString: expected string at %q, got %s", path, v)
+	}
+	if len(v) < 2 || v[len(v)-1] != '"' {
+		return "", fmt.Errorf("JSONText.GetString: unterminated string at %q: %s", path, v)
+	}
+
+	// fast path for strings without escapes
+	if bytes.IndexByte(v, '\\') < 0 {
+		return string(v[1 : len(v)-1]), nil
+	}
+
+	var s string
+	if err = json.Unmarshal(v, &s); err != nil {
+		return "", fmt.Errorf("JSONText.GetString: %s", err)
+	}
+	return s, nil
+}
+
+// GetInt returns an integer number at path. See Get for details.
+// It returns an error if path is not found or value is not an integer number.
+func (j JSONText) GetInt(path ...string) (int64, error) {
+	v, err := j.get("JSONText.GetInt", path)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("JSONText.GetInt: expected integer at %q, got %s", path, v)
+	}
+	return i, nil
+}
+
+// GetFloat returns a number at path. See Get for details.
+// It returns an error if path is not found or value is not a number.
+func (j JSONText) GetFloat(path ...string) (float64, error) {
+	v, err := j.get("JSONText.GetFloat", path)
+	if err != nil {
+		return 0, err
+	}
+	if v[0] != '-' && (v[0] < '0' || v[0] > '9') {
+		return 0, fmt.Errorf("JSONText.GetFloat: expected number at %q, got %s", path, v)
+	}
+	f, err := strconv.ParseFloat(string(v), 64)
+	if err != nil {
+		return 0, fmt.Errorf("JSONText.GetFloat: expected number at %q, got %s", path, v)
+	}
+	return f, nil
+}
+
+// GetBool returns a boolean at path. See Get for details.
+// It returns an error if path is not found or value is not a boolean.
+func (j JSONText) GetBool(path ...string) (bool, error) {
+	v, err := j.get("JSONText.GetBool", path)
+	if err != nil {
+		return false, err
+	}
+	switch string(v) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, fmt.Errorf("JSONText.GetBool: expected boolean at %q, got %s", path, v)
+	}
+}
+
+// Each calls fn for each member of object or element of array without decoding the whole document.
+// For objects key is a member name, for arrays key is an element index.
+// Values share memory with j. Iteration stops on the first error returned by fn, and Each returns it.
+// It returns an error if j is not an object or an array.
+func (j JSONText) Each(fn func(key string, value JSONText) error) error {
+	b := bytes.TrimSpace(j)
+	if len(b) == 0 || (b[0] != '{' && b[0] != '[') {
+		return fmt.Errorf("JSONText.Each: expected object or array, got %q", b)
+	}
+
+	var fnErr error
+	err := jsonEach(b, func(i int, key, value []byte) (bool, error) {
+		var k string
+		if key == nil {
+			k = strconv.Itoa(i)
+		} else if bytes.IndexByte(key, '\\') < 0 {
+			k = string(key[1 : len(key)-1])
+		} else if err := json.Unmarshal(key, &k); err != nil {
+			return false, err
+		}
+
+		fnErr = fn(k, JSONText(value))
+		return fnErr != nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("JSONText.Each: %s", err)
+	}
+	return fnErr
+}
+
+// get is a common part of typed getters.
+func (j JSONText) get(method string, path []string) (JSONText, error) {
+	v, err := j.Get(path...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", method, err)
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%s: path %q not found", method, path)
+	}
+	return v, nil
+}
+
+// jsonEach iterates over object or array b (without leading spaces) calling fn for each member or element.
+// For objects key is a raw quoted member name, for arrays it is nil.
+// Iteration stops when fn returns true or error.
+func jsonEach(b []byte, fn func(i int, key, value []byte) (bool, error)) error {
+	obj := b[0] == '{'
+	end := byte(']')
+	if obj {
+		end = '}'
+	}
+
+	pos := jsonSkipSpace(b, 1)
+	if pos < len(b) && b[pos] == end {
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		var key []byte
+		if obj {
+			if pos >= len(b) || b[pos] != '"' {
+				return fmt.Errorf("expected object key at offset %d", pos)
+			}
+			keyEnd, err := jsonValueEnd(b, pos)
+			if err != nil {
+				return err
+			}
+			key = b[pos:keyEnd]
+
+			pos = jsonSkipSpace(b, keyEnd)
+			if pos >= len(b) || b[pos] != ':' {
+				return fmt.Errorf("expected ':' at offset %d", pos)
+			}
+			pos = jsonSkipSpace(b, pos+1)
+		}
+
+		valueEnd, err := jsonValueEnd(b, pos)
+		if err != nil {
+			return err
+		}
+		stop, err := fn(i, key, b[pos:valueEnd])
+		if stop || err != nil {
+			return err
+		}
+
+		pos = jsonSkipSpace(b, valueEnd)
+		if pos >= len(b) {
+			return fmt.Errorf("unexpected end of JSON input")
+		}
+		switch b[pos] {
+		case ',':
+			pos = jsonSkipSpace(b, pos+1)
+		case end:
+			return nil
+		default:
+			return fmt.Errorf("unexpected %q at offset %d", b[pos], pos)
+		}
+	}
+}
+
+// jsonValueEnd returns the offset just after JSON value starting at b[pos].
+func jsonValueEnd(b []byte, pos int) (int, error) {
+	if pos >= len(b) {
+		return 0, fmt.Errorf("unexpected end of JSON input")
+	}
+
+	switch b[pos] {
+	case '"':
+		for i := pos + 1; i < len(b); i++ {
+			switch b[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("unexpected end of JSON input")
+
+	case '{', '[':
+		var depth int
+		for i := pos; i < len(b); i++ {
+			switch b[i] {
+			case '"':
+				end, err := jsonValueEnd(b, i)
+				if err != nil {
+					return 0, err
+				}
+				i = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("unexpected end of JSON input")
+
+	default:
+		i := pos
+		for i < len(b) {
+			switch b[i] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				if i == pos {
+					return 0, fmt.Errorf("unexpected %q at offset %d", b[i], i)
+				}
+				return i, nil
+			}
+			i++
+		}
+		return i, nil
+	}
+}
+
+func jsonSkipSpace(b []byte, pos int) int {
+	for pos < len(b) {
+		switch b[pos] {
+		case ' ', '\t', '\n', '\r':
+			pos++
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// jsonKeyEqual compares raw quoted key with name.
+func jsonKeyEqual(key []byte, name string) (bool, error) {
+	if bytes.IndexByte(key, '\\') < 0 {
+		return string(key[1:len(key)-1]) == name, nil
+	}
+
+	var k string
+	if err := json.Unmarshal(key, &k); err != nil {
+		return false, err
+	}
+	return k == name, nil
+}
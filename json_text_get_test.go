@@ -0,0 +1,147 @@
+package pq_types
+
+import (
+	"errors"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestJSONTextGet(c *C) {
+	j := JSONText(` {"id": 42, "name": "foo \"bar\"", "price": -1.5e2, "ok": true, "nil": null,
+		"tags": ["a", {"b": [1, 2, "]}"]}, []],
+		"obj": {"a\/b": {"c": "d"}, "e": {}}} `)
+
+	type testData struct {
+		path []string
+		v    JSONText
+	}
+	for _, d := range []testData{
+		{nil, JSONText(`{"id": 42, "name": "foo \"bar\"", "price": -1.5e2, "ok": true, "nil": null,
+		"tags": ["a", {"b": [1, 2, "]}"]}, []],
+		"obj": {"a\/b": {"c": "d"}, "e": {}}}`)},
+		{[]string{"id"}, JSONText(`42`)},
+		{[]string{"name"}, JSONText(`"foo \"bar\""`)},
+		{[]string{"nil"}, JSONText(`null`)},
+		{[]string{"tags", "0"}, JSONText(`"a"`)},
+		{[]string{"tags", "1", "b"}, JSONText(`[1, 2, "]}"]`)},
+		{[]string{"tags", "1", "b", "2"}, JSONText(`"]}"`)},
+		{[]string{"tags", "2"}, JSONText(`[]`)},
+		{[]string{"obj", "a/b", "c"}, JSONText(`"d"`)},
+		{[]string{"obj", "e"}, JSONText(`{}`)},
+
+		{[]string{"missing"}, nil},
+		{[]string{"id", "missing"}, nil},
+		{[]string{"tags", "3"}, nil},
+		{[]string{"tags", "-1"}, nil},
+		{[]string{"tags", "a"}, nil},
+		{[]string{"tags", "2", "0"}, nil},
+		{[]string{"obj", "e", "f"}, nil},
+	} {
+		v, err := j.Get(d.path...)
+		c.Check(err, IsNil)
+		c.Check(v, DeepEquals, d.v, Commentf("%q", d.path))
+	}
+
+	str, err := j.GetString("name")
+	c.Check(err, IsNil)
+	c.Check(str, Equals, `foo "bar"`)
+	str, err = j.GetString("obj", "a/b", "c")
+	c.Check(err, IsNil)
+	c.Check(str, Equals, `d`)
+	_, err = j.GetString("id")
+	c.Check(err, ErrorMatches, `JSONText.GetString: expected string at \["id"\], got 42`)
+	for _, malformed := range []JSONText{JSONText(`"`), JSONText(`"abc`), JSONText(` "abc `)} {
+		_, err = malformed.GetString()
+		c.Check(err, ErrorMatches, `JSONText.GetString: unterminated string at \[\]: .*`, Commentf("%s", malformed))
+	}
+
+	i, err := j.GetInt("id")
+	c.Check(err, IsNil)
+	c.Check(i, Equals, int64(42))
+	_, err = j.GetInt("price")
+	c.Check(err, ErrorMatches, `JSONText.GetInt: expected integer at \["price"\], got -1.5e2`)
+	_, err = j.GetInt("missing")
+	c.Check(err, ErrorMatches, `JSONText.GetInt: path \["missing"\] not found`)
+
+	f, err := j.GetFloat("price")
+	c.Check(err, IsNil)
+	c.Check(f, Equals, -150.0)
+	f, err = j.GetFloat("tags", "1", "b", "1")
+	c.Check(err, IsNil)
+	c.Check(f, Equals, 2.0)
+	_, err = j.GetFloat("ok")
+	c.Check(err, ErrorMatches, `JSONText.GetFloat: expected number at \["ok"\], got true`)
+
+	b, err := j.GetBool("ok")
+	c.Check(err, IsNil)
+	c.Check(b, Equals, true)
+	_, err = j.GetBool("nil")
+	c.Check(err, ErrorMatches, `JSONText.GetBool: expected boolean at \["nil"\], got null`)
+
+	// values share memory with the document
+	v, err := j.Get("obj")
+	c.Check(err, IsNil)
+	v[1] = '\''
+	c.Check(strings.HasSuffix(string(j), `"obj": {'a\/b": {"c": "d"}, "e": {}}} `), Equals, true)
+
+	for _, bad := range []JSONText{
+		JSONText(`{"a" 1}`),
+		JSONText(`{"a": 1 "1": 2}`),
+		JSONText(`{"a": "1}`),
+		JSONText(`{"a": [1, 2}`),
+		JSONText(`{1: 2}`),
+		JSONText(`[1 2]`),
+	} {
+		_, err = bad.Get("1")
+		c.Check(err, NotNil, Commentf("%s", bad))
+	}
+}
+
+func (s *TypesSuite) TestJSONTextEach(c *C) {
+	type kv struct {
+		k string
+		v JSONText
+	}
+
+	var res []kv
+	err := JSONText(`{"a": 1, "b\n": [true, null], "c": {"d": "e"}}`).Each(func(k string, v JSONText) error {
+		res = append(res, kv{k, v})
+		return nil
+	})
+	c.Check(err, IsNil)
+	c.Check(res, DeepEquals, []kv{
+		{"a", JSONText(`1`)},
+		{"b\n", JSONText(`[true, null]`)},
+		{"c", JSONText(`{"d": "e"}`)},
+	})
+
+	res = nil
+	stop := errors.New("stop")
+	err = JSONText(`[ "a", 2 , {}, 4]`).Each(func(k string, v JSONText) error {
+		res = append(res, kv{k, v})
+		if k == "2" {
+			return stop
+		}
+		return nil
+	})
+	c.Check(err, Equals, stop)
+	c.Check(res, DeepEquals, []kv{
+		{"0", JSONText(`"a"`)},
+		{"1", JSONText(`2`)},
+		{"2", JSONText(`{}`)},
+	})
+
+	for _, j := range []JSONText{JSONText(`[]`), JSONText(` {} `)} {
+		err = j.Each(func(k string, v JSONText) error {
+			c.Errorf("unexpected call for %s", j)
+			return nil
+		})
+		c.Check(err, IsNil)
+	}
+
+	err = JSONText(`"a"`).Each(func(k string, v JSONText) error { return nil })
+	c.Check(err, ErrorMatches, `JSONText.Each: expected object or array, got "\\"a\\""`)
+	err = JSONText(`[1,`).Each(func(k string, v JSONText) error { return nil })
+	c.Check(err, ErrorMatches, `JSONText.Each: unexpected end of JSON input`)
+}
@@ -2,6 +2,7 @@ package pq_types
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -74,3 +75,72 @@ func (s *TypesSuite) TestJSONText(c *C) {
 		}
 	}
 }
+
+func (s *TypesSuite) TestJSONTextBinary(c *C) {
+	for _, b := range [][]byte{
+		[]byte("\x01{\"foo\": \"bar\"}"),
+		[]byte(`{"foo": "bar"}`),
+	} {
+		var j JSONText
+		c.Check(j.Scan(b), IsNil)
+		c.Check(j, DeepEquals, JSONText(`{"foo": "bar"}`))
+
+		var jb JSONBBinary
+		c.Check(jb.Scan(string(b)), IsNil)
+		c.Check(jb, DeepEquals, JSONBBinary{j})
+
+		sj := SchemaJSON{Schema: MustCompileJSONSchema(JSONText(`{"required": ["foo"]}`))}
+		c.Check(sj.Scan(b), IsNil)
+		c.Check(sj.JSONText, DeepEquals, j)
+
+		var cj CompressedJSON
+		c.Check(cj.Scan(b), IsNil)
+		c.Check(cj.JSONText, DeepEquals, j)
+
+		v, err := j.BinaryValue()
+		c.Check(err, IsNil)
+		c.Check(v, DeepEquals, []byte("\x01{\"foo\": \"bar\"}"))
+
+		v, err = jb.Value()
+		c.Check(err, IsNil)
+		c.Check(v, DeepEquals, []byte("\x01{\"foo\": \"bar\"}"))
+	}
+
+	v, err := JSONBBinary{}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, IsNil)
+
+	_, err = JSONBBinary{JSONText(`{`)}.Value()
+	c.Check(err, ErrorMatches, `unexpected end of JSON input`)
+
+	b, err := json.Marshal(JSONBBinary{JSONText(`{"foo": "bar"}`)})
+	c.Check(err, IsNil)
+	c.Check(b, DeepEquals, []byte(`{"foo":"bar"}`))
+
+	if s.skipJSONB {
+		return
+	}
+
+	// binary format works only with binary parameters
+	db, err := sql.Open("postgres", "dbname=pq_types sslmode=disable binary_parameters=yes")
+	c.Assert(err, IsNil)
+	defer db.Close()
+	for _, j := range []JSONBBinary{{JSONText(`{"foo": "bar"}`)}, {}} {
+		s.SetUpTest(c)
+
+		_, err = db.Exec("INSERT INTO pq_types (jsontext_jsonb) VALUES($1)", j)
+		c.Assert(err, IsNil)
+
+		var j1, j2 JSONBBinary
+		err = db.QueryRow("SELECT jsontext_jsonb FROM pq_types").Scan(&j1)
+		c.Check(err, IsNil)
+		c.Check(j1, DeepEquals, j)
+		err = s.db.QueryRow("SELECT jsontext_jsonb FROM pq_types").Scan(&j2)
+		c.Check(err, IsNil)
+		c.Check(j2, DeepEquals, j)
+	}
+
+	// with text parameters version byte is rejected
+	_, err = s.db.Exec("INSERT INTO pq_types (jsontext_jsonb) VALUES($1)", JSONBBinary{JSONText(`{"foo": "bar"}`)})
+	c.Check(err, ErrorMatches, `pq: invalid input syntax for type json`)
+}
@@ -0,0 +1,191 @@
+package pq_types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Contains reports whether j contains other, like PostgreSQL's jsonb @> operator.
+//
+// Objects contain objects with a subset of their keys (recursively), arrays contain arrays
+// whose elements are all contained in some element (order and duplicates don't matter),
+// scalars contain equal scalars. As a special exception, a top-level array contains
+// a primitive value that is one of its elements.
+// It returns an error if j or other is not valid JSON.
+func (j JSONText) Contains(other JSONText) (bool, error) {
+	val, err := decodeJSONB(j, "JSONText.Contains")
+	if err != nil {
+		return false, err
+	}
+	cont, err := decodeJSONB(other, "JSONText.Contains")
+	if err != nil {
+		return false, err
+	}
+
+	// a raw scalar may contain another raw scalar, and an array may contain a raw scalar,
+	// but a raw scalar may not contain an array
+	if a, ok := val.([]interface{}); ok && isJSONBScalar(cont) {
+		return jsonbContains(a, []interface{}{cont}), nil
+	}
+	return jsonbContains(val, cont), nil
+}
+
+// ContainedBy reports whether j is contained in other, like PostgreSQL's jsonb <@ operator.
+// See Contains for details.
+func (j JSONText) ContainedBy(other JSONText) (bool, error) {
+	return other.Contains(j)
+}
+
+// HasKey reports whether key exists as a top-level key or array element within j,
+// like PostgreSQL's jsonb ? operator. A top-level string scalar equal to key also matches.
+// It returns an error if j is not valid JSON.
+func (j JSONText) HasKey(key string) (bool, error) {
+	val, err := decodeJSONB(j, "JSONText.HasKey")
+	if err != nil {
+		return false, err
+	}
+	return jsonbExists(val, key), nil
+}
+
+// HasAnyKey reports whether any of keys exists in j, like PostgreSQL's jsonb ?| operator.
+// See HasKey for details.
+func (j JSONText) HasAnyKey(keys ...string) (bool, error) {
+	val, err := decodeJSONB(j, "JSONText.HasAnyKey")
+	if err != nil {
+		return false, err
+	}
+	for _, k := range keys {
+		if jsonbExists(val, k) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasAllKeys reports whether all of keys exist in j, like PostgreSQL's jsonb ?& operator.
+// See HasKey for details.
+func (j JSONText) HasAllKeys(keys ...string) (bool, error) {
+	val, err := decodeJSONB(j, "JSONText.HasAllKeys")
+	if err != nil {
+		return false, err
+	}
+	for _, k := range keys {
+		if !jsonbExists(val, k) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// decodeJSONB decodes j keeping numbers as json.Number, so they can be compared like jsonb numeric values.
+// Duplicate object keys are resolved like jsonb does: the last one wins.
+func decodeJSONB(j JSONText, method string) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(j))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%s: %s", method, err)
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, fmt.Errorf("%s: invalid data after top-level value", method)
+	}
+	return v, nil
+}
+
+func isJSONBScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	default:
+		return true
+	}
+}
+
+// jsonbContains implements jsonb containment for non-top-level values.
+func jsonbContains(val, cont interface{}) bool {
+	switch c := cont.(type) {
+	case map[string]interface{}:
+		v, ok := val.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, ce := range c {
+			ve, ok := v[k]
+			if !ok || !jsonbContains(ve, ce) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		v, ok := val.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, ce := range c {
+			var found bool
+			for _, ve := range v {
+				// scalar elements match only scalar elements, containers match containers of the same kind
+				if isJSONBScalar(ce) != isJSONBScalar(ve) {
+					continue
+				}
+				if jsonbContains(ve, ce) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return jsonbScalarEqual(val, cont)
+	}
+}
+
+// jsonbScalarEqual compares scalars. Numbers are compared by value, so 1 equals 1.0.
+func jsonbScalarEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ra, okA := new(big.Rat).SetString(string(a))
+		rb, okB := new(big.Rat).SetString(string(b))
+		if !okA || !okB {
+			return a == b
+		}
+		return ra.Cmp(rb) == 0
+	case string, bool, nil:
+		return a == b
+	default:
+		return false
+	}
+}
+
+// jsonbExists implements jsonb ? operator.
+func jsonbExists(val interface{}, key string) bool {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		_, ok := v[key]
+		return ok
+	case []interface{}:
+		for _, e := range v {
+			if s, ok := e.(string); ok && s == key {
+				return true
+			}
+		}
+		return false
+	case string:
+		return v == key
+	default:
+		return false
+	}
+}
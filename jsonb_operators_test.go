@@ -0,0 +1,98 @@
+package pq_types
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestJSONTextContains(c *C) {
+	type testData struct {
+		j, other JSONText
+		contains bool
+	}
+
+	for _, d := range []testData{
+		{JSONText(`"foo"`), JSONText(`"foo"`), true},
+		{JSONText(`1`), JSONText(`1.0`), true},
+		{JSONText(`1`), JSONText(`"1"`), false},
+		{JSONText(`null`), JSONText(`null`), true},
+		{JSONText(`[1, 2, 3]`), JSONText(`[1, 3]`), true},
+		{JSONText(`[1, 2, 3]`), JSONText(`[3, 1, 1]`), true},
+		{JSONText(`[1, 2, 3]`), JSONText(`[]`), true},
+		{JSONText(`[1, 2, [1, 3]]`), JSONText(`[1, 3]`), false},
+		{JSONText(`[1, 2, [1, 3]]`), JSONText(`[[1]]`), true},
+		{JSONText(`["foo", "bar"]`), JSONText(`"foo"`), true},
+		{JSONText(`"foo"`), JSONText(`["foo"]`), false},
+		{JSONText(`{"a": 1, "b": {"c": [1, 2]}}`), JSONText(`{"b": {"c": [2]}}`), true},
+		{JSONText(`{"a": 1, "b": {"c": [1, 2]}}`), JSONText(`{"b": {"c": 2}}`), false},
+		{JSONText(`{"a": 1, "b": 2}`), JSONText(`{}`), true},
+		{JSONText(`{"a": 1, "a": 2}`), JSONText(`{"a": 1}`), false},
+		{JSONText(`{"a": 1}`), JSONText(`[]`), false},
+		{JSONText(`[{"a": 1, "b": 2}, {"c": 3}]`), JSONText(`[{"a": 1}]`), true},
+		{JSONText(`[{"a": 1, "b": 2}, {"c": 3}]`), JSONText(`[{"a": 1, "c": 3}]`), false},
+	} {
+		res, err := d.j.Contains(d.other)
+		c.Check(err, IsNil)
+		c.Check(res, Equals, d.contains, Commentf("%s @> %s", d.j, d.other))
+
+		res, err = d.other.ContainedBy(d.j)
+		c.Check(err, IsNil)
+		c.Check(res, Equals, d.contains, Commentf("%s <@ %s", d.other, d.j))
+
+		if s.skipJSONB {
+			continue
+		}
+		var dbRes bool
+		err = s.db.QueryRow("SELECT $1::jsonb @> $2::jsonb", d.j, d.other).Scan(&dbRes)
+		c.Check(err, IsNil)
+		c.Check(res, Equals, dbRes, Commentf("%s @> %s", d.j, d.other))
+	}
+
+	_, err := JSONText(`{`).Contains(JSONText(`{}`))
+	c.Check(err, ErrorMatches, `JSONText.Contains: unexpected EOF`)
+	_, err = JSONText(`{}`).Contains(JSONText(`{} {}`))
+	c.Check(err, ErrorMatches, `JSONText.Contains: invalid data after top-level value`)
+}
+
+func (s *TypesSuite) TestJSONTextHasKey(c *C) {
+	type testData struct {
+		j             JSONText
+		keys          []string
+		key, any, all bool
+	}
+
+	for _, d := range []testData{
+		{JSONText(`{"a": 1, "b": null}`), []string{"a"}, true, true, true},
+		{JSONText(`{"a": 1, "b": null}`), []string{"b", "c"}, true, true, false},
+		{JSONText(`{"a": {"c": 1}}`), []string{"c"}, false, false, false},
+		{JSONText(`["a", "b", 1]`), []string{"b", "a"}, true, true, true},
+		{JSONText(`["a", "b", 1]`), []string{"1"}, false, false, false},
+		{JSONText(`"a"`), []string{"a"}, true, true, true},
+		{JSONText(`{}`), []string{}, false, false, true},
+	} {
+		if len(d.keys) > 0 {
+			res, err := d.j.HasKey(d.keys[0])
+			c.Check(err, IsNil)
+			c.Check(res, Equals, d.key, Commentf("%s ? %q", d.j, d.keys[0]))
+		}
+
+		res, err := d.j.HasAnyKey(d.keys...)
+		c.Check(err, IsNil)
+		c.Check(res, Equals, d.any, Commentf("%s ?| %q", d.j, d.keys))
+
+		res, err = d.j.HasAllKeys(d.keys...)
+		c.Check(err, IsNil)
+		c.Check(res, Equals, d.all, Commentf("%s ?& %q", d.j, d.keys))
+
+		if s.skipJSONB {
+			continue
+		}
+		var dbAny, dbAll bool
+		err = s.db.QueryRow("SELECT $1::jsonb ?| $2, $1::jsonb ?& $2", d.j, StringArray(d.keys)).Scan(&dbAny, &dbAll)
+		c.Check(err, IsNil)
+		c.Check(dbAny, Equals, d.any, Commentf("%s ?| %q", d.j, d.keys))
+		c.Check(dbAll, Equals, d.all, Commentf("%s ?& %q", d.j, d.keys))
+	}
+
+	_, err := JSONText(`nope`).HasKey("a")
+	c.Check(err, ErrorMatches, `JSONText.HasKey: invalid character .*`)
+}
@@ -1,69 +1,162 @@
 package pq_types
 
 import (
-	"bytes"
 	"database/sql"
 	"database/sql/driver"
-	"encoding/binary"
-	"encoding/hex"
 	"fmt"
+	"math"
 	"strings"
 )
 
+// PostGISDefaultSRID is SRID 4326 (WGS 84) used by geometry types when SRID is not set.
+// Since zero value of SRID fields means PostGISDefaultSRID, SRID 0 is represented by PostGISUnknownSRID.
+const PostGISDefaultSRID = 4326
+
+// PostGISUnknownSRID is value of SRID fields for SRID 0 (unknown spatial reference system in PostGIS).
+const PostGISUnknownSRID = -1
+
+// postGISMaxSRID is the maximum SRID supported by PostGIS.
+const postGISMaxSRID = 999999
+
 // PostGISPoint is wrapper for PostGIS POINT type.
+//
+// SRID is a spatial reference system identifier. Zero value means PostGISDefaultSRID.
+// Scan stores PostGISDefaultSRID as zero, so scanned values are equal to ones created without SRID.
+// SRID 0 and absent SRID (PostGIS omits SRID 0 in its output) are stored as PostGISUnknownSRID,
+// so SRID is kept through Scan and Value.
+// SRID of points inside other geometries should be zero or match SRID of the enclosing geometry.
+//
+// Z and M coordinates are used only if Layout has them. Layout of points inside other geometries
+// should be zero or match Layout of the enclosing geometry, which defines used coordinates.
+//
+// Empty point (POINT EMPTY) has NaN Lon and Lat, like in WKB.
 type PostGISPoint struct {
 	Lon, Lat float64
+	Z, M     float64
+	SRID     int
+	Layout   PostGISLayout
 }
 
 // Value implements database/sql/driver Valuer interface.
-// It returns point as WKT with SRID 4326 (WGS 84).
+// It returns point as EWKT with SRID.
+// Coordinates are formatted with 8 decimal digits; use PostGISEWKB to keep them bit-exact.
 func (p PostGISPoint) Value() (driver.Value, error) {
-	return []byte(fmt.Sprintf("SRID=4326;POINT(%.8f %.8f)", p.Lon, p.Lat)), nil
-}
-
-type ewkbPoint struct {
-	ByteOrder byte   // 1 (LittleEndian)
-	WkbType   uint32 // 0x20000001 (PointS)
-	SRID      uint32 // 4326
-	Point     PostGISPoint
+	v, err := postGISValue(p)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISPoint.Value: %s", err)
+	}
+	return v, nil
 }
 
 // Scan implements database/sql Scanner interface.
-// It expectes EWKB with SRID 4326 (WGS 84).
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout.
 func (p *PostGISPoint) Scan(value interface{}) error {
 	if value == nil {
 		*p = PostGISPoint{}
 		return nil
 	}
 
-	v, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("PostGISPoint.Scan: expected []byte, got %T (%v)", value, value)
+	g, err := scanGeometry(value, wkbPoint)
+	if err != nil {
+		return fmt.Errorf("PostGISPoint.Scan: %w", err)
 	}
 
-	ewkb := make([]byte, hex.DecodedLen(len(v)))
-	n, err := hex.Decode(ewkb, v)
-	if err != nil {
-		return err
+	*p = g.(PostGISPoint)
+	return nil
+}
+
+// GeometryType implements PostGISGeometry interface.
+func (p PostGISPoint) GeometryType() string { return "POINT" }
+
+func (p PostGISPoint) geometrySRID() int { return p.SRID }
+
+func (p PostGISPoint) geometryLayout() PostGISLayout { return p.Layout }
+
+// wktText returns point as WKT text like "(1 2)" or "EMPTY".
+func (p PostGISPoint) wktText(layout PostGISLayout) string {
+	if isEmptyPoint(p) {
+		return "EMPTY"
 	}
+	return wktPointsText([]PostGISPoint{p}, layout)
+}
 
-	var ewkbP ewkbPoint
-	err = binary.Read(bytes.NewReader(ewkb[:n]), binary.LittleEndian, &ewkbP)
-	if err != nil {
-		return err
+// isEmptyPoint returns true for empty point, which has NaN Lon and Lat like POINT EMPTY in WKB.
+func isEmptyPoint(p PostGISPoint) bool {
+	return math.IsNaN(p.Lon) && math.IsNaN(p.Lat)
+}
+
+// postGISSRID checks value of SRID field and returns SRID for PostGIS:
+// PostGISDefaultSRID for zero value, 0 for PostGISUnknownSRID.
+func postGISSRID(srid int) (int, error) {
+	switch {
+	case srid == PostGISUnknownSRID:
+		return 0, nil
+	case srid < 0 || srid > postGISMaxSRID:
+		return 0, fmt.Errorf("invalid SRID %d", srid)
+	case srid == 0:
+		return PostGISDefaultSRID, nil
+	}
+	return srid, nil
+}
+
+// sridMatches returns true if value of SRID field of nested geometry is zero or matches SRID for PostGIS
+// of the enclosing geometry.
+func sridMatches(s, srid int) bool {
+	return s == 0 || s == srid || (s == PostGISUnknownSRID && srid == 0)
+}
+
+// wkt returns WKT for geometry with given tag, layout and text like "POINT(1 2)", "POINT Z (1 2 3)" or "POINT EMPTY".
+func wkt(tag string, layout PostGISLayout, text string) string {
+	if layout != PostGISLayoutXY {
+		tag += " " + layout.String()[2:]
 	}
+	if layout != PostGISLayoutXY || text == "EMPTY" {
+		tag += " "
+	}
+	return tag + text
+}
 
-	if ewkbP.ByteOrder != 1 || ewkbP.WkbType != 0x20000001 || ewkbP.SRID != 4326 {
-		return fmt.Errorf("PostGISPoint.Scan: unexpected ewkb %#v", ewkbP)
+// ewkt returns EWKT for geometry with given SRID, WKT tag, layout and text.
+func ewkt(srid int, tag string, layout PostGISLayout, text string) []byte {
+	return []byte(fmt.Sprintf("SRID=%d;%s", srid, wkt(tag, layout, text)))
+}
+
+// wktPointsText returns points as WKT text like "(1 2,3 4)" or "EMPTY".
+func wktPointsText(points []PostGISPoint, layout PostGISLayout) string {
+	if len(points) == 0 {
+		return "EMPTY"
+	}
+
+	parts := make([]string, len(points))
+	for i, pt := range points {
+		parts[i] = fmt.Sprintf("%.8f %.8f", pt.Lon, pt.Lat)
+		if layout.HasZ() {
+			parts[i] += fmt.Sprintf(" %.8f", pt.Z)
+		}
+		if layout.HasM() {
+			parts[i] += fmt.Sprintf(" %.8f", pt.M)
+		}
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// checkPoints checks that all points have zero SRID and layout or the same ones as the enclosing geometry.
+func checkPoints(points []PostGISPoint, srid int, layout PostGISLayout) error {
+	for i, p := range points {
+		if !sridMatches(p.SRID, srid) {
+			return fmt.Errorf("point %d has SRID %d, expected %d", i, p.SRID, srid)
+		}
+		if p.Layout != PostGISLayoutXY && p.Layout != layout {
+			return fmt.Errorf("point %d has layout %s, expected %s", i, p.Layout, layout)
+		}
 	}
-	*p = ewkbP.Point
 	return nil
 }
 
 // check interfaces
 var (
-	_ driver.Valuer = PostGISPoint{}
-	_ sql.Scanner   = &PostGISPoint{}
+	_ PostGISGeometry = PostGISPoint{}
+	_ sql.Scanner     = &PostGISPoint{}
 )
 
 // PostGISBox2D is wrapper for PostGIS Box2D type.
@@ -78,26 +171,29 @@ func (b PostGISBox2D) Value() (driver.Value, error) {
 }
 
 // Scan implements database/sql Scanner interface.
-// It expectes WKT.
+// It expects box2d text like "BOX(1 2,3 4)".
 func (b *PostGISBox2D) Scan(value interface{}) error {
 	if value == nil {
 		*b = PostGISBox2D{}
 		return nil
 	}
 
-	v, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("PostGISBox2D.Scan: expected []byte, got %T (%v)", value, value)
+	var s string
+	switch v := value.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("PostGISBox2D.Scan: expected []byte or string, got %T (%v)", value, value)
 	}
 
-	n, err := fmt.Sscanf(string(v), "BOX(%f %f,%f %f)", &b.Min.Lon, &b.Min.Lat, &b.Max.Lon, &b.Max.Lat)
+	res, err := parseBox2D(s)
 	if err != nil {
-		return err
-	}
-	if n != 4 {
-		return fmt.Errorf("PostGISBox2D.Scan: not enough params in the string: %v, %v != 4", v, n)
+		return fmt.Errorf("PostGISBox2D.Scan: %w", err)
 	}
 
+	*b = res
 	return nil
 }
 
@@ -108,99 +204,236 @@ var (
 )
 
 // PostGISPolygon is wrapper for PostGIS Polygon type.
+// Points is the exterior ring, Holes are interior rings.
+// SRID and Layout are handled like PostGISPoint's ones.
 type PostGISPolygon struct {
 	Points []PostGISPoint
+	Holes  [][]PostGISPoint
+	SRID   int
+	Layout PostGISLayout
+}
+
+// Rings returns all polygon rings: exterior ring followed by interior rings, or nil for empty polygon.
+func (p PostGISPolygon) Rings() [][]PostGISPoint {
+	if len(p.Points) == 0 && len(p.Holes) == 0 {
+		return nil
+	}
+	return append([][]PostGISPoint{p.Points}, p.Holes...)
 }
 
-// MakeEnvelope returns rectangular (min, max) polygon
+// MakeEnvelope returns rectangular (min, max) polygon with min's SRID
 func MakeEnvelope(min, max PostGISPoint) PostGISPolygon {
 	return PostGISPolygon{
-		Points: []PostGISPoint{min, {Lon: min.Lon, Lat: max.Lat}, max, {Lon: max.Lon, Lat: min.Lat}, min},
+		Points: []PostGISPoint{
+			min, {Lon: min.Lon, Lat: max.Lat, SRID: min.SRID}, max, {Lon: max.Lon, Lat: min.Lat, SRID: min.SRID}, min,
+		},
+		SRID: min.SRID,
 	}
 }
 
-// Min returns min side of rectangular polygon
+// AsEnvelope returns bounding box of polygon and true if polygon is an axis-aligned rectangle
+// (a closed ring of four corners without holes, starting at any corner and in any winding order),
+// like polygons returned by MakeEnvelope. For other polygons it returns bounding box and false.
+func (p PostGISPolygon) AsEnvelope() (PostGISBox2D, bool) {
+	return p.Envelope(), isRectangle(p.Points) && len(p.Holes) == 0
+}
+
+// Min returns min corner of polygon's bounding box.
+// Use AsEnvelope to check that polygon is rectangular.
 func (p *PostGISPolygon) Min() PostGISPoint {
-	if len(p.Points) != 5 || p.Points[0] != p.Points[4] ||
-		p.Points[0].Lon != p.Points[1].Lon || p.Points[0].Lat != p.Points[3].Lat ||
-		p.Points[1].Lat != p.Points[2].Lat || p.Points[2].Lon != p.Points[3].Lon {
-		panic("Not an envelope polygon")
+	b, _ := p.AsEnvelope()
+	return b.Min
+}
+
+// Max returns max corner of polygon's bounding box.
+// Use AsEnvelope to check that polygon is rectangular.
+func (p *PostGISPolygon) Max() PostGISPoint {
+	b, _ := p.AsEnvelope()
+	return b.Max
+}
+
+// isRectangle returns true if ring is closed and has four non-degenerate edges,
+// alternating between horizontal and vertical ones.
+func isRectangle(ring []PostGISPoint) bool {
+	if len(ring) != 5 || ring[0].Lon != ring[4].Lon || ring[0].Lat != ring[4].Lat {
+		return false
 	}
 
-	return p.Points[0]
+	horizontal := ring[0].Lat == ring[1].Lat
+	for i := 0; i < 4; i++ {
+		a, b := ring[i], ring[i+1]
+		if horizontal && (a.Lat != b.Lat || a.Lon == b.Lon) || !horizontal && (a.Lon != b.Lon || a.Lat == b.Lat) {
+			return false
+		}
+		horizontal = !horizontal
+	}
+	return true
 }
 
-// Max returns max side of rectangular polygon
-func (p *PostGISPolygon) Max() PostGISPoint {
-	if len(p.Points) != 5 || p.Points[0] != p.Points[4] ||
-		p.Points[0].Lon != p.Points[1].Lon || p.Points[0].Lat != p.Points[3].Lat ||
-		p.Points[1].Lat != p.Points[2].Lat || p.Points[2].Lon != p.Points[3].Lon {
-		panic("Not an envelope polygon")
+// Area returns planar area of polygon in square units of its spatial reference system,
+// like PostGIS ST_Area for geometry. Area of holes is subtracted.
+func (p PostGISPolygon) Area() float64 {
+	var area float64
+	for i, ring := range p.Rings() {
+		a := math.Abs(planarRingArea(ring))
+		if i == 0 {
+			area += a
+		} else {
+			area -= a
+		}
 	}
+	return area
+}
 
-	return p.Points[2]
+// Perimeter returns planar length of all polygon rings in units of its spatial reference system,
+// like PostGIS ST_Perimeter for geometry.
+func (p PostGISPolygon) Perimeter() float64 {
+	var perimeter float64
+	for _, ring := range p.Rings() {
+		perimeter += PostGISLineString{Points: ring}.Length()
+	}
+	return perimeter
 }
 
-// Value implements database/sql/driver Valuer interface.
-// It returns polygon as WKT with SRID 4326 (WGS 84).
-func (p PostGISPolygon) Value() (driver.Value, error) {
-	parts := make([]string, len(p.Points))
-	for i, pt := range p.Points {
-		parts[i] = fmt.Sprintf("%.8f %.8f", pt.Lon, pt.Lat)
+// Centroid returns planar center of mass of polygon with polygon's SRID, like PostGIS ST_Centroid for geometry.
+// For polygon with zero area, center of mass of its rings as line strings is returned.
+// It returns zero point for empty polygon.
+func (p PostGISPolygon) Centroid() PostGISPoint {
+	rings := p.Rings()
+	if len(rings) == 0 || len(rings[0]) == 0 {
+		return PostGISPoint{SRID: p.SRID}
 	}
-	return []byte(fmt.Sprintf("SRID=4326;POLYGON((%s))", strings.Join(parts, ","))), nil
+
+	// coordinates are relative to the first point for better precision
+	origin := rings[0][0]
+	var area, lon, lat float64
+	for i, ring := range rings {
+		var a, x, y float64
+		for j := 1; j < len(ring); j++ {
+			x1, y1 := ring[j-1].Lon-origin.Lon, ring[j-1].Lat-origin.Lat
+			x2, y2 := ring[j].Lon-origin.Lon, ring[j].Lat-origin.Lat
+			cross := x1*y2 - x2*y1
+			a += cross
+			x += (x1 + x2) * cross
+			y += (y1 + y2) * cross
+		}
+
+		// make exterior ring positive and holes negative regardless of orientation
+		if (a < 0) == (i == 0) {
+			a, x, y = -a, -x, -y
+		}
+		area += a / 2
+		lon += x / 6
+		lat += y / 6
+	}
+	if area != 0 {
+		return PostGISPoint{Lon: origin.Lon + lon/area, Lat: origin.Lat + lat/area, SRID: p.SRID}
+	}
+
+	// degenerate polygon
+	var length float64
+	lon, lat = 0, 0
+	for _, ring := range rings {
+		for j := 1; j < len(ring); j++ {
+			l := math.Hypot(ring[j].Lon-ring[j-1].Lon, ring[j].Lat-ring[j-1].Lat)
+			length += l
+			lon += l * (ring[j].Lon + ring[j-1].Lon) / 2
+			lat += l * (ring[j].Lat + ring[j-1].Lat) / 2
+		}
+	}
+	if length == 0 {
+		return PostGISPoint{Lon: origin.Lon, Lat: origin.Lat, SRID: p.SRID}
+	}
+	return PostGISPoint{Lon: lon / length, Lat: lat / length, SRID: p.SRID}
 }
 
-type ewkbPolygon struct {
-	ByteOrder byte   // 1 (LittleEndian)
-	WkbType   uint32 // 0x20000003 (PolygonS)
-	SRID      uint32 // 4326
-	Rings     uint32
-	Count     uint32
+// Envelope returns planar bounding box of polygon, like PostGIS Box2D function.
+// Box points have polygon's SRID. It returns zero box for empty polygon.
+func (p PostGISPolygon) Envelope() PostGISBox2D {
+	return pointsEnvelope(p.Points, p.SRID)
 }
 
-// Scan implements database/sql Scanner interface.
-// It expectes EWKB with SRID 4326 (WGS 84).
-func (p *PostGISPolygon) Scan(value interface{}) error {
-	if value == nil {
-		*p = PostGISPolygon{}
-		return nil
+// pointsEnvelope returns bounding box of points with points having the given SRID, or zero box for no points.
+func pointsEnvelope(points []PostGISPoint, srid int) PostGISBox2D {
+	if len(points) == 0 {
+		return PostGISBox2D{}
+	}
+	b := PostGISBox2D{
+		Min: PostGISPoint{Lon: points[0].Lon, Lat: points[0].Lat, SRID: srid},
+		Max: PostGISPoint{Lon: points[0].Lon, Lat: points[0].Lat, SRID: srid},
+	}
+	for _, p := range points[1:] {
+		b.Min.Lon, b.Min.Lat = math.Min(b.Min.Lon, p.Lon), math.Min(b.Min.Lat, p.Lat)
+		b.Max.Lon, b.Max.Lat = math.Max(b.Max.Lon, p.Lon), math.Max(b.Max.Lat, p.Lat)
 	}
+	return b
+}
 
-	v, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("PostGISPolygon.Scan: expected []byte, got %T (%v)", value, value)
+// planarRingArea returns signed planar area of closed ring: positive for counterclockwise ring.
+func planarRingArea(ring []PostGISPoint) float64 {
+	if len(ring) == 0 {
+		return 0
+	}
+	var sum float64
+	origin := ring[0]
+	for i := 1; i < len(ring); i++ {
+		x1, y1 := ring[i-1].Lon-origin.Lon, ring[i-1].Lat-origin.Lat
+		x2, y2 := ring[i].Lon-origin.Lon, ring[i].Lat-origin.Lat
+		sum += x1*y2 - x2*y1
 	}
+	return sum / 2
+}
 
-	ewkb := make([]byte, hex.DecodedLen(len(v)))
-	_, err := hex.Decode(ewkb, v)
-	if err != nil {
-		return err
+// GeometryType implements PostGISGeometry interface.
+func (p PostGISPolygon) GeometryType() string { return "POLYGON" }
+
+func (p PostGISPolygon) geometrySRID() int { return p.SRID }
+
+func (p PostGISPolygon) geometryLayout() PostGISLayout { return p.Layout }
+
+// wktText returns polygon as WKT text like "((1 2,3 4,5 6,1 2),(7 8,9 10,11 12,7 8))" or "EMPTY".
+func (p PostGISPolygon) wktText(layout PostGISLayout) string {
+	rings := p.Rings()
+	if len(rings) == 0 {
+		return "EMPTY"
 	}
 
-	r := bytes.NewReader(ewkb)
+	parts := make([]string, len(rings))
+	for i, ring := range rings {
+		parts[i] = wktPointsText(closeRing(ring), layout)
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
 
-	var ewkbP ewkbPolygon
-	err = binary.Read(r, binary.LittleEndian, &ewkbP)
+// Value implements database/sql/driver Valuer interface.
+// It returns polygon as EWKT with SRID. Rings which are not closed are closed by repeating their first points.
+func (p PostGISPolygon) Value() (driver.Value, error) {
+	v, err := postGISValue(p)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("PostGISPolygon.Value: %s", err)
 	}
+	return v, nil
+}
 
-	if ewkbP.ByteOrder != 1 || ewkbP.WkbType != 0x20000003 || ewkbP.SRID != 4326 || ewkbP.Rings != 1 {
-		return fmt.Errorf("PostGISPolygon.Scan: unexpected ewkb %#v", ewkbP)
+// Scan implements database/sql Scanner interface.
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout.
+func (p *PostGISPolygon) Scan(value interface{}) error {
+	if value == nil {
+		*p = PostGISPolygon{}
+		return nil
 	}
-	p.Points = make([]PostGISPoint, ewkbP.Count)
 
-	err = binary.Read(r, binary.LittleEndian, p.Points)
+	g, err := scanGeometry(value, wkbPolygon)
 	if err != nil {
-		return err
+		return fmt.Errorf("PostGISPolygon.Scan: %w", err)
 	}
 
+	*p = g.(PostGISPolygon)
 	return nil
 }
 
 // check interfaces
 var (
-	_ driver.Valuer = PostGISPolygon{}
-	_ sql.Scanner   = &PostGISPolygon{}
+	_ PostGISGeometry = PostGISPolygon{}
+	_ sql.Scanner     = &PostGISPolygon{}
 )
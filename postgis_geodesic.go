@@ -0,0 +1,589 @@
+package pq_types
+
+import (
+	"math"
+)
+
+// Karney's solution of the inverse geodesic problem, see C. F. F. Karney, Algorithms for geodesics,
+// J. Geodesy 87, 43-55 (2013), https://doi.org/10.1007/s00190-012-0578-z.
+// It is a port of the part of GeographicLib's geodesic.c used by PROJ and therefore by PostGIS,
+// reduced to distance and azimuths on WGS 84 ellipsoid with series of order 6.
+// Unlike Vincenty's formula, it converges for all pairs of points, including nearly antipodal ones.
+
+const karneyOrder = 6
+
+const (
+	karneyF1  = 1 - wgs84F
+	karneyE2  = wgs84F * (2 - wgs84F)
+	karneyEp2 = karneyE2 / (karneyF1 * karneyF1)
+	karneyN   = wgs84F / (2 - wgs84F)
+
+	karneyTol0   = 2.220446049250313e-16 // machine epsilon
+	karneyTol1   = 200 * karneyTol0
+	karneyTiny   = 1.4916681462400413e-154 // square root of the smallest normal float64
+	karneyMaxit1 = 20
+	karneyMaxit2 = karneyMaxit1 + 53 + 10
+	karneyDegree = math.Pi / 180
+)
+
+var (
+	karneyTol2    = math.Sqrt(karneyTol0)
+	karneyTolb    = karneyTol0 * karneyTol2
+	karneyXthresh = 1000 * karneyTol2
+	karneyEtol2   = 0.1 * karneyTol2 / math.Sqrt(math.Max(0.001, math.Abs(wgs84F))*math.Min(1, 1-wgs84F/2)/2)
+
+	karneyA3x = karneyA3coeff()
+	karneyC3x = karneyC3coeff()
+)
+
+// karneyInverse returns geodesic distance in meters between two points given in degrees on WGS 84 ellipsoid,
+// and initial and final azimuths in degrees clockwise from north in range [0, 360), using Karney's algorithm.
+func karneyInverse(lon1, lat1, lon2, lat2 float64) (dist, azi1, azi2 float64) {
+	var ca [karneyOrder + 1]float64
+
+	// bring points to the canonical form: 0 <= lon12 <= 180, -90 <= lat1 <= -0, lat1 <= lat2 <= -lat1
+	lon12 := math.Remainder(lon2-lon1, 360)
+	lonsign := 1.0
+	if math.Signbit(lon12) {
+		lonsign = -1
+	}
+	lon12 = lonsign * karneyAngRound(lon12)
+	lon12s := karneyAngRound(180 - lon12)
+	lam12 := lon12 * karneyDegree
+	var slam12, clam12 float64
+	if lon12 > 90 {
+		slam12, clam12 = karneySincosd(lon12s)
+		clam12 = -clam12
+	} else {
+		slam12, clam12 = karneySincosd(lon12)
+	}
+
+	lat1, lat2 = karneyAngRound(lat1), karneyAngRound(lat2)
+	swapp := 1.0
+	if math.Abs(lat1) < math.Abs(lat2) {
+		swapp = -1
+		lonsign = -lonsign
+		lat1, lat2 = lat2, lat1
+	}
+	latsign := -1.0
+	if math.Signbit(lat1) {
+		latsign = 1
+	}
+	lat1 *= latsign
+	lat2 *= latsign
+
+	sbet1, cbet1 := karneySincosd(lat1)
+	sbet1 *= karneyF1
+	sbet1, cbet1 = karneyNorm(sbet1, cbet1)
+	cbet1 = math.Max(karneyTiny, cbet1)
+
+	sbet2, cbet2 := karneySincosd(lat2)
+	sbet2 *= karneyF1
+	sbet2, cbet2 = karneyNorm(sbet2, cbet2)
+	cbet2 = math.Max(karneyTiny, cbet2)
+
+	// force bet2 = +/- bet1 exactly when they are very close
+	if cbet1 < -sbet1 {
+		if cbet2 == cbet1 {
+			sbet2 = math.Copysign(sbet1, sbet2)
+		}
+	} else if math.Abs(sbet2) == -sbet1 {
+		cbet2 = cbet1
+	}
+
+	dn1 := math.Sqrt(1 + karneyEp2*sbet1*sbet1)
+	dn2 := math.Sqrt(1 + karneyEp2*sbet2*sbet2)
+
+	var s12x, m12x, sig12, salp1, calp1, salp2, calp2 float64
+	meridian := lat1 == -90 || slam12 == 0
+	if meridian {
+		// endpoints are on a single full meridian, so the geodesic might lie on it
+		calp1, salp1 = clam12, slam12
+		calp2, salp2 = 1, 0
+
+		ssig1, csig1 := sbet1, calp1*cbet1
+		ssig2, csig2 := sbet2, calp2*cbet2
+		sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+		s12x, m12x = karneyLengths(karneyN, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, ca[:])
+		if sig12 < 1 || m12x >= 0 {
+			if sig12 < 3*karneyTiny || (sig12 < karneyTol0 && (s12x < 0 || m12x < 0)) {
+				sig12, m12x, s12x = 0, 0, 0
+			}
+			s12x *= wgs84B
+		} else {
+			// too close to antipodal
+			meridian = false
+		}
+	}
+
+	switch {
+	case meridian:
+		// done above
+
+	case sbet1 == 0 && lon12s >= wgs84F*180:
+		// geodesic runs along equator
+		calp1, calp2 = 0, 0
+		salp1, salp2 = 1, 1
+		s12x = wgs84A * lam12
+
+	default:
+		var dnm float64
+		sig12, salp1, calp1, salp2, calp2, dnm = karneyInverseStart(sbet1, cbet1, sbet2, cbet2, lam12, slam12, clam12, ca[:])
+
+		if sig12 >= 0 {
+			// short line
+			s12x = sig12 * wgs84B * dnm
+			break
+		}
+
+		// Newton's method for f(alp1) = lambda12(alp1) - lam12 = 0, with bracketing (alp1a, alp1b) of the root,
+		// and bisection when Newton's step is not usable
+		var ssig1, csig1, ssig2, csig2, eps float64
+		salp1a, calp1a, salp1b, calp1b := karneyTiny, 1.0, karneyTiny, -1.0
+		var tripn, tripb bool
+		for numit := 0; ; numit++ {
+			var v, dv float64
+			v, dv, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps = karneyLambda12(
+				sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam12, clam12, numit < karneyMaxit1, ca[:])
+			tol := karneyTol0
+			if tripn {
+				tol *= 8
+			}
+			if tripb || !(math.Abs(v) >= tol) || numit == karneyMaxit2 {
+				break
+			}
+
+			if v > 0 && (numit > karneyMaxit1 || calp1/salp1 > calp1b/salp1b) {
+				salp1b, calp1b = salp1, calp1
+			} else if v < 0 && (numit > karneyMaxit1 || calp1/salp1 < calp1a/salp1a) {
+				salp1a, calp1a = salp1, calp1
+			}
+			if numit < karneyMaxit1 && dv > 0 {
+				dalp1 := -v / dv
+				if math.Abs(dalp1) < math.Pi {
+					sdalp1, cdalp1 := math.Sincos(dalp1)
+					nsalp1 := salp1*cdalp1 + calp1*sdalp1
+					if nsalp1 > 0 {
+						calp1 = calp1*cdalp1 - salp1*sdalp1
+						salp1 = nsalp1
+						salp1, calp1 = karneyNorm(salp1, calp1)
+						tripn = math.Abs(v) <= 16*karneyTol0
+						continue
+					}
+				}
+			}
+
+			salp1, calp1 = karneyNorm((salp1a+salp1b)/2, (calp1a+calp1b)/2)
+			tripn = false
+			tripb = math.Abs(salp1a-salp1)+(calp1a-calp1) < karneyTolb || math.Abs(salp1-salp1b)+(calp1-calp1b) < karneyTolb
+		}
+		s12x, _ = karneyLengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, ca[:])
+		s12x *= wgs84B
+	}
+
+	// undo the transformation to the canonical form
+	if swapp < 0 {
+		salp1, salp2 = salp2, salp1
+		calp1, calp2 = calp2, calp1
+	}
+	salp1 *= swapp * lonsign
+	calp1 *= swapp * latsign
+	salp2 *= swapp * lonsign
+	calp2 *= swapp * latsign
+
+	azi1 = normalizeBearing(degrees(math.Atan2(salp1, calp1)))
+	azi2 = normalizeBearing(degrees(math.Atan2(salp2, calp2)))
+	return 0 + s12x, azi1, azi2
+}
+
+// karneyInverseStart returns a starting point for Newton's method, or the solution for short lines
+// with non-negative sig12 and dnm.
+func karneyInverseStart(sbet1, cbet1, sbet2, cbet2, lam12, slam12, clam12 float64, ca []float64) (
+	sig12, salp1, calp1, salp2, calp2, dnm float64) {
+	sig12 = -1
+	sbet12 := sbet2*cbet1 - cbet2*sbet1
+	cbet12 := cbet2*cbet1 + sbet2*sbet1
+	sbet12a := sbet2*cbet1 + cbet2*sbet1
+
+	shortline := cbet12 >= 0 && sbet12 < 0.5 && cbet2*lam12 < 0.5
+	var somg12, comg12 float64
+	if shortline {
+		sbetm2 := (sbet1 + sbet2) * (sbet1 + sbet2)
+		sbetm2 /= sbetm2 + (cbet1+cbet2)*(cbet1+cbet2)
+		dnm = math.Sqrt(1 + karneyEp2*sbetm2)
+		somg12, comg12 = math.Sincos(lam12 / (karneyF1 * dnm))
+	} else {
+		somg12, comg12 = slam12, clam12
+	}
+
+	salp1 = cbet2 * somg12
+	if comg12 >= 0 {
+		calp1 = sbet12 + cbet2*sbet1*somg12*somg12/(1+comg12)
+	} else {
+		calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+	}
+	ssig12 := math.Hypot(salp1, calp1)
+	csig12 := sbet1*sbet2 + cbet1*cbet2*comg12
+
+	switch {
+	case shortline && ssig12 < karneyEtol2:
+		// really short line
+		salp2 = cbet1 * somg12
+		if comg12 >= 0 {
+			calp2 = sbet12 - cbet1*sbet2*somg12*somg12/(1+comg12)
+		} else {
+			calp2 = sbet12 - cbet1*sbet2*(1-comg12)
+		}
+		salp2, calp2 = karneyNorm(salp2, calp2)
+		sig12 = math.Atan2(ssig12, csig12)
+
+	case math.Abs(karneyN) > 0.1 || csig12 >= 0 || ssig12 >= 6*math.Abs(karneyN)*math.Pi*cbet1*cbet1:
+		// zeroth order spherical approximation is good enough
+
+	default:
+		// nearly antipodal points: scale lam12 and bet2 to x, y coordinate system
+		// where antipodal point is at origin and singular point is at y = 0, x = -1
+		lam12x := math.Atan2(-slam12, -clam12) // lam12 - pi
+		k2 := sbet1 * sbet1 * karneyEp2
+		eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+		lamscale := wgs84F * cbet1 * karneyA3f(eps) * math.Pi
+		betscale := lamscale * cbet1
+		x := lam12x / lamscale
+		y := sbet12a / betscale
+
+		if y > -karneyTol1 && x > -1-karneyXthresh {
+			salp1 = math.Min(1, -x)
+			calp1 = -math.Sqrt(1 - salp1*salp1)
+		} else {
+			// estimate omg12 by solving the astroid problem, and use spherical formula to compute alp1
+			k := karneyAstroid(x, y)
+			omg12a := lamscale * (-x * k / (1 + k))
+			somg12, comg12 = math.Sincos(omg12a)
+			comg12 = -comg12
+			salp1 = cbet2 * somg12
+			calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+		}
+	}
+
+	if !(salp1 <= 0) {
+		salp1, calp1 = karneyNorm(salp1, calp1)
+	} else {
+		salp1, calp1 = 1, 0
+	}
+	return sig12, salp1, calp1, salp2, calp2, dnm
+}
+
+// karneyLambda12 returns the difference of longitude difference of geodesic with initial azimuth alp1
+// and the target one, its derivative by alp1 (if diffp is true), and intermediate values.
+func karneyLambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam120, clam120 float64, diffp bool, ca []float64) (
+	lam12, dlam12, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps float64) {
+	if sbet1 == 0 && calp1 == 0 {
+		// break degeneracy of equatorial line
+		calp1 = -karneyTiny
+	}
+
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	ssig1 = sbet1
+	somg1 := salp0 * sbet1
+	csig1 = calp1 * cbet1
+	comg1 := csig1
+	ssig1, csig1 = karneyNorm(ssig1, csig1)
+
+	if cbet2 != cbet1 {
+		salp2 = salp0 / cbet2
+	} else {
+		salp2 = salp1
+	}
+	if cbet2 != cbet1 || math.Abs(sbet2) != -sbet1 {
+		var d float64
+		if cbet1 < -sbet1 {
+			d = (cbet2 - cbet1) * (cbet1 + cbet2)
+		} else {
+			d = (sbet1 - sbet2) * (sbet1 + sbet2)
+		}
+		calp2 = math.Sqrt(calp1*cbet1*calp1*cbet1+d) / cbet2
+	} else {
+		calp2 = math.Abs(calp1)
+	}
+
+	ssig2 = sbet2
+	somg2 := salp0 * sbet2
+	csig2 = calp2 * cbet2
+	comg2 := csig2
+	ssig2, csig2 = karneyNorm(ssig2, csig2)
+
+	sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+	somg12 := math.Max(0, comg1*somg2-somg1*comg2)
+	comg12 := comg1*comg2 + somg1*somg2
+	eta := math.Atan2(somg12*clam120-comg12*slam120, comg12*clam120+somg12*slam120)
+
+	k2 := calp0 * calp0 * karneyEp2
+	eps = k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+	karneyC3f(eps, ca)
+	b312 := karneySinCosSeries(ssig2, csig2, ca, karneyOrder-1) - karneySinCosSeries(ssig1, csig1, ca, karneyOrder-1)
+	lam12 = eta - wgs84F*karneyA3f(eps)*salp0*(sig12+b312)
+
+	if diffp {
+		if calp2 == 0 {
+			dlam12 = -2 * karneyF1 * dn1 / sbet1
+		} else {
+			_, dlam12 = karneyLengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, ca)
+			dlam12 *= karneyF1 / (calp2 * cbet2)
+		}
+	}
+	return lam12, dlam12, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps
+}
+
+// karneyLengths returns distance and reduced length divided by semi-minor axis.
+func karneyLengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2 float64, ca []float64) (s12b, m12b float64) {
+	var cb [karneyOrder + 1]float64
+	a1 := karneyA1m1f(eps)
+	karneyC1f(eps, ca)
+	a2 := karneyA2m1f(eps)
+	karneyC2f(eps, cb[:])
+	m0 := a1 - a2
+	a1++
+	a2++
+
+	b1 := karneySinCosSeries(ssig2, csig2, ca, karneyOrder) - karneySinCosSeries(ssig1, csig1, ca, karneyOrder)
+	b2 := karneySinCosSeries(ssig2, csig2, cb[:], karneyOrder) - karneySinCosSeries(ssig1, csig1, cb[:], karneyOrder)
+	s12b = a1 * (sig12 + b1)
+	j12 := m0*sig12 + (a1*b1 - a2*b2)
+	m12b = dn2*(csig1*ssig2) - dn1*(ssig1*csig2) - csig1*csig2*j12
+	return s12b, m12b
+}
+
+// karneyAstroid returns the positive root k of k^4 + 2k^3 - (x^2 + y^2 - 1)k^2 - 2y^2k - y^2 = 0.
+func karneyAstroid(x, y float64) float64 {
+	p := x * x
+	q := y * y
+	r := (p + q - 1) / 6
+	if q == 0 && r <= 0 {
+		return 0
+	}
+
+	s := p * q / 4
+	r2 := r * r
+	r3 := r * r2
+	disc := s * (s + 2*r3)
+	u := r
+	if disc >= 0 {
+		t3 := s + r3
+		if t3 < 0 {
+			t3 -= math.Sqrt(disc)
+		} else {
+			t3 += math.Sqrt(disc)
+		}
+		t := math.Cbrt(t3)
+		u += t
+		if t != 0 {
+			u += r2 / t
+		}
+	} else {
+		ang := math.Atan2(math.Sqrt(-disc), -(s + r3))
+		u += 2 * r * math.Cos(ang/3)
+	}
+	v := math.Sqrt(u*u + q)
+	var uv float64
+	if u < 0 {
+		uv = q / (v - u)
+	} else {
+		uv = u + v
+	}
+	w := (uv - q) / (2 * v)
+	return uv / (math.Sqrt(uv+w*w) + w)
+}
+
+// karneySinCosSeries returns sum of c[i] * sin(2*i*x) for i from 1 to n using Clenshaw summation.
+func karneySinCosSeries(sinx, cosx float64, c []float64, n int) float64 {
+	i := n + 1
+	ar := 2 * (cosx - sinx) * (cosx + sinx)
+	var y0, y1 float64
+	if n&1 != 0 {
+		i--
+		y0 = c[i]
+	}
+	for n /= 2; n > 0; n-- {
+		i--
+		y1 = ar*y0 - y1 + c[i]
+		i--
+		y0 = ar*y1 - y0 + c[i]
+	}
+	return 2 * sinx * cosx * y0
+}
+
+// karneyPolyval evaluates polynomial of degree n with coefficients p from the highest degree.
+func karneyPolyval(n int, p []float64, x float64) float64 {
+	if n < 0 {
+		return 0
+	}
+	y := p[0]
+	for i := 1; i <= n; i++ {
+		y = y*x + p[i]
+	}
+	return y
+}
+
+// karneyA1m1f returns A1 - 1.
+func karneyA1m1f(eps float64) float64 {
+	coeff := []float64{1, 4, 64, 0, 256}
+	m := karneyOrder / 2
+	t := karneyPolyval(m, coeff, eps*eps) / coeff[m+1]
+	return (t + eps) / (1 - eps)
+}
+
+// karneyC1f sets coefficients C1[l] for l from 1 to karneyOrder.
+func karneyC1f(eps float64, c []float64) {
+	coeff := []float64{
+		-1, 6, -16, 32,
+		-9, 64, -128, 2048,
+		9, -16, 768,
+		3, -5, 512,
+		-7, 1280,
+		-7, 2048,
+	}
+	karneySeriesCoeffs(eps, coeff, c)
+}
+
+// karneyA2m1f returns A2 - 1.
+func karneyA2m1f(eps float64) float64 {
+	coeff := []float64{-11, -28, -192, 0, 256}
+	m := karneyOrder / 2
+	t := karneyPolyval(m, coeff, eps*eps) / coeff[m+1]
+	return (t - eps) / (1 + eps)
+}
+
+// karneyC2f sets coefficients C2[l] for l from 1 to karneyOrder.
+func karneyC2f(eps float64, c []float64) {
+	coeff := []float64{
+		1, 2, 16, 32,
+		35, 64, 384, 2048,
+		15, 80, 768,
+		7, 35, 512,
+		63, 1280,
+		77, 2048,
+	}
+	karneySeriesCoeffs(eps, coeff, c)
+}
+
+// karneySeriesCoeffs sets c[l] = eps^l * P_l(eps^2) for l from 1 to karneyOrder,
+// where polynomials P_l are given by packed coefficients with denominators.
+func karneySeriesCoeffs(eps float64, coeff, c []float64) {
+	eps2 := eps * eps
+	d := eps
+	o := 0
+	for l := 1; l <= karneyOrder; l++ {
+		m := (karneyOrder - l) / 2
+		c[l] = d * karneyPolyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+// karneyA3coeff returns coefficients of A3 as polynomial in eps for WGS 84 third flattening.
+func karneyA3coeff() []float64 {
+	coeff := []float64{
+		-3, 128,
+		-2, -3, 64,
+		-1, -3, -1, 16,
+		3, -1, -2, 8,
+		1, -1, 2,
+		1, 1,
+	}
+	res := make([]float64, 0, karneyOrder)
+	o := 0
+	for j := karneyOrder - 1; j >= 0; j-- {
+		m := karneyOrder - j - 1
+		if j < m {
+			m = j
+		}
+		res = append(res, karneyPolyval(m, coeff[o:], karneyN)/coeff[o+m+1])
+		o += m + 2
+	}
+	return res
+}
+
+// karneyC3coeff returns coefficients of C3 as polynomials in eps for WGS 84 third flattening.
+func karneyC3coeff() []float64 {
+	coeff := []float64{
+		3, 128,
+		2, 5, 128,
+		-1, 3, 3, 64,
+		-1, 0, 1, 8,
+		-1, 1, 4,
+		5, 256,
+		1, 3, 128,
+		-3, -2, 3, 64,
+		1, -3, 2, 32,
+		7, 512,
+		-10, 9, 384,
+		5, -9, 5, 192,
+		7, 512,
+		-14, 7, 512,
+		21, 2560,
+	}
+	res := make([]float64, 0, karneyOrder*(karneyOrder-1)/2)
+	o := 0
+	for l := 1; l < karneyOrder; l++ {
+		for j := karneyOrder - 1; j >= l; j-- {
+			m := karneyOrder - j - 1
+			if j < m {
+				m = j
+			}
+			res = append(res, karneyPolyval(m, coeff[o:], karneyN)/coeff[o+m+1])
+			o += m + 2
+		}
+	}
+	return res
+}
+
+// karneyA3f returns A3.
+func karneyA3f(eps float64) float64 {
+	return karneyPolyval(karneyOrder-1, karneyA3x, eps)
+}
+
+// karneyC3f sets coefficients C3[l] for l from 1 to karneyOrder-1.
+func karneyC3f(eps float64, c []float64) {
+	mult := 1.0
+	o := 0
+	for l := 1; l < karneyOrder; l++ {
+		m := karneyOrder - l - 1
+		mult *= eps
+		c[l] = mult * karneyPolyval(m, karneyC3x[o:], eps)
+		o += m + 1
+	}
+}
+
+// karneyNorm normalizes sine and cosine.
+func karneyNorm(s, c float64) (float64, float64) {
+	r := math.Hypot(s, c)
+	return s / r, c / r
+}
+
+// karneyAngRound rounds tiny angle in degrees to zero to avoid underflows.
+func karneyAngRound(x float64) float64 {
+	const z = 1.0 / 16
+	y := math.Abs(x)
+	if w := z - y; w > 0 {
+		y = z - w
+	}
+	return math.Copysign(y, x)
+}
+
+// karneySincosd returns sine and cosine of angle in degrees, exact for multiples of 90.
+func karneySincosd(x float64) (s, c float64) {
+	r := math.Mod(x, 360)
+	q := int(math.Round(r / 90))
+	s, c = math.Sincos(radians(r - 90*float64(q)))
+	switch q & 3 {
+	case 1:
+		s, c = c, -s
+	case 2:
+		s, c = -s, -c
+	case 3:
+		s, c = -c, s
+	}
+	return s, c + 0
+}
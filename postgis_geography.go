@@ -0,0 +1,560 @@
+package pq_types
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+)
+
+// WGS 84 ellipsoid used by PostGIS geography type with SRID 4326.
+const (
+	wgs84A = 6378137.0         // semi-major axis, meters
+	wgs84F = 1 / 298.257223563 // flattening
+	wgs84B = wgs84A * (1 - wgs84F)
+
+	// wgs84MeanRadius is mean radius (2a+b)/3, used for spherical calculations.
+	wgs84MeanRadius = (2*wgs84A + wgs84B) / 3
+)
+
+// PostGISGeographyPoint is wrapper for PostGIS geography(POINT, 4326) type.
+// It has the same fields as PostGISPoint (use conversion to get one), but coordinates are always longitude and latitude
+// in degrees on WGS 84 ellipsoid, and distances are in meters.
+//
+// SRID should be zero or PostGISDefaultSRID.
+// Value returns EWKT which PostgreSQL casts to geography when parameter type is known,
+// for example, when it is inserted into geography column; use "$1::geography" in other cases.
+type PostGISGeographyPoint PostGISPoint
+
+// Distance returns distance to point q in meters along the geodesic on WGS 84 ellipsoid,
+// like PostGIS ST_Distance for geography with default use_spheroid = true.
+// See PostGISPoint.VincentyDistance for details.
+func (p PostGISGeographyPoint) Distance(q PostGISGeographyPoint) float64 {
+	return PostGISPoint(p).VincentyDistance(PostGISPoint(q))
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It returns point as EWKT with SRID.
+func (p PostGISGeographyPoint) Value() (driver.Value, error) {
+	if err := checkGeography(PostGISPoint(p).SRID, [][]PostGISPoint{{PostGISPoint(p)}}); err != nil {
+		return nil, fmt.Errorf("PostGISGeographyPoint.Value: %s", err)
+	}
+	v, err := postGISValue(PostGISPoint(p))
+	if err != nil {
+		return nil, fmt.Errorf("PostGISGeographyPoint.Value: %s", err)
+	}
+	return v, nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It accepts the same values as PostGISPoint.Scan with SRID 4326 or without SRID.
+func (p *PostGISGeographyPoint) Scan(value interface{}) error {
+	var res PostGISPoint
+	if err := res.Scan(value); err != nil {
+		return fmt.Errorf("PostGISGeographyPoint.Scan: %w", err)
+	}
+	if err := checkGeographySRID(res.SRID); err != nil {
+		return fmt.Errorf("PostGISGeographyPoint.Scan: %s", err)
+	}
+
+	*p = PostGISGeographyPoint(res)
+	return nil
+}
+
+// HaversineDistance returns great circle distance to point q in meters on sphere with mean Earth radius 6371008.77 m,
+// like PostGIS ST_Distance for geography with use_spheroid = false. Results match it within 1 mm.
+// It differs from the distance on WGS 84 ellipsoid by up to 0.5%.
+// Coordinates are treated as longitude and latitude in degrees regardless of SRID.
+func (p PostGISPoint) HaversineDistance(q PostGISPoint) float64 {
+	return sphericalDistance(p.Lon, p.Lat, q.Lon, q.Lat, wgs84MeanRadius)
+}
+
+// VincentyDistance returns distance to point q in meters along the geodesic on WGS 84 ellipsoid,
+// like PostGIS ST_Distance for geography with default use_spheroid = true. Results match it within 1 cm.
+// It uses Vincenty's inverse formula, and Karney's algorithm (which PostGIS uses) for nearly antipodal points
+// where the formula does not converge.
+// Coordinates are treated as longitude and latitude in degrees regardless of SRID.
+func (p PostGISPoint) VincentyDistance(q PostGISPoint) float64 {
+	d, _, _ := geodesicInverse(p.Lon, p.Lat, q.Lon, q.Lat)
+	return d
+}
+
+// Bearing returns initial bearing (azimuth) from this point to point q in degrees clockwise from north in range [0, 360)
+// along the geodesic on WGS 84 ellipsoid, like PostGIS ST_Azimuth for geography (which returns radians).
+// Results match it within 1e-6 degrees. Bearing to the same point is zero.
+// See PostGISPoint.VincentyDistance for details.
+// Coordinates are treated as longitude and latitude in degrees regardless of SRID.
+func (p PostGISPoint) Bearing(q PostGISPoint) float64 {
+	_, azi, _ := geodesicInverse(p.Lon, p.Lat, q.Lon, q.Lat)
+	return azi
+}
+
+// Destination returns point at distance dist in meters from this point with initial bearing in degrees clockwise from north
+// along the geodesic on WGS 84 ellipsoid, like PostGIS ST_Project for geography (which takes azimuth in radians).
+// Results match it within 1 cm. Longitude of the result is normalized to range [-180, 180].
+// Returned point has the same SRID, and zero Z, M and Layout.
+// Coordinates are treated as longitude and latitude in degrees regardless of SRID.
+func (p PostGISPoint) Destination(dist, bearing float64) PostGISPoint {
+	lon, lat, _ := vincentyDirect(p.Lon, p.Lat, bearing, dist)
+	return PostGISPoint{Lon: lon, Lat: lat, SRID: p.SRID}
+}
+
+// PostGISGeographyPolygon is wrapper for PostGIS geography(POLYGON, 4326) type.
+// It has the same fields as PostGISPolygon; see PostGISGeographyPoint for details.
+type PostGISGeographyPolygon PostGISPolygon
+
+// Area returns area of polygon in square meters on WGS 84 ellipsoid,
+// like PostGIS ST_Area for geography with default use_spheroid = true.
+// See PostGISPolygon.GeodesicArea for details.
+func (p PostGISGeographyPolygon) Area() float64 {
+	return PostGISPolygon(p).GeodesicArea()
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It returns polygon as EWKT with SRID.
+func (p PostGISGeographyPolygon) Value() (driver.Value, error) {
+	if err := checkGeography(p.SRID, PostGISPolygon(p).Rings()); err != nil {
+		return nil, fmt.Errorf("PostGISGeographyPolygon.Value: %s", err)
+	}
+	v, err := postGISValue(PostGISPolygon(p))
+	if err != nil {
+		return nil, fmt.Errorf("PostGISGeographyPolygon.Value: %s", err)
+	}
+	return v, nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It accepts the same values as PostGISPolygon.Scan with SRID 4326 or without SRID.
+func (p *PostGISGeographyPolygon) Scan(value interface{}) error {
+	var res PostGISPolygon
+	if err := res.Scan(value); err != nil {
+		return fmt.Errorf("PostGISGeographyPolygon.Scan: %w", err)
+	}
+	if err := checkGeographySRID(res.SRID); err != nil {
+		return fmt.Errorf("PostGISGeographyPolygon.Scan: %s", err)
+	}
+
+	*p = PostGISGeographyPolygon(res)
+	return nil
+}
+
+// GeodesicArea returns area of polygon in square meters on WGS 84 ellipsoid,
+// like PostGIS ST_Area for geography with default use_spheroid = true.
+// Area of holes is subtracted. Rings which enclose a pole are supported; the smaller of two regions bounded by ring is used.
+// Coordinates are treated as longitude and latitude in degrees regardless of SRID.
+//
+// Edges are split into segments up to 100 km long along geodesics, and the area is calculated exactly
+// on the authalic (equal-area) sphere with segments being great circle arcs on it. The relative error is below 1e-6.
+func (p PostGISPolygon) GeodesicArea() float64 {
+	var area float64
+	for i, ring := range p.Rings() {
+		a := geodesicRingArea(ring)
+		if i == 0 {
+			area += a
+		} else {
+			area -= a
+		}
+	}
+	return area
+}
+
+// GeodesicPerimeter returns length of all polygon rings in meters along geodesics on WGS 84 ellipsoid,
+// like PostGIS ST_Perimeter for geography with default use_spheroid = true.
+// See PostGISPoint.VincentyDistance for details.
+func (p PostGISPolygon) GeodesicPerimeter() float64 {
+	var perimeter float64
+	for _, ring := range p.Rings() {
+		for i := 1; i < len(ring); i++ {
+			perimeter += ring[i-1].VincentyDistance(ring[i])
+		}
+	}
+	return perimeter
+}
+
+// GeodesicCentroid returns center of mass of polygon on sphere with polygon's SRID, like PostGIS ST_Centroid for geography.
+// Polygon is split into spherical triangles, and their centers are averaged with weights equal to their areas.
+// For polygon with zero area, the average of its points is returned. It returns zero point for empty polygon.
+// Coordinates are treated as longitude and latitude in degrees regardless of SRID.
+func (p PostGISPolygon) GeodesicCentroid() PostGISPoint {
+	rings := p.Rings()
+	if len(rings) == 0 || len(rings[0]) == 0 {
+		return PostGISPoint{SRID: p.SRID}
+	}
+
+	var sum vector3
+	for i, ring := range rings {
+		if len(ring) < 3 {
+			continue
+		}
+		var ringSum vector3
+		var ringArea float64
+		a := unitVector(ring[0])
+		for j := 1; j+1 < len(ring); j++ {
+			b, c := unitVector(ring[j]), unitVector(ring[j+1])
+			area := sphericalTriangleArea(a, b, c)
+			ringSum = ringSum.add(a.add(b).add(c).normalize().scale(area))
+			ringArea += area
+		}
+
+		// make exterior ring positive and holes negative regardless of orientation
+		if (ringArea < 0) == (i == 0) {
+			ringSum = ringSum.scale(-1)
+		}
+		sum = sum.add(ringSum)
+	}
+
+	if sum.norm() < 1e-15 {
+		// degenerate polygon
+		sum = vector3{}
+		for _, pt := range rings[0] {
+			sum = sum.add(unitVector(pt))
+		}
+		if sum.norm() == 0 {
+			return PostGISPoint{Lon: rings[0][0].Lon, Lat: rings[0][0].Lat, SRID: p.SRID}
+		}
+	}
+	res := sum.lonLat()
+	res.SRID = p.SRID
+	return res
+}
+
+// GeodesicEnvelope returns bounding box of polygon with geodesic edges on WGS 84 ellipsoid.
+// Unlike Envelope, it includes parts of edges which are closer to a pole than their ends.
+// For polygon crossing the antimeridian, Min.Lon is greater than Max.Lon.
+// For polygon enclosing a pole, longitudes are from -180 to 180, and latitude is extended to the pole.
+// Box points have polygon's SRID. It returns zero box for empty polygon.
+// Coordinates are treated as longitude and latitude in degrees regardless of SRID.
+func (p PostGISPolygon) GeodesicEnvelope() PostGISBox2D {
+	ring := p.Points
+	if len(ring) == 0 {
+		return PostGISBox2D{}
+	}
+
+	b := pointsEnvelope(ring, p.SRID)
+	lon, minLon, maxLon := ring[0].Lon, ring[0].Lon, ring[0].Lon
+	for i := 1; i < len(ring); i++ {
+		p1, p2 := ring[i-1], ring[i]
+		lon += math.Remainder(p2.Lon-p1.Lon, 360)
+		minLon, maxLon = math.Min(minLon, lon), math.Max(maxLon, lon)
+
+		// edge reaches the northernmost or southernmost point of its geodesic between ends
+		_, azi1, azi2 := geodesicInverse(p1.Lon, p1.Lat, p2.Lon, p2.Lat)
+		cos1, cos2 := math.Cos(radians(azi1)), math.Cos(radians(azi2))
+		if (cos1 > 0) == (cos2 > 0) || cos1 == 0 || cos2 == 0 {
+			continue
+		}
+		beta1 := math.Atan((1 - wgs84F) * math.Tan(radians(p1.Lat)))
+		betaMax := math.Acos(math.Abs(math.Sin(radians(azi1)) * math.Cos(beta1)))
+		latMax := degrees(math.Atan2(math.Sin(betaMax), (1-wgs84F)*math.Cos(betaMax)))
+		if cos1 > 0 {
+			b.Max.Lat = math.Max(b.Max.Lat, latMax)
+		} else {
+			b.Min.Lat = math.Min(b.Min.Lat, -latMax)
+		}
+	}
+
+	if math.Abs(lon-ring[0].Lon) > 180 {
+		// ring encloses a pole
+		b.Min.Lon, b.Max.Lon = -180, 180
+		if b.Min.Lat+b.Max.Lat > 0 {
+			b.Max.Lat = 90
+		} else {
+			b.Min.Lat = -90
+		}
+		return b
+	}
+
+	offset := 360 * math.Floor((minLon+180)/360)
+	b.Min.Lon, b.Max.Lon = minLon-offset, maxLon-offset
+	if b.Max.Lon > 180 {
+		b.Max.Lon -= 360
+	}
+	return b
+}
+
+// checkGeographySRID checks that SRID is zero or PostGISDefaultSRID.
+func checkGeographySRID(srid int) error {
+	if srid != 0 && srid != PostGISDefaultSRID {
+		return fmt.Errorf("geography requires SRID %d, got %d", PostGISDefaultSRID, srid)
+	}
+	return nil
+}
+
+// checkGeography checks geography SRID and that latitudes of all points are in range.
+func checkGeography(srid int, rings [][]PostGISPoint) error {
+	if err := checkGeographySRID(srid); err != nil {
+		return err
+	}
+	for _, ring := range rings {
+		for _, p := range ring {
+			if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+				return fmt.Errorf("latitude %v is out of range [-90, 90]", p.Lat)
+			}
+		}
+	}
+	return nil
+}
+
+// radians converts degrees to radians.
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// degrees converts radians to degrees.
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// sphericalDistance returns great circle distance between two points given in degrees on sphere with radius r
+// using haversine formula.
+func sphericalDistance(lon1, lat1, lon2, lat2, r float64) float64 {
+	phi1, phi2 := radians(lat1), radians(lat2)
+	sinDPhi := math.Sin((phi2 - phi1) / 2)
+	sinDLambda := math.Sin(radians(lon2-lon1) / 2)
+	h := sinDPhi*sinDPhi + math.Cos(phi1)*math.Cos(phi2)*sinDLambda*sinDLambda
+	return 2 * r * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// normalizeBearing returns bearing in degrees normalized to range [0, 360).
+func normalizeBearing(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	if deg == 360 {
+		return 0
+	}
+	return deg
+}
+
+// vincentyInverse returns geodesic distance in meters between two points given in degrees on WGS 84 ellipsoid,
+// and initial and final azimuths in degrees clockwise from north, using Vincenty's inverse formula.
+// The last result is false if the formula does not converge, which happens for nearly antipodal points.
+func vincentyInverse(lon1, lat1, lon2, lat2 float64) (dist, azi1, azi2 float64, ok bool) {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+	)
+
+	L := math.Remainder(radians(lon2-lon1), 2*math.Pi)
+	tanU1 := (1 - wgs84F) * math.Tan(radians(lat1))
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
+	tanU2 := (1 - wgs84F) * math.Tan(radians(lat2))
+	cosU2 := 1 / math.Sqrt(1+tanU2*tanU2)
+	sinU2 := tanU2 * cosU2
+
+	var sinLambda, cosLambda, sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	lambda := L
+	for i := 0; ; i++ {
+		if i == maxIterations {
+			return 0, 0, 0, false
+		}
+
+		sinLambda, cosLambda = math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			// coincident points
+			return 0, 0, 0, true
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			// not equatorial line
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) <= epsilon {
+			break
+		}
+		if math.Abs(lambda) > math.Pi {
+			return 0, 0, 0, false
+		}
+	}
+
+	uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+	dist = wgs84B * A * (sigma - deltaSigma)
+	azi1 = degrees(math.Atan2(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda))
+	azi2 = degrees(math.Atan2(cosU1*sinLambda, -sinU1*cosU2+cosU1*sinU2*cosLambda))
+	return dist, normalizeBearing(azi1), normalizeBearing(azi2), true
+}
+
+// geodesicInverse returns geodesic distance in meters between two points given in degrees on WGS 84 ellipsoid,
+// and initial and final azimuths in degrees clockwise from north, using Vincenty's inverse formula,
+// or Karney's algorithm for nearly antipodal points where the formula does not converge.
+func geodesicInverse(lon1, lat1, lon2, lat2 float64) (dist, azi1, azi2 float64) {
+	if dist, azi1, azi2, ok := vincentyInverse(lon1, lat1, lon2, lat2); ok {
+		return dist, azi1, azi2
+	}
+	return karneyInverse(lon1, lat1, lon2, lat2)
+}
+
+// vincentyDirect returns point in degrees at distance dist in meters along the geodesic on WGS 84 ellipsoid
+// from point given in degrees with initial azimuth in degrees, and final azimuth in degrees, using Vincenty's direct formula.
+func vincentyDirect(lon1, lat1, azi1, dist float64) (lon2, lat2, azi2 float64) {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+	)
+
+	sinAlpha1, cosAlpha1 := math.Sincos(radians(azi1))
+	tanU1 := (1 - wgs84F) * math.Tan(radians(lat1))
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
+	sigma1 := math.Atan2(tanU1, cosAlpha1)
+	sinAlpha := cosU1 * sinAlpha1
+	cosSqAlpha := 1 - sinAlpha*sinAlpha
+	uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+
+	var sinSigma, cosSigma, cos2SigmaM float64
+	sigma := dist / (wgs84B * A)
+	for i := 0; i < maxIterations; i++ {
+		cos2SigmaM = math.Cos(2*sigma1 + sigma)
+		sinSigma, cosSigma = math.Sincos(sigma)
+		deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		prev := sigma
+		sigma = dist/(wgs84B*A) + deltaSigma
+		if math.Abs(sigma-prev) <= epsilon {
+			break
+		}
+	}
+	cos2SigmaM = math.Cos(2*sigma1 + sigma)
+	sinSigma, cosSigma = math.Sincos(sigma)
+
+	x := sinU1*sinSigma - cosU1*cosSigma*cosAlpha1
+	phi2 := math.Atan2(sinU1*cosSigma+cosU1*sinSigma*cosAlpha1, (1-wgs84F)*math.Hypot(sinAlpha, x))
+	lambda := math.Atan2(sinSigma*sinAlpha1, cosU1*cosSigma-sinU1*sinSigma*cosAlpha1)
+	C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+	L := lambda - (1-C)*wgs84F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+	lon2 = math.Remainder(lon1+degrees(L), 360)
+	azi2 = normalizeBearing(degrees(math.Atan2(sinAlpha, -x)))
+	return lon2, degrees(phi2), azi2
+}
+
+// vector3 is a point in 3D space used for calculations on unit sphere.
+type vector3 [3]float64
+
+// unitVector returns unit vector for point with longitude and latitude in degrees.
+func unitVector(p PostGISPoint) vector3 {
+	sinLon, cosLon := math.Sincos(radians(p.Lon))
+	sinLat, cosLat := math.Sincos(radians(p.Lat))
+	return vector3{cosLat * cosLon, cosLat * sinLon, sinLat}
+}
+
+func (v vector3) add(w vector3) vector3 { return vector3{v[0] + w[0], v[1] + w[1], v[2] + w[2]} }
+
+func (v vector3) scale(k float64) vector3 { return vector3{v[0] * k, v[1] * k, v[2] * k} }
+
+func (v vector3) dot(w vector3) float64 { return v[0]*w[0] + v[1]*w[1] + v[2]*w[2] }
+
+func (v vector3) cross(w vector3) vector3 {
+	return vector3{v[1]*w[2] - v[2]*w[1], v[2]*w[0] - v[0]*w[2], v[0]*w[1] - v[1]*w[0]}
+}
+
+func (v vector3) norm() float64 { return math.Sqrt(v.dot(v)) }
+
+func (v vector3) normalize() vector3 { return v.scale(1 / v.norm()) }
+
+// lonLat returns point with longitude and latitude in degrees for non-zero vector.
+func (v vector3) lonLat() PostGISPoint {
+	return PostGISPoint{Lon: degrees(math.Atan2(v[1], v[0])), Lat: degrees(math.Atan2(v[2], math.Hypot(v[0], v[1])))}
+}
+
+// sphericalTriangleArea returns signed area of triangle on unit sphere: positive for counterclockwise triangle.
+func sphericalTriangleArea(a, b, c vector3) float64 {
+	return 2 * math.Atan2(a.dot(b.cross(c)), 1+a.dot(b)+b.dot(c)+c.dot(a))
+}
+
+// authalicLatitude returns latitude in radians on WGS 84 authalic sphere for geodetic latitude in degrees.
+func authalicLatitude(lat float64) float64 {
+	return math.Asin(authalicQ(math.Sin(radians(lat))) / authalicQ(1))
+}
+
+// authalicQ returns q function of authalic latitude for the given sine of geodetic latitude.
+func authalicQ(sinPhi float64) float64 {
+	e2 := wgs84F * (2 - wgs84F)
+	e := math.Sqrt(e2)
+	return (1 - e2) * (sinPhi/(1-e2*sinPhi*sinPhi) + math.Atanh(e*sinPhi)/e)
+}
+
+// authalicRadius returns radius of WGS 84 authalic sphere which has the same surface area as ellipsoid.
+func authalicRadius() float64 {
+	return wgs84A * math.Sqrt(authalicQ(1)/2)
+}
+
+// geodesicAreaSegment is the maximum length in meters of ring segment used by geodesicRingArea;
+// longer edges are split along the geodesic.
+const geodesicAreaSegment = 100000
+
+// geodesicRingArea returns unsigned area in square meters of region bounded by ring of points given in degrees
+// on WGS 84 ellipsoid. Ring can be closed or not. For ring enclosing a pole, the smaller region is used.
+func geodesicRingArea(ring []PostGISPoint) float64 {
+	n := len(ring)
+	if n > 1 && ring[0].Lon == ring[n-1].Lon && ring[0].Lat == ring[n-1].Lat {
+		n--
+	}
+	if n < 3 {
+		return 0
+	}
+
+	// sum of signed areas of quadrilaterals between edges and equator on unit sphere,
+	// and of longitude differences to detect rings enclosing a pole
+	var excess, winding float64
+	addEdge := func(lon1, lat1, lon2, lat2 float64) {
+		dLambda := math.Remainder(radians(lon2-lon1), 2*math.Pi)
+		t1 := math.Tan(authalicLatitude(lat1) / 2)
+		t2 := math.Tan(authalicLatitude(lat2) / 2)
+		excess += 2 * math.Atan2(math.Tan(dLambda/2)*(t1+t2), 1+t1*t2)
+		winding += dLambda
+	}
+	for i := 0; i < n; i++ {
+		p1, p2 := ring[i], ring[(i+1)%n]
+		if sphericalDistance(p1.Lon, p1.Lat, p2.Lon, p2.Lat, wgs84MeanRadius) <= geodesicAreaSegment {
+			addEdge(p1.Lon, p1.Lat, p2.Lon, p2.Lat)
+			continue
+		}
+
+		// split long edge into segments along the geodesic
+		dist, azi, _ := geodesicInverse(p1.Lon, p1.Lat, p2.Lon, p2.Lat)
+		segments := int(math.Ceil(dist / geodesicAreaSegment))
+		lon, lat := p1.Lon, p1.Lat
+		for j := 1; j < segments; j++ {
+			lon2, lat2, _ := vincentyDirect(p1.Lon, p1.Lat, azi, dist*float64(j)/float64(segments))
+			addEdge(lon, lat, lon2, lat2)
+			lon, lat = lon2, lat2
+		}
+		addEdge(lon, lat, p2.Lon, p2.Lat)
+	}
+
+	area := math.Abs(excess)
+	if math.Abs(winding) > math.Pi {
+		area = 2*math.Pi - area
+	}
+	if area > 2*math.Pi {
+		area = 4*math.Pi - area
+	}
+	r := authalicRadius()
+	return area * r * r
+}
+
+// check interfaces
+var (
+	_ driver.Valuer = PostGISGeographyPoint{}
+	_ sql.Scanner   = &PostGISGeographyPoint{}
+	_ driver.Valuer = PostGISGeographyPolygon{}
+	_ sql.Scanner   = &PostGISGeographyPolygon{}
+)
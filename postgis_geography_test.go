@@ -0,0 +1,321 @@
+package pq_types
+
+import (
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISGeographyScanValue(c *C) {
+	p := PostGISGeographyPoint{Lon: 37.60889, Lat: 55.821913}
+	v, err := p.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=4326;POINT(37.60889000 55.82191300)`), Commentf("%s", v))
+
+	_, err = PostGISGeographyPoint{SRID: 3857}.Value()
+	c.Check(err, ErrorMatches, `PostGISGeographyPoint.Value: geography requires SRID 4326, got 3857`)
+	_, err = PostGISGeographyPoint{Lon: 1, Lat: 91}.Value()
+	c.Check(err, ErrorMatches, `PostGISGeographyPoint.Value: latitude 91 is out of range \[-90, 90\]`)
+
+	c.Check(p.Scan("0101000020E6100000000000000000F03F0000000000000040"), IsNil)
+	c.Check(p, DeepEquals, PostGISGeographyPoint{Lon: 1, Lat: 2})
+	c.Check(p.Scan("0101000020110F0000000000000000F03F0000000000000040"), ErrorMatches,
+		`PostGISGeographyPoint.Scan: geography requires SRID 4326, got 3857`)
+	c.Check(p.Scan("010200000000000000"), ErrorMatches,
+		`PostGISGeographyPoint.Scan: PostGISPoint.Scan: invalid WKB at offset 0: expected Point, got LineString`)
+
+	pl := PostGISGeographyPolygon(MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1}))
+	v, err = pl.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=4326;POLYGON((0.00000000 0.00000000,0.00000000 1.00000000,`+
+		`1.00000000 1.00000000,1.00000000 0.00000000,0.00000000 0.00000000))`), Commentf("%s", v))
+	pl.Holes = [][]PostGISPoint{{{Lon: 0, Lat: -91}}}
+	_, err = pl.Value()
+	c.Check(err, ErrorMatches, `PostGISGeographyPolygon.Value: latitude -91 is out of range \[-90, 90\]`)
+
+	c.Check(pl.Scan("SRID=4326;POLYGON EMPTY"), IsNil)
+	c.Check(pl, DeepEquals, PostGISGeographyPolygon{})
+	c.Check(pl.Scan("SRID=3857;POLYGON EMPTY"), ErrorMatches,
+		`PostGISGeographyPolygon.Scan: geography requires SRID 4326, got 3857`)
+}
+
+func (s *TypesSuite) TestPostGISGeographyDistance(c *C) {
+	type testData struct {
+		p, q PostGISGeographyPoint
+		d    float64
+	}
+	for _, d := range []testData{
+		{PostGISGeographyPoint{Lon: 1, Lat: 2}, PostGISGeographyPoint{Lon: 1, Lat: 2}, 0},
+
+		// Flinders Peak to Buninyong, from Vincenty's paper
+		{
+			PostGISGeographyPoint{Lon: 144 + 25.0/60 + 29.5244/3600, Lat: -(37 + 57.0/60 + 3.7203/3600)},
+			PostGISGeographyPoint{Lon: 143 + 55.0/60 + 35.3839/3600, Lat: -(37 + 39.0/60 + 10.1561/3600)},
+			54972.271,
+		},
+
+		// LAX to CDG, from PostGIS ST_Distance documentation
+		{PostGISGeographyPoint{Lon: -118.4079, Lat: 33.9434}, PostGISGeographyPoint{Lon: 2.5559, Lat: 49.0083}, 9124665.269},
+
+		// one degree of longitude on equator
+		{PostGISGeographyPoint{Lon: 179.5, Lat: 0}, PostGISGeographyPoint{Lon: -179.5, Lat: 0}, 111319.491},
+
+		// nearly antipodal points where Vincenty's formula does not converge, from Karney's paper and GeographicLib documentation
+		{PostGISGeographyPoint{Lon: 0, Lat: -30}, PostGISGeographyPoint{Lon: 179.8, Lat: 29.9}, 19989832.828},
+		{PostGISGeographyPoint{Lon: 174.81, Lat: -41.32}, PostGISGeographyPoint{Lon: -5.5, Lat: 40.96}, 19959679.267},
+		{PostGISGeographyPoint{Lon: 0, Lat: 0}, PostGISGeographyPoint{Lon: 179.5, Lat: 0.5}, 19936288.579},
+	} {
+		dist := d.p.Distance(d.q)
+		c.Check(math.Abs(dist-d.d) < 0.01, Equals, true, Commentf("%v != %v", dist, d.d))
+		dist = d.q.Distance(d.p)
+		c.Check(math.Abs(dist-d.d) < 0.01, Equals, true, Commentf("%v != %v", dist, d.d))
+	}
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, d := range []testData{
+		{PostGISGeographyPoint{Lon: 37.60889, Lat: 55.821913}, PostGISGeographyPoint{Lon: 30.315868, Lat: 59.939095}, 0},
+		{PostGISGeographyPoint{Lon: -118.4079, Lat: 33.9434}, PostGISGeographyPoint{Lon: 2.5559, Lat: 49.0083}, 0},
+		{PostGISGeographyPoint{Lon: 179.5, Lat: -10}, PostGISGeographyPoint{Lon: -170, Lat: 10}, 0},
+		{PostGISGeographyPoint{Lon: 0, Lat: 0}, PostGISGeographyPoint{Lon: 179.5, Lat: 0.5}, 0},
+	} {
+		var dist float64
+		err := s.db.QueryRow("SELECT ST_Distance($1::geography, $2::geography)", d.p, d.q).Scan(&dist)
+		c.Check(err, IsNil)
+		c.Check(math.Abs(d.p.Distance(d.q)-dist) < 0.01, Equals, true, Commentf("%v != %v", d.p.Distance(d.q), dist))
+	}
+
+	// without cast parameter type is not known, and PostgreSQL can't choose between geometry and geography
+	lax := PostGISGeographyPoint{Lon: -118.4079, Lat: 33.9434}
+	cdg := PostGISGeographyPoint{Lon: 2.5559, Lat: 49.0083}
+	var dist float64
+	err := s.db.QueryRow("SELECT ST_Distance($1, $2)", lax, cdg).Scan(&dist)
+	c.Check(err, ErrorMatches, `pq: function st_distance\(unknown, unknown\) is not unique`)
+
+	// geography column gives parameter its type
+	s.SetUpTest(c)
+	_, err = s.db.Exec("INSERT INTO pq_types (point) VALUES($1)", lax)
+	c.Assert(err, IsNil)
+	err = s.db.QueryRow("SELECT ST_Distance(point, $1) FROM pq_types", cdg).Scan(&dist)
+	c.Check(err, IsNil)
+	c.Check(math.Abs(lax.Distance(cdg)-dist) < 0.01, Equals, true, Commentf("%v != %v", lax.Distance(cdg), dist))
+}
+
+func (s *TypesSuite) TestPostGISGeographyArea(c *C) {
+	type testData struct {
+		p PostGISGeographyPolygon
+		a float64
+	}
+	for _, d := range []testData{
+		{PostGISGeographyPolygon{}, 0},
+
+		// reference values are calculated by integration along densely sampled geodesics
+		{PostGISGeographyPolygon(MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1})), 12308778361.464},
+		{PostGISGeographyPolygon(MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 10, Lat: 10})), 1227877191601.588},
+		{PostGISGeographyPolygon(MakeEnvelope(PostGISPoint{Lon: 37.5, Lat: 55.5}, PostGISPoint{Lon: 37.7, Lat: 55.9})), 560022087.211},
+		{PostGISGeographyPolygon{
+			Points: MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 10, Lat: 10}).Points,
+			Holes:  [][]PostGISPoint{MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1}).Points},
+		}, 1227877191601.588 - 12308778361.464},
+
+		// octant: one eighth of ellipsoid surface
+		{PostGISGeographyPolygon{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 90, Lat: 0}, {Lon: 0, Lat: 90}, {Lon: 0, Lat: 0}}}, 510065621724088.5 / 8},
+
+		// hemisphere, in both orientations
+		{PostGISGeographyPolygon{Points: []PostGISPoint{
+			{Lon: 0, Lat: 0}, {Lon: 90, Lat: 0}, {Lon: 180, Lat: 0}, {Lon: -90, Lat: 0}, {Lon: 0, Lat: 0},
+		}}, 510065621724088.5 / 2},
+		{PostGISGeographyPolygon{Points: []PostGISPoint{
+			{Lon: 0, Lat: 0}, {Lon: -90, Lat: 0}, {Lon: 180, Lat: 0}, {Lon: 90, Lat: 0}, {Lon: 0, Lat: 0},
+		}}, 510065621724088.5 / 2},
+	} {
+		area := d.p.Area()
+		c.Check(math.Abs(area-d.a) <= d.a*1e-6, Equals, true, Commentf("%v != %v", area, d.a))
+	}
+
+	// rings around north and south poles have the same area
+	north := PostGISGeographyPolygon{Points: []PostGISPoint{
+		{Lon: 0, Lat: 80}, {Lon: 90, Lat: 80}, {Lon: 180, Lat: 80}, {Lon: -90, Lat: 80}, {Lon: 0, Lat: 80},
+	}}
+	south := PostGISGeographyPolygon{Points: []PostGISPoint{
+		{Lon: 0, Lat: -80}, {Lon: 90, Lat: -80}, {Lon: 180, Lat: -80}, {Lon: -90, Lat: -80}, {Lon: 0, Lat: -80},
+	}}
+	c.Check(math.Abs(north.Area()-south.Area()) < 1, Equals, true, Commentf("%v != %v", north.Area(), south.Area()))
+	c.Check(north.Area() < 510065621724088.5/2, Equals, true, Commentf("%v", north.Area()))
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, p := range []PostGISGeographyPolygon{
+		PostGISGeographyPolygon(MakeEnvelope(PostGISPoint{Lon: 37.5, Lat: 55.5}, PostGISPoint{Lon: 37.7, Lat: 55.9})),
+		PostGISGeographyPolygon(MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 10, Lat: 10})),
+		{
+			Points: MakeEnvelope(PostGISPoint{Lon: -10, Lat: -10}, PostGISPoint{Lon: 10, Lat: 10}).Points,
+			Holes:  [][]PostGISPoint{MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1}).Points},
+		},
+	} {
+		s.SetUpTest(c)
+
+		_, err := s.db.Exec("INSERT INTO pq_types (polygon) VALUES($1)", p)
+		c.Assert(err, IsNil)
+
+		var p1 PostGISGeographyPolygon
+		var area float64
+		err = s.db.QueryRow("SELECT polygon, ST_Area(polygon) FROM pq_types").Scan(&p1, &area)
+		c.Check(err, IsNil)
+		c.Check(p1, DeepEquals, p)
+		c.Check(math.Abs(p1.Area()-area) <= area*1e-6, Equals, true, Commentf("%v != %v", p1.Area(), area))
+	}
+}
+
+func (s *TypesSuite) TestPostGISPointGeodesic(c *C) {
+	// Flinders Peak to Buninyong, from Vincenty's paper
+	flinders := PostGISPoint{Lon: 144 + 25.0/60 + 29.5244/3600, Lat: -(37 + 57.0/60 + 3.7203/3600)}
+	buninyong := PostGISPoint{Lon: 143 + 55.0/60 + 35.3839/3600, Lat: -(37 + 39.0/60 + 10.1561/3600)}
+	bearing := 306 + 52.0/60 + 5.37/3600
+
+	c.Check(math.Abs(flinders.VincentyDistance(buninyong)-54972.271) < 0.001, Equals, true, Commentf("%v", flinders.VincentyDistance(buninyong)))
+	c.Check(math.Abs(flinders.Bearing(buninyong)-bearing) < 1e-5, Equals, true, Commentf("%v", flinders.Bearing(buninyong)))
+	p := flinders.Destination(54972.271, bearing)
+	c.Check(math.Abs(p.Lon-buninyong.Lon) < 1e-7 && math.Abs(p.Lat-buninyong.Lat) < 1e-7, Equals, true, Commentf("%v", p))
+
+	// LAX to CDG
+	lax := PostGISPoint{Lon: -118.4079, Lat: 33.9434}
+	cdg := PostGISPoint{Lon: 2.5559, Lat: 49.0083}
+	c.Check(math.Abs(lax.HaversineDistance(cdg)-9103087.983) < 0.001, Equals, true, Commentf("%v", lax.HaversineDistance(cdg)))
+	c.Check(math.Abs(lax.VincentyDistance(cdg)-9124665.273) < 0.001, Equals, true, Commentf("%v", lax.VincentyDistance(cdg)))
+
+	origin := PostGISPoint{SRID: 4326}
+	c.Check(origin.HaversineDistance(origin), Equals, 0.0)
+	c.Check(origin.VincentyDistance(origin), Equals, 0.0)
+	c.Check(origin.Bearing(origin), Equals, 0.0)
+	c.Check(origin.Destination(0, 42), DeepEquals, origin)
+	for q, b := range map[PostGISPoint]float64{
+		{Lon: 0, Lat: 1}:  0,
+		{Lon: 1, Lat: 0}:  90,
+		{Lon: 0, Lat: -1}: 180,
+		{Lon: -1, Lat: 0}: 270,
+	} {
+		c.Check(math.Abs(origin.Bearing(q)-b) < 1e-9, Equals, true, Commentf("%v: %v", q, origin.Bearing(q)))
+	}
+
+	// across antimeridian
+	p = PostGISPoint{Lon: 179.5, Lat: 0, Z: 1, Layout: PostGISLayoutXYZ}.Destination(111319.491, 90)
+	c.Check(math.Abs(p.Lon+179.5) < 1e-7 && math.Abs(p.Lat) < 1e-9, Equals, true, Commentf("%v", p))
+	c.Check(p.Z, Equals, 0.0)
+	c.Check(p.Layout, Equals, PostGISLayoutXY)
+
+	// Vincenty's formula does not converge for nearly antipodal points, Karney's algorithm is used;
+	// from Karney's paper
+	q := PostGISPoint{Lon: 179.8, Lat: 29.9}
+	p = PostGISPoint{Lon: 0, Lat: -30}
+	_, _, _, ok := vincentyInverse(p.Lon, p.Lat, q.Lon, q.Lat)
+	c.Check(ok, Equals, false)
+	c.Check(math.Abs(p.VincentyDistance(q)-19989832.82761) < 0.001, Equals, true, Commentf("%v", p.VincentyDistance(q)))
+	c.Check(math.Abs(p.Bearing(q)-161.890524736) < 1e-6, Equals, true, Commentf("%v", p.Bearing(q)))
+	c.Check(math.Abs(q.Bearing(p)-(18.090737246+180)) < 1e-6, Equals, true, Commentf("%v", q.Bearing(p)))
+	c.Check(math.Abs(PostGISPoint{}.Bearing(PostGISPoint{Lon: 180})) < 1e-9, Equals, true)
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, d := range [][2]PostGISPoint{
+		{{Lon: 37.60889, Lat: 55.821913}, {Lon: 30.315868, Lat: 59.939095}},
+		{lax, cdg},
+		{{Lon: 179.5, Lat: -10}, {Lon: -170, Lat: 10}},
+		{flinders, buninyong},
+		{{Lon: 0, Lat: 0}, {Lon: 179.5, Lat: 0.5}},
+		{{Lon: 0, Lat: -30}, {Lon: 179.8, Lat: 29.9}},
+	} {
+		p, q := d[0], d[1]
+		var haversine, vincenty, azimuth float64
+		var dest PostGISPoint
+		err := s.db.QueryRow(`SELECT ST_Distance($1::geography, $2::geography, false), ST_Distance($1::geography, $2::geography),
+			ST_Azimuth($1::geography, $2::geography), ST_Project($1::geography, 100000, radians(30))::geometry`, p, q).Scan(
+			&haversine, &vincenty, &azimuth, &dest)
+		c.Check(err, IsNil)
+		c.Check(math.Abs(p.HaversineDistance(q)-haversine) < 0.001, Equals, true, Commentf("%v != %v", p.HaversineDistance(q), haversine))
+		c.Check(math.Abs(p.VincentyDistance(q)-vincenty) < 0.01, Equals, true, Commentf("%v != %v", p.VincentyDistance(q), vincenty))
+		c.Check(math.Abs(p.Bearing(q)-degrees(azimuth)) < 1e-6, Equals, true, Commentf("%v != %v", p.Bearing(q), degrees(azimuth)))
+		d := p.Destination(100000, 30)
+		c.Check(math.Abs(d.Lon-dest.Lon) < 1e-7 && math.Abs(d.Lat-dest.Lat) < 1e-7, Equals, true, Commentf("%v != %v", d, dest))
+	}
+}
+
+func (s *TypesSuite) TestPostGISPolygonGeodesic(c *C) {
+	env := func(minLon, minLat, maxLon, maxLat float64) PostGISPolygon {
+		return MakeEnvelope(PostGISPoint{Lon: minLon, Lat: minLat}, PostGISPoint{Lon: maxLon, Lat: maxLat})
+	}
+	antimeridian := PostGISPolygon{Points: []PostGISPoint{
+		{Lon: 170, Lat: -10}, {Lon: -170, Lat: -10}, {Lon: -170, Lat: 10}, {Lon: 170, Lat: 10}, {Lon: 170, Lat: -10},
+	}}
+	pole := PostGISPolygon{Points: []PostGISPoint{
+		{Lon: 0, Lat: 80}, {Lon: 90, Lat: 80}, {Lon: 180, Lat: 80}, {Lon: -90, Lat: 80}, {Lon: 0, Lat: 80},
+	}}
+
+	p := env(0, 0, 1, 1)
+	c.Check(p.GeodesicArea(), Equals, PostGISGeographyPolygon(p).Area())
+	c.Check(math.Abs(p.GeodesicPerimeter()-443770.917) < 0.01, Equals, true, Commentf("%v", p.GeodesicPerimeter()))
+	c.Check(PostGISPolygon{}.GeodesicPerimeter(), Equals, 0.0)
+
+	// centroids of symmetric polygons
+	type testData struct {
+		p        PostGISPolygon
+		centroid PostGISPoint
+	}
+	for _, d := range []testData{
+		{env(-1, -1, 1, 1), PostGISPoint{}},
+		{PostGISPolygon{Points: env(-10, -10, 10, 10).Points, Holes: [][]PostGISPoint{env(-1, -1, 1, 1).Points}, SRID: 4326}, PostGISPoint{SRID: 4326}},
+		{antimeridian, PostGISPoint{Lon: 180}},
+		{PostGISPolygon{Points: []PostGISPoint{{Lon: 1, Lat: 2}, {Lon: 1, Lat: 2}, {Lon: 1, Lat: 2}, {Lon: 1, Lat: 2}}}, PostGISPoint{Lon: 1, Lat: 2}},
+		{PostGISPolygon{SRID: 3857}, PostGISPoint{SRID: 3857}},
+	} {
+		centroid := d.p.GeodesicCentroid()
+		c.Check(math.Abs(math.Remainder(centroid.Lon-d.centroid.Lon, 360)) < 1e-9 && math.Abs(centroid.Lat-d.centroid.Lat) < 1e-9, Equals, true,
+			Commentf("%v != %v", centroid, d.centroid))
+		c.Check(centroid.SRID, Equals, d.centroid.SRID)
+	}
+
+	// geodesic edges bend towards the pole
+	centroid := env(10, 20, 12, 22).GeodesicCentroid()
+	c.Check(math.Abs(centroid.Lon-11) < 1e-5 && centroid.Lat > 21 && centroid.Lat < 21.01, Equals, true, Commentf("%v", centroid))
+
+	// geodesic edges are closer to poles than their ends
+	type envelopeData struct {
+		p        PostGISPolygon
+		min, max [2]float64
+	}
+	for _, d := range []envelopeData{
+		{env(0, 0, 1, 1), [2]float64{0, 0}, [2]float64{1, 1.0000383}},
+		{env(0, 60, 90, 70), [2]float64{0, 60}, [2]float64{90, 75.5700815}},
+		{antimeridian, [2]float64{170, -10.1520809}, [2]float64{-170, 10.1520809}},
+		{pole, [2]float64{-180, 80}, [2]float64{180, 90}},
+		{PostGISPolygon{}, [2]float64{0, 0}, [2]float64{0, 0}},
+	} {
+		b := d.p.GeodesicEnvelope()
+		c.Check(math.Abs(b.Min.Lon-d.min[0]) < 1e-7 && math.Abs(b.Min.Lat-d.min[1]) < 1e-7 &&
+			math.Abs(b.Max.Lon-d.max[0]) < 1e-7 && math.Abs(b.Max.Lat-d.max[1]) < 1e-7, Equals, true, Commentf("%v", b))
+	}
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, p := range []PostGISPolygon{
+		{Points: []PostGISPoint{{Lon: 37.5, Lat: 55.5}, {Lon: 37.9, Lat: 55.6}, {Lon: 37.6, Lat: 55.9}, {Lon: 37.5, Lat: 55.5}}},
+		env(0, 0, 10, 10),
+	} {
+		var perimeter float64
+		var centroid PostGISPoint
+		err := s.db.QueryRow("SELECT ST_Perimeter($1::geography), ST_Centroid($1::geography)::geometry",
+			p).Scan(&perimeter, &centroid)
+		c.Check(err, IsNil)
+		c.Check(math.Abs(p.GeodesicPerimeter()-perimeter) < 0.01, Equals, true, Commentf("%v != %v", p.GeodesicPerimeter(), perimeter))
+		c1 := p.GeodesicCentroid()
+		c.Check(math.Abs(c1.Lon-centroid.Lon) < 1e-6 && math.Abs(c1.Lat-centroid.Lat) < 1e-6, Equals, true, Commentf("%v != %v", c1, centroid))
+	}
+}
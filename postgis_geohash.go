@@ -0,0 +1,107 @@
+package pq_types
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// geoHashAlphabet is base32 alphabet used by geohashes.
+const geoHashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// geoHashMaxPrecision is the default and maximal number of geohash characters, like in PostGIS.
+const geoHashMaxPrecision = 20
+
+// GeoHash returns geohash of point with the given number of characters, like PostGIS ST_GeoHash.
+// Zero or negative precision means 20 characters, as PostGIS uses for points; larger precisions are reduced to 20.
+// Longitude is normalized to range [-180, 180], latitude is clamped to range [-90, 90].
+func (p PostGISPoint) GeoHash(precision int) string {
+	if precision <= 0 || precision > geoHashMaxPrecision {
+		precision = geoHashMaxPrecision
+	}
+	lon, lat := normalizeLongitude(p.Lon), math.Max(-90, math.Min(90, p.Lat))
+
+	minLon, maxLon, minLat, maxLat := -180.0, 180.0, -90.0, 90.0
+	res := make([]byte, 0, precision)
+	var ch byte
+	for bit := 0; len(res) < precision; bit++ {
+		ch <<= 1
+		if bit%2 == 0 {
+			if mid := (minLon + maxLon) / 2; lon >= mid {
+				ch |= 1
+				minLon = mid
+			} else {
+				maxLon = mid
+			}
+		} else {
+			if mid := (minLat + maxLat) / 2; lat >= mid {
+				ch |= 1
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+		if bit%5 == 4 {
+			res = append(res, geoHashAlphabet[ch])
+			ch = 0
+		}
+	}
+	return string(res)
+}
+
+// PostGISGeoHashBox returns cell of geohash as a box, like PostGIS ST_Box2dFromGeoHash.
+// Geohash is case-insensitive; empty geohash is the whole world.
+func PostGISGeoHashBox(hash string) (PostGISBox2D, error) {
+	minLon, maxLon, minLat, maxLat := -180.0, 180.0, -90.0, 90.0
+	for i, c := range strings.ToLower(hash) {
+		v := strings.IndexRune(geoHashAlphabet, c)
+		if v < 0 {
+			return PostGISBox2D{}, fmt.Errorf("PostGISGeoHashBox: invalid character %q at position %d", c, i)
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := v&(1<<uint(bit)) != 0
+			if (i*5+4-bit)%2 == 0 {
+				if mid := (minLon + maxLon) / 2; set {
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				if mid := (minLat + maxLat) / 2; set {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+		}
+	}
+	return PostGISBox2D{
+		Min: PostGISPoint{Lon: minLon, Lat: minLat},
+		Max: PostGISPoint{Lon: maxLon, Lat: maxLat},
+	}, nil
+}
+
+// PostGISGeoHashNeighbors returns geohashes of the same length for eight cells around the cell of geohash,
+// in order: north, north-east, east, south-east, south, south-west, west, north-west.
+// Cells wrap around the antimeridian; cells beyond poles are returned as empty strings.
+func PostGISGeoHashNeighbors(hash string) ([8]string, error) {
+	var res [8]string
+	box, err := PostGISGeoHashBox(hash)
+	if err != nil {
+		return res, fmt.Errorf("PostGISGeoHashNeighbors: %s", strings.TrimPrefix(err.Error(), "PostGISGeoHashBox: "))
+	}
+	if hash == "" {
+		return res, nil
+	}
+
+	width, height := box.Max.Lon-box.Min.Lon, box.Max.Lat-box.Min.Lat
+	center := PostGISPoint{Lon: (box.Min.Lon + box.Max.Lon) / 2, Lat: (box.Min.Lat + box.Max.Lat) / 2}
+	for i, d := range [8][2]float64{{0, 1}, {1, 1}, {1, 0}, {1, -1}, {0, -1}, {-1, -1}, {-1, 0}, {-1, 1}} {
+		p := PostGISPoint{Lon: center.Lon + d[0]*width, Lat: center.Lat + d[1]*height}
+		if p.Lat < -90 || p.Lat > 90 {
+			continue
+		}
+		res[i] = p.GeoHash(len(hash))
+	}
+	return res, nil
+}
@@ -0,0 +1,95 @@
+package pq_types
+
+import (
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISGeoHash(c *C) {
+	type testData struct {
+		p         PostGISPoint
+		precision int
+		hash      string
+	}
+	for _, d := range []testData{
+		{PostGISPoint{Lon: -126, Lat: 48}, 0, "c0w3hf1s70w3hf1s70w3"},
+		{PostGISPoint{Lon: -126, Lat: 48}, 5, "c0w3h"},
+		{PostGISPoint{Lon: -126, Lat: 48}, 30, "c0w3hf1s70w3hf1s70w3"},
+		{PostGISPoint{Lon: -5.6, Lat: 42.6}, 5, "ezs42"},
+		{PostGISPoint{Lon: 0, Lat: 0}, 4, "s000"},
+		{PostGISPoint{Lon: 180, Lat: 90}, 4, "zzzz"},
+		{PostGISPoint{Lon: -180, Lat: -90}, 4, "0000"},
+		{PostGISPoint{Lon: 234, Lat: 48}, 5, PostGISPoint{Lon: -126, Lat: 48}.GeoHash(5)},
+	} {
+		comment := Commentf("%v %d", d.p, d.precision)
+		c.Check(d.p.GeoHash(d.precision), Equals, d.hash, comment)
+
+		box, err := PostGISGeoHashBox(d.hash)
+		c.Check(err, IsNil, comment)
+		lon := normalizeLongitude(d.p.Lon)
+		c.Check(box.Min.Lon <= lon && lon <= box.Max.Lon, Equals, true, comment)
+		c.Check(box.Min.Lat <= d.p.Lat && d.p.Lat <= box.Max.Lat, Equals, true, comment)
+
+		if s.skipPostGIS || d.p.Lon > 180 {
+			continue
+		}
+
+		var hash string
+		var dbBox PostGISBox2D
+		err = s.db.QueryRow("SELECT ST_GeoHash(ST_SetSRID(ST_Point($1, $2), 4326), $3), ST_Box2dFromGeoHash($4)",
+			d.p.Lon, d.p.Lat, d.precision, d.hash).Scan(&hash, &dbBox)
+		c.Check(err, IsNil, comment)
+		c.Check(hash, Equals, d.hash, comment)
+		for _, pair := range [][2]float64{
+			{box.Min.Lon, dbBox.Min.Lon}, {box.Min.Lat, dbBox.Min.Lat},
+			{box.Max.Lon, dbBox.Max.Lon}, {box.Max.Lat, dbBox.Max.Lat},
+		} {
+			c.Check(math.Abs(pair[0]-pair[1]) < 1e-9, Equals, true, Commentf("%v %v %v", d.hash, box, dbBox))
+		}
+	}
+}
+
+func (s *TypesSuite) TestPostGISGeoHashBox(c *C) {
+	box, err := PostGISGeoHashBox("ezs42")
+	c.Check(err, IsNil)
+	c.Check(box, DeepEquals, PostGISBox2D{
+		Min: PostGISPoint{Lon: -5.625, Lat: 42.5830078125},
+		Max: PostGISPoint{Lon: -5.5810546875, Lat: 42.626953125},
+	})
+
+	upper, err := PostGISGeoHashBox("EZS42")
+	c.Check(err, IsNil)
+	c.Check(upper, DeepEquals, box)
+
+	box, err = PostGISGeoHashBox("")
+	c.Check(err, IsNil)
+	c.Check(box, DeepEquals, PostGISBox2D{Min: PostGISPoint{Lon: -180, Lat: -90}, Max: PostGISPoint{Lon: 180, Lat: 90}})
+
+	_, err = PostGISGeoHashBox("ezs4a")
+	c.Check(err, ErrorMatches, `PostGISGeoHashBox: invalid character 'a' at position 4`)
+}
+
+func (s *TypesSuite) TestPostGISGeoHashNeighbors(c *C) {
+	type testData struct {
+		hash      string
+		neighbors [8]string
+	}
+	for _, d := range []testData{
+		{"u1pb", [8]string{"u1pc", "u301", "u300", "u2bp", "u0zz", "u0zx", "u1p8", "u1p9"}},
+		{"ezzz", [8]string{"gbpb", "u000", "spbp", "spbn", "ezzy", "ezzw", "ezzx", "gbp8"}},
+		{"zz", [8]string{"", "", "bp", "bn", "zy", "zw", "zx", ""}},
+		{"00", [8]string{"01", "03", "02", "", "", "", "pb", "pc"}},
+	} {
+		neighbors, err := PostGISGeoHashNeighbors(d.hash)
+		c.Check(err, IsNil)
+		c.Check(neighbors, DeepEquals, d.neighbors, Commentf("%s", d.hash))
+	}
+
+	neighbors, err := PostGISGeoHashNeighbors("")
+	c.Check(err, IsNil)
+	c.Check(neighbors, DeepEquals, [8]string{})
+
+	_, err = PostGISGeoHashNeighbors("u1pi")
+	c.Check(err, ErrorMatches, `PostGISGeoHashNeighbors: invalid character 'i' at position 3`)
+}
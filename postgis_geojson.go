@@ -0,0 +1,643 @@
+package pq_types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// GeoJSON (RFC 7946) support.
+//
+// GeoJSON always uses WGS 84, so only geometries with zero SRID or PostGISDefaultSRID can be marshaled.
+// Positions contain longitude, latitude and, for layouts with Z, altitude. M coordinates are not
+// representable in GeoJSON and are omitted. Unmarshaled geometries get PostGISLayoutXYZ layout
+// if positions have altitude. Empty point is marshaled as Point with empty coordinates,
+// and empty points of MultiPoint are omitted.
+
+// geoJSONTypes maps geometry types to GeoJSON types.
+var geoJSONTypes = map[string]string{
+	"POINT":              "Point",
+	"LINESTRING":         "LineString",
+	"POLYGON":            "Polygon",
+	"MULTIPOINT":         "MultiPoint",
+	"MULTILINESTRING":    "MultiLineString",
+	"MULTIPOLYGON":       "MultiPolygon",
+	"GEOMETRYCOLLECTION": "GeometryCollection",
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type geoJSONCollection struct {
+	Type       string        `json:"type"`
+	Geometries []interface{} `json:"geometries"`
+}
+
+// marshalGeoJSON checks SRIDs and layouts of geometry g and returns it as GeoJSON geometry object.
+func marshalGeoJSON(g PostGISGeometry) ([]byte, error) {
+	srid, layout, err := checkTopGeometry(g)
+	if err != nil {
+		return nil, err
+	}
+	if srid != PostGISDefaultSRID {
+		return nil, fmt.Errorf("GeoJSON requires SRID %d, got %d", PostGISDefaultSRID, srid)
+	}
+	return json.Marshal(geoJSONObject(g, layout.HasZ()))
+}
+
+// geoJSONObject returns value to marshal as GeoJSON geometry object.
+func geoJSONObject(g PostGISGeometry, hasZ bool) interface{} {
+	var coordinates interface{}
+	switch g := g.(type) {
+	case PostGISPoint:
+		coordinates = geoJSONPosition(g, hasZ)
+	case PostGISLineString:
+		coordinates = geoJSONPositions(g.Points, hasZ)
+	case PostGISPolygon:
+		coordinates = geoJSONPolygon(g, hasZ)
+	case PostGISMultiPoint:
+		positions := make([][]float64, 0, len(g.Points))
+		for _, p := range g.Points {
+			if !isEmptyPoint(p) {
+				positions = append(positions, geoJSONPosition(p, hasZ))
+			}
+		}
+		coordinates = positions
+	case PostGISMultiLineString:
+		lines := make([][][]float64, len(g.LineStrings))
+		for i, l := range g.LineStrings {
+			lines[i] = geoJSONPositions(l.Points, hasZ)
+		}
+		coordinates = lines
+	case PostGISMultiPolygon:
+		polygons := make([][][][]float64, len(g.Polygons))
+		for i, p := range g.Polygons {
+			polygons[i] = geoJSONPolygon(p, hasZ)
+		}
+		coordinates = polygons
+	case PostGISGeometryCollection:
+		geometries := make([]interface{}, len(g.Geometries))
+		for i, e := range g.Geometries {
+			geometries[i] = geoJSONObject(e, hasZ)
+		}
+		return geoJSONCollection{Type: "GeometryCollection", Geometries: geometries}
+	}
+	return geoJSONGeometry{Type: geoJSONTypes[g.GeometryType()], Coordinates: coordinates}
+}
+
+func geoJSONPosition(p PostGISPoint, hasZ bool) []float64 {
+	if isEmptyPoint(p) {
+		return []float64{}
+	}
+	if hasZ {
+		return []float64{p.Lon, p.Lat, p.Z}
+	}
+	return []float64{p.Lon, p.Lat}
+}
+
+func geoJSONPositions(points []PostGISPoint, hasZ bool) [][]float64 {
+	res := make([][]float64, len(points))
+	for i, p := range points {
+		res[i] = geoJSONPosition(p, hasZ)
+	}
+	return res
+}
+
+func geoJSONPolygon(p PostGISPolygon, hasZ bool) [][][]float64 {
+	rings := p.Rings()
+	res := make([][][]float64, len(rings))
+	for i, ring := range rings {
+		res[i] = geoJSONPositions(closeRing(ring), hasZ)
+	}
+	return res
+}
+
+// geoJSONDims tracks number of position elements: 0 (no positions yet), 2 or 3.
+type geoJSONDims int
+
+func (d *geoJSONDims) add(position []float64) error {
+	n := len(position)
+	if n != 2 && n != 3 {
+		return fmt.Errorf("position should have 2 or 3 elements, got %d", n)
+	}
+	if *d != 0 && int(*d) != n {
+		return errors.New("mixed positions with and without altitude")
+	}
+	*d = geoJSONDims(n)
+	return nil
+}
+
+func (d geoJSONDims) layout() PostGISLayout {
+	if d == 3 {
+		return PostGISLayoutXYZ
+	}
+	return PostGISLayoutXY
+}
+
+func (d *geoJSONDims) point(position []float64) (PostGISPoint, error) {
+	if err := d.add(position); err != nil {
+		return PostGISPoint{}, err
+	}
+	p := PostGISPoint{Lon: position[0], Lat: position[1]}
+	if len(position) == 3 {
+		p.Z = position[2]
+	}
+	return p, nil
+}
+
+func (d *geoJSONDims) points(positions [][]float64) ([]PostGISPoint, error) {
+	if len(positions) == 0 {
+		return nil, nil
+	}
+	points := make([]PostGISPoint, len(positions))
+	for i, position := range positions {
+		var err error
+		if points[i], err = d.point(position); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+func (d *geoJSONDims) polygon(rings [][][]float64) (PostGISPolygon, error) {
+	var p PostGISPolygon
+	for i, ring := range rings {
+		points, err := d.points(ring)
+		if err != nil {
+			return p, err
+		}
+		if i == 0 {
+			p.Points = points
+		} else {
+			p.Holes = append(p.Holes, points)
+		}
+	}
+	return p, nil
+}
+
+// unmarshalGeoJSON decodes GeoJSON geometry object of the given type (any type if t is empty).
+// It returns geometry with layout set and its dimensions.
+func unmarshalGeoJSON(data []byte, t string) (PostGISGeometry, geoJSONDims, error) {
+	var obj struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometries  []json.RawMessage `json:"geometries"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, 0, err
+	}
+	if t != "" && obj.Type != t {
+		return nil, 0, fmt.Errorf("expected GeoJSON %s, got %q", t, obj.Type)
+	}
+
+	var d geoJSONDims
+	switch obj.Type {
+	case "Point":
+		var position []float64
+		if err := unmarshalGeoJSONCoordinates(obj.Coordinates, &position); err != nil {
+			return nil, 0, err
+		}
+		if len(position) == 0 {
+			return PostGISPoint{Lon: math.NaN(), Lat: math.NaN()}, 0, nil
+		}
+		p, err := d.point(position)
+		p.Layout = d.layout()
+		return p, d, err
+
+	case "LineString":
+		var positions [][]float64
+		if err := unmarshalGeoJSONCoordinates(obj.Coordinates, &positions); err != nil {
+			return nil, 0, err
+		}
+		points, err := d.points(positions)
+		return PostGISLineString{Points: points, Layout: d.layout()}, d, err
+
+	case "Polygon":
+		var rings [][][]float64
+		if err := unmarshalGeoJSONCoordinates(obj.Coordinates, &rings); err != nil {
+			return nil, 0, err
+		}
+		p, err := d.polygon(rings)
+		p.Layout = d.layout()
+		return p, d, err
+
+	case "MultiPoint":
+		var positions [][]float64
+		if err := unmarshalGeoJSONCoordinates(obj.Coordinates, &positions); err != nil {
+			return nil, 0, err
+		}
+		points, err := d.points(positions)
+		return PostGISMultiPoint{Points: points, Layout: d.layout()}, d, err
+
+	case "MultiLineString":
+		var lines [][][]float64
+		if err := unmarshalGeoJSONCoordinates(obj.Coordinates, &lines); err != nil {
+			return nil, 0, err
+		}
+		var ml PostGISMultiLineString
+		for _, line := range lines {
+			points, err := d.points(line)
+			if err != nil {
+				return nil, 0, err
+			}
+			ml.LineStrings = append(ml.LineStrings, PostGISLineString{Points: points})
+		}
+		ml.Layout = d.layout()
+		return ml, d, nil
+
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := unmarshalGeoJSONCoordinates(obj.Coordinates, &polygons); err != nil {
+			return nil, 0, err
+		}
+		var mp PostGISMultiPolygon
+		for _, rings := range polygons {
+			p, err := d.polygon(rings)
+			if err != nil {
+				return nil, 0, err
+			}
+			mp.Polygons = append(mp.Polygons, p)
+		}
+		mp.Layout = d.layout()
+		return mp, d, nil
+
+	case "GeometryCollection":
+		if obj.Geometries == nil {
+			return nil, 0, errors.New("missing geometries")
+		}
+		var gc PostGISGeometryCollection
+		for i, raw := range obj.Geometries {
+			g, ed, err := unmarshalGeoJSON(raw, "")
+			if err != nil {
+				return nil, 0, fmt.Errorf("geometry %d: %s", i, err)
+			}
+			if ed != 0 && d != 0 && ed != d {
+				return nil, 0, errors.New("mixed positions with and without altitude")
+			}
+			if ed != 0 {
+				d = ed
+			}
+			gc.Geometries = append(gc.Geometries, clearLayout(g))
+		}
+		gc.Layout = d.layout()
+		return gc, d, nil
+
+	default:
+		return nil, 0, fmt.Errorf("unsupported GeoJSON type %q", obj.Type)
+	}
+}
+
+func unmarshalGeoJSONCoordinates(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return errors.New("missing coordinates")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid coordinates: %s", err)
+	}
+	return nil
+}
+
+// clearLayout returns geometry g with zero layout, as expected for geometries inside collection.
+func clearLayout(g PostGISGeometry) PostGISGeometry {
+	switch g := g.(type) {
+	case PostGISPoint:
+		g.Layout = PostGISLayoutXY
+		return g
+	case PostGISLineString:
+		g.Layout = PostGISLayoutXY
+		return g
+	case PostGISPolygon:
+		g.Layout = PostGISLayoutXY
+		return g
+	case PostGISMultiPoint:
+		g.Layout = PostGISLayoutXY
+		return g
+	case PostGISMultiLineString:
+		g.Layout = PostGISLayoutXY
+		return g
+	case PostGISMultiPolygon:
+		g.Layout = PostGISLayoutXY
+		return g
+	case PostGISGeometryCollection:
+		g.Layout = PostGISLayoutXY
+		return g
+	}
+	return g
+}
+
+// MarshalJSON returns p as GeoJSON Point.
+func (p PostGISPoint) MarshalJSON() ([]byte, error) {
+	b, err := marshalGeoJSON(p)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISPoint.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// postGISLegacyPoint is JSON representation of PostGISPoint before GeoJSON support.
+type postGISLegacyPoint struct {
+	Lon, Lat float64
+}
+
+// UnmarshalJSON sets p from GeoJSON Point.
+// It also accepts legacy object {"Lon": 1, "Lat": 2} without "type".
+func (p *PostGISPoint) UnmarshalJSON(data []byte) error {
+	var legacy struct {
+		Type     string `json:"type"`
+		Lon, Lat *float64
+	}
+	if json.Unmarshal(data, &legacy) == nil && legacy.Type == "" && legacy.Lon != nil && legacy.Lat != nil {
+		*p = PostGISPoint{Lon: *legacy.Lon, Lat: *legacy.Lat}
+		return nil
+	}
+
+	g, _, err := unmarshalGeoJSON(data, "Point")
+	if err != nil {
+		return fmt.Errorf("PostGISPoint.UnmarshalJSON: %s", err)
+	}
+	*p = g.(PostGISPoint)
+	return nil
+}
+
+type postGISBox2DJSON struct {
+	Min, Max postGISLegacyPoint
+}
+
+// MarshalJSON returns b as {"Min": {"Lon": 1, "Lat": 2}, "Max": {"Lon": 3, "Lat": 4}}.
+// GeoJSON has no box geometry, so b is marshaled with any SRID, which is not kept, like Value does.
+func (b PostGISBox2D) MarshalJSON() ([]byte, error) {
+	res, err := json.Marshal(postGISBox2DJSON{
+		Min: postGISLegacyPoint{Lon: b.Min.Lon, Lat: b.Min.Lat},
+		Max: postGISLegacyPoint{Lon: b.Max.Lon, Lat: b.Max.Lat},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PostGISBox2D.MarshalJSON: %s", err)
+	}
+	return res, nil
+}
+
+// UnmarshalJSON sets b from object returned by MarshalJSON.
+func (b *PostGISBox2D) UnmarshalJSON(data []byte) error {
+	var res postGISBox2DJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("PostGISBox2D.UnmarshalJSON: %s", err)
+	}
+	*b = PostGISBox2D{
+		Min: PostGISPoint{Lon: res.Min.Lon, Lat: res.Min.Lat},
+		Max: PostGISPoint{Lon: res.Max.Lon, Lat: res.Max.Lat},
+	}
+	return nil
+}
+
+// MarshalJSON returns l as GeoJSON LineString.
+func (l PostGISLineString) MarshalJSON() ([]byte, error) {
+	b, err := marshalGeoJSON(l)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISLineString.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets l from GeoJSON LineString.
+func (l *PostGISLineString) UnmarshalJSON(data []byte) error {
+	g, _, err := unmarshalGeoJSON(data, "LineString")
+	if err != nil {
+		return fmt.Errorf("PostGISLineString.UnmarshalJSON: %s", err)
+	}
+	*l = g.(PostGISLineString)
+	return nil
+}
+
+// MarshalJSON returns p as GeoJSON Polygon.
+func (p PostGISPolygon) MarshalJSON() ([]byte, error) {
+	b, err := marshalGeoJSON(p)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISPolygon.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets p from GeoJSON Polygon.
+func (p *PostGISPolygon) UnmarshalJSON(data []byte) error {
+	g, _, err := unmarshalGeoJSON(data, "Polygon")
+	if err != nil {
+		return fmt.Errorf("PostGISPolygon.UnmarshalJSON: %s", err)
+	}
+	*p = g.(PostGISPolygon)
+	return nil
+}
+
+// MarshalJSON returns mp as GeoJSON MultiPoint.
+func (mp PostGISMultiPoint) MarshalJSON() ([]byte, error) {
+	b, err := marshalGeoJSON(mp)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISMultiPoint.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets mp from GeoJSON MultiPoint.
+func (mp *PostGISMultiPoint) UnmarshalJSON(data []byte) error {
+	g, _, err := unmarshalGeoJSON(data, "MultiPoint")
+	if err != nil {
+		return fmt.Errorf("PostGISMultiPoint.UnmarshalJSON: %s", err)
+	}
+	*mp = g.(PostGISMultiPoint)
+	return nil
+}
+
+// MarshalJSON returns ml as GeoJSON MultiLineString.
+func (ml PostGISMultiLineString) MarshalJSON() ([]byte, error) {
+	b, err := marshalGeoJSON(ml)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISMultiLineString.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets ml from GeoJSON MultiLineString.
+func (ml *PostGISMultiLineString) UnmarshalJSON(data []byte) error {
+	g, _, err := unmarshalGeoJSON(data, "MultiLineString")
+	if err != nil {
+		return fmt.Errorf("PostGISMultiLineString.UnmarshalJSON: %s", err)
+	}
+	*ml = g.(PostGISMultiLineString)
+	return nil
+}
+
+// MarshalJSON returns mp as GeoJSON MultiPolygon.
+func (mp PostGISMultiPolygon) MarshalJSON() ([]byte, error) {
+	b, err := marshalGeoJSON(mp)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISMultiPolygon.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets mp from GeoJSON MultiPolygon.
+func (mp *PostGISMultiPolygon) UnmarshalJSON(data []byte) error {
+	g, _, err := unmarshalGeoJSON(data, "MultiPolygon")
+	if err != nil {
+		return fmt.Errorf("PostGISMultiPolygon.UnmarshalJSON: %s", err)
+	}
+	*mp = g.(PostGISMultiPolygon)
+	return nil
+}
+
+// MarshalJSON returns gc as GeoJSON GeometryCollection.
+func (gc PostGISGeometryCollection) MarshalJSON() ([]byte, error) {
+	b, err := marshalGeoJSON(gc)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISGeometryCollection.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets gc from GeoJSON GeometryCollection.
+func (gc *PostGISGeometryCollection) UnmarshalJSON(data []byte) error {
+	g, _, err := unmarshalGeoJSON(data, "GeometryCollection")
+	if err != nil {
+		return fmt.Errorf("PostGISGeometryCollection.UnmarshalJSON: %s", err)
+	}
+	*gc = g.(PostGISGeometryCollection)
+	return nil
+}
+
+// MarshalJSON returns g.Geometry as GeoJSON geometry object, or null for nil Geometry.
+func (g PostGISAnyGeometry) MarshalJSON() ([]byte, error) {
+	if g.Geometry == nil {
+		return []byte(`null`), nil
+	}
+	b, err := marshalGeoJSON(g.Geometry)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISAnyGeometry.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets g.Geometry from GeoJSON geometry object of any type, or to nil for null.
+func (g *PostGISAnyGeometry) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte(`null`)) {
+		g.Geometry = nil
+		return nil
+	}
+	res, _, err := unmarshalGeoJSON(data, "")
+	if err != nil {
+		return fmt.Errorf("PostGISAnyGeometry.UnmarshalJSON: %s", err)
+	}
+	g.Geometry = res
+	return nil
+}
+
+// GeoJSONFeature is a GeoJSON Feature: a geometry with properties.
+// Nil Geometry and Properties are marshaled as null.
+type GeoJSONFeature struct {
+	ID         JSONText // optional string or number
+	Geometry   PostGISGeometry
+	Properties JSONText
+}
+
+type geoJSONFeature struct {
+	Type       string             `json:"type"`
+	ID         JSONText           `json:"id,omitempty"`
+	Geometry   PostGISAnyGeometry `json:"geometry"`
+	Properties JSONText           `json:"properties"`
+}
+
+// MarshalJSON returns f as GeoJSON Feature.
+func (f GeoJSONFeature) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(geoJSONFeature{
+		Type:       "Feature",
+		ID:         f.ID,
+		Geometry:   PostGISAnyGeometry{Geometry: f.Geometry},
+		Properties: f.Properties,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GeoJSONFeature.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets f from GeoJSON Feature.
+func (f *GeoJSONFeature) UnmarshalJSON(data []byte) error {
+	var res geoJSONFeature
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("GeoJSONFeature.UnmarshalJSON: %s", err)
+	}
+	if res.Type != "Feature" {
+		return fmt.Errorf("GeoJSONFeature.UnmarshalJSON: expected GeoJSON Feature, got %q", res.Type)
+	}
+
+	*f = GeoJSONFeature{ID: res.ID, Geometry: res.Geometry.Geometry, Properties: res.Properties}
+	if bytes.Equal(f.Properties, []byte(`null`)) {
+		f.Properties = nil
+	}
+	return nil
+}
+
+// GeoJSONFeatureCollection is a GeoJSON FeatureCollection.
+type GeoJSONFeatureCollection struct {
+	Features []GeoJSONFeature
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// MarshalJSON returns fc as GeoJSON FeatureCollection.
+func (fc GeoJSONFeatureCollection) MarshalJSON() ([]byte, error) {
+	features := fc.Features
+	if features == nil {
+		features = []GeoJSONFeature{}
+	}
+	b, err := json.Marshal(geoJSONFeatureCollection{Type: "FeatureCollection", Features: features})
+	if err != nil {
+		return nil, fmt.Errorf("GeoJSONFeatureCollection.MarshalJSON: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON sets fc from GeoJSON FeatureCollection.
+func (fc *GeoJSONFeatureCollection) UnmarshalJSON(data []byte) error {
+	var res geoJSONFeatureCollection
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("GeoJSONFeatureCollection.UnmarshalJSON: %s", err)
+	}
+	if res.Type != "FeatureCollection" {
+		return fmt.Errorf("GeoJSONFeatureCollection.UnmarshalJSON: expected GeoJSON FeatureCollection, got %q", res.Type)
+	}
+
+	fc.Features = res.Features
+	return nil
+}
+
+// check interfaces
+var (
+	_ json.Marshaler   = PostGISPoint{}
+	_ json.Unmarshaler = &PostGISPoint{}
+	_ json.Marshaler   = PostGISBox2D{}
+	_ json.Unmarshaler = &PostGISBox2D{}
+	_ json.Marshaler   = PostGISLineString{}
+	_ json.Unmarshaler = &PostGISLineString{}
+	_ json.Marshaler   = PostGISPolygon{}
+	_ json.Unmarshaler = &PostGISPolygon{}
+	_ json.Marshaler   = PostGISMultiPoint{}
+	_ json.Unmarshaler = &PostGISMultiPoint{}
+	_ json.Marshaler   = PostGISMultiLineString{}
+	_ json.Unmarshaler = &PostGISMultiLineString{}
+	_ json.Marshaler   = PostGISMultiPolygon{}
+	_ json.Unmarshaler = &PostGISMultiPolygon{}
+	_ json.Marshaler   = PostGISGeometryCollection{}
+	_ json.Unmarshaler = &PostGISGeometryCollection{}
+	_ json.Marshaler   = PostGISAnyGeometry{}
+	_ json.Unmarshaler = &PostGISAnyGeometry{}
+	_ json.Marshaler   = GeoJSONFeature{}
+	_ json.Unmarshaler = &GeoJSONFeature{}
+	_ json.Marshaler   = GeoJSONFeatureCollection{}
+	_ json.Unmarshaler = &GeoJSONFeatureCollection{}
+)
@@ -0,0 +1,223 @@
+package pq_types
+
+import (
+	"encoding/json"
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISGeoJSON(c *C) {
+	type testData struct {
+		g interface {
+			PostGISGeometry
+			json.Marshaler
+		}
+		j string
+	}
+	for _, d := range []testData{
+		{
+			PostGISPoint{Lon: 37.60889, Lat: 55.821913},
+			`{"type":"Point","coordinates":[37.60889,55.821913]}`,
+		},
+		{
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3, Layout: PostGISLayoutXYZ},
+			`{"type":"Point","coordinates":[1,2,3]}`,
+		},
+		{
+			PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1.5, Lat: -1}}},
+			`{"type":"LineString","coordinates":[[0,0],[1.5,-1]]}`,
+		},
+		{
+			PostGISLineString{},
+			`{"type":"LineString","coordinates":[]}`,
+		},
+		{
+			PostGISPolygon{
+				Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 0}},
+				Holes:  [][]PostGISPoint{{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 1, Lat: 1}}},
+			},
+			`{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,0]],[[1,1],[2,1],[2,2],[1,1]]]}`,
+		},
+		{
+			PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2, Z: 3}, {Lon: 4, Lat: 5, Z: 6}}, Layout: PostGISLayoutXYZ},
+			`{"type":"MultiPoint","coordinates":[[1,2,3],[4,5,6]]}`,
+		},
+		{
+			PostGISMultiLineString{LineStrings: []PostGISLineString{{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}}}},
+			`{"type":"MultiLineString","coordinates":[[[0,0],[1,1]]]}`,
+		},
+		{
+			PostGISMultiPolygon{Polygons: []PostGISPolygon{MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1})}},
+			`{"type":"MultiPolygon","coordinates":[[[[0,0],[0,1],[1,1],[1,0],[0,0]]]]}`,
+		},
+		{
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{
+				PostGISPoint{Lon: 1, Lat: 2, Z: 3},
+				PostGISGeometryCollection{},
+			}, Layout: PostGISLayoutXYZ},
+			`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2,3]},{"type":"GeometryCollection","geometries":[]}]}`,
+		},
+	} {
+		b, err := json.Marshal(d.g)
+		c.Check(err, IsNil)
+		c.Check(string(b), Equals, d.j)
+
+		var g PostGISAnyGeometry
+		c.Check(json.Unmarshal([]byte(d.j), &g), IsNil)
+		c.Check(g.Geometry, DeepEquals, d.g)
+	}
+
+	var p PostGISPoint
+	c.Check(json.Unmarshal([]byte(` {"coordinates": [1, 2.5], "type": "Point", "bbox": [1, 2.5, 1, 2.5]} `), &p), IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: 1, Lat: 2.5})
+
+	// M is omitted
+	b, err := json.Marshal(PostGISPoint{Lon: 1, Lat: 2, Z: 3, M: 4, Layout: PostGISLayoutXYZM})
+	c.Check(err, IsNil)
+	c.Check(string(b), Equals, `{"type":"Point","coordinates":[1,2,3]}`)
+
+	// empty points
+	empty := PostGISPoint{Lon: math.NaN(), Lat: math.NaN()}
+	for _, d := range []struct {
+		g json.Marshaler
+		j string
+	}{
+		{empty, `{"type":"Point","coordinates":[]}`},
+		{PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2}, empty}}, `{"type":"MultiPoint","coordinates":[[1,2]]}`},
+		{
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{empty, PostGISMultiPoint{Points: []PostGISPoint{empty}}}},
+			`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[]},{"type":"MultiPoint","coordinates":[]}]}`,
+		},
+	} {
+		b, err = json.Marshal(d.g)
+		c.Check(err, IsNil)
+		c.Check(string(b), Equals, d.j)
+	}
+	p = PostGISPoint{Lon: 1, Lat: 2}
+	c.Check(json.Unmarshal([]byte(`{"type":"Point","coordinates":[]}`), &p), IsNil)
+	c.Check(isEmptyPoint(p), Equals, true)
+	c.Check(p.Layout, Equals, PostGISLayoutXY)
+
+	_, err = json.Marshal(PostGISPoint{SRID: 3857})
+	c.Check(err, ErrorMatches, `.*PostGISPoint.MarshalJSON: GeoJSON requires SRID 4326, got 3857`)
+	_, err = json.Marshal(PostGISLineString{Points: []PostGISPoint{{SRID: 3857}}})
+	c.Check(err, ErrorMatches, `.*PostGISLineString.MarshalJSON: point 0 has SRID 3857, expected 4326`)
+
+	// boxes are not GeoJSON and can have any SRID
+	box := MakeEnvelope(PostGISPoint{Lon: 1, Lat: 2, SRID: 3857}, PostGISPoint{Lon: 3, Lat: 4, SRID: 3857}).Envelope()
+	b, err = json.Marshal(box)
+	c.Check(err, IsNil)
+	c.Check(string(b), Equals, `{"Min":{"Lon":1,"Lat":2},"Max":{"Lon":3,"Lat":4}}`)
+	var box1 PostGISBox2D
+	c.Check(json.Unmarshal(b, &box1), IsNil)
+	c.Check(box1, DeepEquals, PostGISBox2D{Min: PostGISPoint{Lon: 1, Lat: 2}, Max: PostGISPoint{Lon: 3, Lat: 4}})
+	c.Check(json.Unmarshal([]byte(`[]`), &box1), ErrorMatches, `PostGISBox2D.UnmarshalJSON: json: cannot unmarshal array .*`)
+
+	// legacy object form
+	c.Check(json.Unmarshal([]byte(`{"Lon": 1.5, "Lat": -2}`), &p), IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: 1.5, Lat: -2})
+
+	for j, msg := range map[string]string{
+		`{"type": "Polygon", "coordinates": []}`:     `PostGISPoint.UnmarshalJSON: expected GeoJSON Point, got "Polygon"`,
+		`{"type": "Point"}`:                          `PostGISPoint.UnmarshalJSON: missing coordinates`,
+		`{"type": "Point", "coordinates": [1]}`:      `PostGISPoint.UnmarshalJSON: position should have 2 or 3 elements, got 1`,
+		`{"type": "Point", "coordinates": [1, "2"]}`: `PostGISPoint.UnmarshalJSON: invalid coordinates: .*`,
+	} {
+		c.Check(json.Unmarshal([]byte(j), &p), ErrorMatches, msg, Commentf("%s", j))
+	}
+	c.Check(json.Unmarshal([]byte(`[]`), &p), ErrorMatches, `PostGISPoint.UnmarshalJSON: json: cannot unmarshal array .*`)
+
+	var g PostGISAnyGeometry
+	for j, msg := range map[string]string{
+		`{"type": "LineString", "coordinates": [[1, 2], [3, 4, 5]]}`:          `PostGISAnyGeometry.UnmarshalJSON: mixed positions with and without altitude`,
+		`{"type": "GeometryCollection"}`:                                      `PostGISAnyGeometry.UnmarshalJSON: missing geometries`,
+		`{"type": "GeometryCollection", "geometries": [{"type": "Feature"}]}`: `PostGISAnyGeometry.UnmarshalJSON: geometry 0: unsupported GeoJSON type "Feature"`,
+		`{"type": "GeometryCollection", "geometries": [` +
+			`{"type": "Point", "coordinates": [1, 2]}, {"type": "Point", "coordinates": [1, 2, 3]}]}`: `PostGISAnyGeometry.UnmarshalJSON: mixed positions with and without altitude`,
+	} {
+		c.Check(json.Unmarshal([]byte(j), &g), ErrorMatches, msg, Commentf("%s", j))
+	}
+
+	g.Geometry = PostGISPoint{}
+	c.Check(json.Unmarshal([]byte(`null`), &g), IsNil)
+	c.Check(g.Geometry, IsNil)
+	b, err = json.Marshal(g)
+	c.Check(err, IsNil)
+	c.Check(string(b), Equals, `null`)
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, g := range []PostGISGeometry{
+		PostGISPoint{Lon: 37.60889, Lat: 55.821913},
+		PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0, Z: 1}, {Lon: 1, Lat: 1, Z: 2}}, Layout: PostGISLayoutXYZ},
+		PostGISPolygon{
+			Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 0}},
+			Holes:  [][]PostGISPoint{{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 1, Lat: 1}}},
+		},
+		PostGISMultiPolygon{Polygons: []PostGISPolygon{MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1})}},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: 1, Lat: 2},
+			PostGISMultiPoint{Points: []PostGISPoint{{Lon: 3, Lat: 4}}},
+		}},
+	} {
+		s.SetUpTest(c)
+
+		b, err := json.Marshal(PostGISAnyGeometry{Geometry: g})
+		c.Assert(err, IsNil)
+		_, err = s.db.Exec("INSERT INTO pq_types (geometry) VALUES(ST_SetSRID(ST_GeomFromGeoJSON($1), 4326))", string(b))
+		c.Assert(err, IsNil)
+
+		var g1 PostGISAnyGeometry
+		err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&g1)
+		c.Check(err, IsNil)
+		c.Check(g1.Geometry, DeepEquals, g)
+
+		var j JSONText
+		err = s.db.QueryRow("SELECT ST_AsGeoJSON(geometry)::text FROM pq_types").Scan(&j)
+		c.Check(err, IsNil)
+		var g2 PostGISAnyGeometry
+		c.Check(json.Unmarshal(j, &g2), IsNil)
+		c.Check(g2.Geometry, DeepEquals, g)
+	}
+}
+
+func (s *TypesSuite) TestGeoJSONFeature(c *C) {
+	fc := GeoJSONFeatureCollection{Features: []GeoJSONFeature{
+		{
+			ID:         JSONText(`"a"`),
+			Geometry:   PostGISPoint{Lon: 1, Lat: 2},
+			Properties: JSONText(`{"name":"foo","tags":[1,2]}`),
+		},
+		{},
+	}}
+	b, err := json.Marshal(fc)
+	c.Check(err, IsNil)
+	c.Check(string(b), Equals, `{"type":"FeatureCollection","features":[`+
+		`{"type":"Feature","id":"a","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"name":"foo","tags":[1,2]}},`+
+		`{"type":"Feature","geometry":null,"properties":null}]}`)
+
+	var fc1 GeoJSONFeatureCollection
+	c.Check(json.Unmarshal(b, &fc1), IsNil)
+	c.Check(fc1, DeepEquals, fc)
+
+	b, err = json.Marshal(GeoJSONFeatureCollection{})
+	c.Check(err, IsNil)
+	c.Check(string(b), Equals, `{"type":"FeatureCollection","features":[]}`)
+
+	var f GeoJSONFeature
+	c.Check(json.Unmarshal([]byte(`{"type": "Feature", "id": 42, "geometry": {"type": "LineString", "coordinates": []}, "properties": null}`), &f), IsNil)
+	c.Check(f, DeepEquals, GeoJSONFeature{ID: JSONText(`42`), Geometry: PostGISLineString{}})
+
+	c.Check(json.Unmarshal([]byte(`{"type": "Point", "coordinates": [1, 2]}`), &f), ErrorMatches,
+		`GeoJSONFeature.UnmarshalJSON: expected GeoJSON Feature, got "Point"`)
+	c.Check(json.Unmarshal([]byte(`{"type": "Feature", "geometry": {"type": "Point"}}`), &f), ErrorMatches,
+		`GeoJSONFeature.UnmarshalJSON: PostGISAnyGeometry.UnmarshalJSON: missing coordinates`)
+	c.Check(json.Unmarshal([]byte(`{"type": "Feature"}`), &fc1), ErrorMatches,
+		`GeoJSONFeatureCollection.UnmarshalJSON: expected GeoJSON FeatureCollection, got "Feature"`)
+
+	_, err = json.Marshal(GeoJSONFeature{Geometry: PostGISPoint{SRID: 3857}})
+	c.Check(err, ErrorMatches, `.*GeoJSONFeature.MarshalJSON: .*GeoJSON requires SRID 4326, got 3857`)
+}
@@ -0,0 +1,336 @@
+package pq_types
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PostGISLayout defines coordinates of geometry points.
+type PostGISLayout byte
+
+// Geometry layouts.
+const (
+	PostGISLayoutXY   PostGISLayout = 0 // 2D
+	PostGISLayoutXYZ  PostGISLayout = 1 // 3D
+	PostGISLayoutXYM  PostGISLayout = 2 // 2D with measure
+	PostGISLayoutXYZM PostGISLayout = 3 // 3D with measure
+)
+
+// HasZ returns true if layout has Z coordinate.
+func (l PostGISLayout) HasZ() bool { return l&PostGISLayoutXYZ != 0 }
+
+// HasM returns true if layout has M coordinate.
+func (l PostGISLayout) HasM() bool { return l&PostGISLayoutXYM != 0 }
+
+// String implements fmt.Stringer for better output and logging.
+func (l PostGISLayout) String() string {
+	switch l {
+	case PostGISLayoutXY:
+		return "XY"
+	case PostGISLayoutXYZ:
+		return "XYZ"
+	case PostGISLayoutXYM:
+		return "XYM"
+	case PostGISLayoutXYZM:
+		return "XYZM"
+	default:
+		return fmt.Sprintf("PostGISLayout(%d)", byte(l))
+	}
+}
+
+// PostGISGeometry is implemented by all PostGIS geometry types of this package:
+// PostGISPoint, PostGISLineString, PostGISPolygon, PostGISMultiPoint, PostGISMultiLineString,
+// PostGISMultiPolygon and PostGISGeometryCollection. It can't be implemented by other packages.
+//
+// Use PostGISAnyGeometry to scan geometry of unknown type and a type switch to handle it.
+type PostGISGeometry interface {
+	driver.Valuer
+
+	// GeometryType returns geometry type name like PostGIS GeometryType function: "POINT", "MULTIPOLYGON", etc.
+	GeometryType() string
+
+	geometrySRID() int                   // value of SRID field
+	geometryLayout() PostGISLayout       // value of Layout field
+	wktText(layout PostGISLayout) string // WKT without type name and SRID like "(1 2,3 4)" or "EMPTY"
+}
+
+// postGISValue checks SRIDs and layouts of geometry g and returns it as EWKT.
+func postGISValue(g PostGISGeometry) (driver.Value, error) {
+	srid, layout, err := checkTopGeometry(g)
+	if err != nil {
+		return nil, err
+	}
+	return ewkt(srid, g.GeometryType(), layout, g.wktText(layout)), nil
+}
+
+// checkTopGeometry checks SRIDs and layouts of top-level geometry g and returns its SRID and layout.
+func checkTopGeometry(g PostGISGeometry) (int, PostGISLayout, error) {
+	srid, err := postGISSRID(g.geometrySRID())
+	if err != nil {
+		return 0, 0, err
+	}
+	layout := g.geometryLayout()
+	if layout > PostGISLayoutXYZM {
+		return 0, 0, fmt.Errorf("invalid layout %d", byte(layout))
+	}
+	if err = checkGeometry(g, srid, layout); err != nil {
+		return 0, 0, err
+	}
+	return srid, layout, nil
+}
+
+// checkGeometry checks that all geometries and points inside g have zero SRID and layout or the given ones,
+// and that polygon holes are not empty. SRID and layout of g itself are not checked.
+func checkGeometry(g PostGISGeometry, srid int, layout PostGISLayout) error {
+	switch g := g.(type) {
+	case PostGISLineString:
+		return checkPoints(g.Points, srid, layout)
+	case PostGISPolygon:
+		if len(g.Points) == 0 && len(g.Holes) != 0 {
+			return fmt.Errorf("polygon with holes has empty exterior ring")
+		}
+		if err := checkPoints(g.Points, srid, layout); err != nil {
+			return err
+		}
+		for i, hole := range g.Holes {
+			if len(hole) == 0 {
+				return fmt.Errorf("hole %d is empty", i)
+			}
+			if err := checkPoints(hole, srid, layout); err != nil {
+				return fmt.Errorf("hole %d: %s", i, err)
+			}
+		}
+	case PostGISMultiPoint:
+		return checkPoints(g.Points, srid, layout)
+	case PostGISMultiLineString:
+		for i, l := range g.LineStrings {
+			if err := checkElement("line string", i, l, srid, layout); err != nil {
+				return err
+			}
+		}
+	case PostGISMultiPolygon:
+		for i, p := range g.Polygons {
+			if err := checkElement("polygon", i, p, srid, layout); err != nil {
+				return err
+			}
+		}
+	case PostGISGeometryCollection:
+		for i, e := range g.Geometries {
+			if e == nil {
+				return fmt.Errorf("geometry %d is nil", i)
+			}
+			if err := checkElement("geometry", i, e, srid, layout); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkElement checks SRIDs and layouts of i-th element g of multi geometry or collection.
+func checkElement(what string, i int, g PostGISGeometry, srid int, layout PostGISLayout) error {
+	if s := g.geometrySRID(); !sridMatches(s, srid) {
+		return fmt.Errorf("%s %d has SRID %d, expected %d", what, i, s, srid)
+	}
+	if l := g.geometryLayout(); l != PostGISLayoutXY && l != layout {
+		return fmt.Errorf("%s %d has layout %s, expected %s", what, i, l, layout)
+	}
+	if err := checkGeometry(g, srid, layout); err != nil {
+		return fmt.Errorf("%s %d: %s", what, i, err)
+	}
+	return nil
+}
+
+// withSRID returns geometry g with the given SRID.
+func withSRID(g PostGISGeometry, srid int) PostGISGeometry {
+	switch g := g.(type) {
+	case PostGISPoint:
+		g.SRID = srid
+		return g
+	case PostGISLineString:
+		g.SRID = srid
+		return g
+	case PostGISPolygon:
+		g.SRID = srid
+		return g
+	case PostGISMultiPoint:
+		g.SRID = srid
+		return g
+	case PostGISMultiLineString:
+		g.SRID = srid
+		return g
+	case PostGISMultiPolygon:
+		g.SRID = srid
+		return g
+	case PostGISGeometryCollection:
+		g.SRID = srid
+		return g
+	}
+	return g
+}
+
+// PostGISGeometryCollection is wrapper for PostGIS GeometryCollection type.
+// SRID and Layout are handled like PostGISPoint's ones. Geometries can't be nil.
+type PostGISGeometryCollection struct {
+	Geometries []PostGISGeometry
+	SRID       int
+	Layout     PostGISLayout
+}
+
+// GeometryType implements PostGISGeometry interface.
+func (gc PostGISGeometryCollection) GeometryType() string { return "GEOMETRYCOLLECTION" }
+
+func (gc PostGISGeometryCollection) geometrySRID() int { return gc.SRID }
+
+func (gc PostGISGeometryCollection) geometryLayout() PostGISLayout { return gc.Layout }
+
+// wktText returns geometry collection as WKT text like "(POINT(1 2),LINESTRING EMPTY)" or "EMPTY".
+func (gc PostGISGeometryCollection) wktText(layout PostGISLayout) string {
+	if len(gc.Geometries) == 0 {
+		return "EMPTY"
+	}
+
+	parts := make([]string, len(gc.Geometries))
+	for i, g := range gc.Geometries {
+		parts[i] = wkt(g.GeometryType(), layout, g.wktText(layout))
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It returns geometry collection as EWKT with SRID.
+func (gc PostGISGeometryCollection) Value() (driver.Value, error) {
+	v, err := postGISValue(gc)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISGeometryCollection.Value: %s", err)
+	}
+	return v, nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout.
+func (gc *PostGISGeometryCollection) Scan(value interface{}) error {
+	if value == nil {
+		*gc = PostGISGeometryCollection{}
+		return nil
+	}
+
+	g, err := scanGeometry(value, wkbGeometryCollection)
+	if err != nil {
+		return fmt.Errorf("PostGISGeometryCollection.Scan: %w", err)
+	}
+
+	*gc = g.(PostGISGeometryCollection)
+	return nil
+}
+
+// PostGISAnyGeometry is a Scan target for PostGIS geometry of any type.
+// Geometry is nil for NULL.
+type PostGISAnyGeometry struct {
+	Geometry PostGISGeometry
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It returns NULL for nil Geometry, and Geometry's value otherwise.
+func (g PostGISAnyGeometry) Value() (driver.Value, error) {
+	if g.Geometry == nil {
+		return nil, nil
+	}
+	return g.Geometry.Value()
+}
+
+// Scan implements database/sql Scanner interface.
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout,
+// and stores decoded geometry of the matching type in g.Geometry.
+func (g *PostGISAnyGeometry) Scan(value interface{}) error {
+	if value == nil {
+		g.Geometry = nil
+		return nil
+	}
+
+	res, err := scanGeometry(value, 0)
+	if err != nil {
+		return fmt.Errorf("PostGISAnyGeometry.Scan: %w", err)
+	}
+
+	g.Geometry = res
+	return nil
+}
+
+// PostGISEWKB wraps PostGIS geometry of any type to pass it to the database as EWKB instead of EWKT.
+// Unlike EWKT, EWKB keeps coordinates bit-exact. Geometry is nil for NULL.
+type PostGISEWKB struct {
+	Geometry PostGISGeometry
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It returns NULL for nil Geometry, and hex-encoded little-endian EWKB with SRID otherwise.
+func (g PostGISEWKB) Value() (driver.Value, error) {
+	if g.Geometry == nil {
+		return nil, nil
+	}
+	b, err := marshalEWKB(g.Geometry)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISEWKB.Value: %s", err)
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It accepts the same values as PostGISAnyGeometry.Scan.
+func (g *PostGISEWKB) Scan(value interface{}) error {
+	if value == nil {
+		g.Geometry = nil
+		return nil
+	}
+
+	res, err := scanGeometry(value, 0)
+	if err != nil {
+		return fmt.Errorf("PostGISEWKB.Scan: %w", err)
+	}
+
+	g.Geometry = res
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface.
+// It returns raw little-endian EWKB with SRID, or an error for nil Geometry.
+func (g PostGISEWKB) MarshalBinary() ([]byte, error) {
+	if g.Geometry == nil {
+		return nil, fmt.Errorf("PostGISEWKB.MarshalBinary: nil geometry")
+	}
+	b, err := marshalEWKB(g.Geometry)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISEWKB.MarshalBinary: %s", err)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
+// It accepts raw WKB or EWKB in any byte order.
+func (g *PostGISEWKB) UnmarshalBinary(data []byte) error {
+	res, err := readWKB(data)
+	if err != nil {
+		return fmt.Errorf("PostGISEWKB.UnmarshalBinary: %w", err)
+	}
+	g.Geometry = res
+	return nil
+}
+
+// check interfaces
+var (
+	_ fmt.Stringer    = PostGISLayout(0)
+	_ PostGISGeometry = PostGISGeometryCollection{}
+	_ sql.Scanner     = &PostGISGeometryCollection{}
+	_ driver.Valuer   = PostGISAnyGeometry{}
+	_ sql.Scanner     = &PostGISAnyGeometry{}
+
+	_ driver.Valuer              = PostGISEWKB{}
+	_ sql.Scanner                = &PostGISEWKB{}
+	_ encoding.BinaryMarshaler   = PostGISEWKB{}
+	_ encoding.BinaryUnmarshaler = &PostGISEWKB{}
+)
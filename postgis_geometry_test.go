@@ -0,0 +1,205 @@
+package pq_types
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISGeometryCollectionScanValue(c *C) {
+	var gc PostGISGeometryCollection
+	// NDR with point, line string, empty XDR multi polygon and nested collection
+	b := []byte("0107000020110F0000040000000101000000000000000000F03F000000000000004001020000000200000000000000000000000000000000000000000000000000F03F000000000000F03F0000000006000000000107000000010000000101000000000000000000F03F0000000000000040")
+	c.Check(gc.Scan(b), IsNil)
+	c.Check(gc, DeepEquals, PostGISGeometryCollection{
+		Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: 1, Lat: 2},
+			PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}},
+			PostGISMultiPolygon{},
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{PostGISPoint{Lon: 1, Lat: 2}}},
+		},
+		SRID: 3857,
+	})
+	v, err := gc.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=3857;GEOMETRYCOLLECTION(POINT(1.00000000 2.00000000),LINESTRING(0.00000000 0.00000000,1.00000000 1.00000000),MULTIPOLYGON EMPTY,GEOMETRYCOLLECTION(POINT(1.00000000 2.00000000)))`), Commentf("%s", v))
+
+	v, err = PostGISGeometryCollection{}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=4326;GEOMETRYCOLLECTION EMPTY`), Commentf("%s", v))
+
+	gc.Geometries[3] = PostGISGeometryCollection{Geometries: []PostGISGeometry{PostGISPoint{SRID: 4326}}}
+	_, err = gc.Value()
+	c.Check(err, ErrorMatches, `PostGISGeometryCollection.Value: geometry 3: geometry 0 has SRID 4326, expected 3857`)
+	gc.Geometries[3] = nil
+	_, err = gc.Value()
+	c.Check(err, ErrorMatches, `PostGISGeometryCollection.Value: geometry 3 is nil`)
+
+	c.Check(gc.Scan("0107000000010000000101000080000000000000F03F00000000000000400000000000000840"), ErrorMatches,
+		`PostGISGeometryCollection.Scan: invalid WKB at offset 9: Point dimensions do not match GeometryCollection dimensions`)
+	c.Check(gc.Scan("0101000000000000000000F03F0000000000000040"), ErrorMatches,
+		`PostGISGeometryCollection.Scan: invalid WKB at offset 0: expected GeometryCollection, got Point`)
+}
+
+func (s *TypesSuite) TestPostGISAnyGeometryScanValue(c *C) {
+	type testData struct {
+		v interface{}
+		g PostGISGeometry
+	}
+	for _, d := range []testData{
+		{"0101000020110F0000000000000000F03F0000000000000040", PostGISPoint{Lon: 1, Lat: 2, SRID: 3857}},
+		{"010200000000000000", PostGISLineString{SRID: PostGISUnknownSRID}},
+		{"000000000300000000", PostGISPolygon{SRID: PostGISUnknownSRID}},
+		{"010700000000000000", PostGISGeometryCollection{SRID: PostGISUnknownSRID}},
+	} {
+		g := PostGISAnyGeometry{Geometry: PostGISMultiPoint{}}
+		c.Check(g.Scan(d.v), IsNil)
+		c.Check(g.Geometry, DeepEquals, d.g)
+	}
+
+	g := PostGISAnyGeometry{Geometry: PostGISMultiPoint{}}
+	c.Check(g.Scan(nil), IsNil)
+	c.Check(g.Geometry, IsNil)
+	v, err := g.Value()
+	c.Check(err, IsNil)
+	c.Check(v, IsNil)
+
+	g.Geometry = PostGISPoint{Lon: 1, Lat: 2}
+	v, err = g.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=4326;POINT(1.00000000 2.00000000)`), Commentf("%s", v))
+
+	c.Check(g.Scan("0108000000"), ErrorMatches, `PostGISAnyGeometry.Scan: invalid WKB at offset 1: unsupported geometry type 8`)
+}
+
+func (s *TypesSuite) TestPostGISAnyGeometry(c *C) {
+	if s.skipPostGIS {
+		c.Skip("PostGIS not available")
+	}
+
+	for _, g := range []PostGISGeometry{
+		PostGISPoint{Lon: 37.60889, Lat: 55.821913},
+		PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}, SRID: 3857},
+		MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1}),
+		PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2}}},
+		PostGISGeometryCollection{},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: 1, Lat: 2},
+			PostGISLineString{},
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{
+				PostGISMultiLineString{LineStrings: []PostGISLineString{{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}}}},
+			}},
+		}, SRID: 3857},
+	} {
+		s.SetUpTest(c)
+
+		_, err := s.db.Exec("INSERT INTO pq_types (geometry) VALUES($1)", PostGISAnyGeometry{Geometry: g})
+		c.Assert(err, IsNil)
+
+		var g1 PostGISAnyGeometry
+		err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&g1)
+		c.Check(err, IsNil)
+		c.Check(g1.Geometry, DeepEquals, g)
+	}
+
+	s.SetUpTest(c)
+	_, err := s.db.Exec("INSERT INTO pq_types (geometry) VALUES($1)", PostGISAnyGeometry{})
+	c.Assert(err, IsNil)
+	g := PostGISAnyGeometry{Geometry: PostGISPoint{}}
+	err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&g)
+	c.Check(err, IsNil)
+	c.Check(g.Geometry, IsNil)
+}
+
+func (s *TypesSuite) TestPostGISLayoutScanValue(c *C) {
+	type testData struct {
+		v   string
+		g   PostGISGeometry
+		wkt string
+	}
+	for _, d := range []testData{
+		{
+			"01010000A0E6100000000000000000F03F00000000000000400000000000000840",
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3, Layout: PostGISLayoutXYZ},
+			`SRID=4326;POINT Z (1.00000000 2.00000000 3.00000000)`,
+		},
+		{
+			"00000007D13FF000000000000040000000000000004010000000000000",
+			PostGISPoint{Lon: 1, Lat: 2, M: 4, SRID: PostGISUnknownSRID, Layout: PostGISLayoutXYM},
+			`SRID=0;POINT M (1.00000000 2.00000000 4.00000000)`,
+		},
+		{
+			"01B90B0000000000000000F03F000000000000004000000000000008400000000000001040",
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3, M: 4, SRID: PostGISUnknownSRID, Layout: PostGISLayoutXYZM},
+			`SRID=0;POINT ZM (1.00000000 2.00000000 3.00000000 4.00000000)`,
+		},
+		{
+			"010200008002000000000000000000000000000000000000000000000000002440000000000000F03F000000000000F03F0000000000003440",
+			PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0, Z: 10}, {Lon: 1, Lat: 1, Z: 20}}, SRID: PostGISUnknownSRID, Layout: PostGISLayoutXYZ},
+			`SRID=0;LINESTRING Z (0.00000000 0.00000000 10.00000000,1.00000000 1.00000000 20.00000000)`,
+		},
+		{
+			"01D40700000100000001D1070000000000000000F03F00000000000000400000000000001440",
+			PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2, M: 5}}, SRID: PostGISUnknownSRID, Layout: PostGISLayoutXYM},
+			`SRID=0;MULTIPOINT M ((1.00000000 2.00000000 5.00000000))`,
+		},
+	} {
+		var g PostGISAnyGeometry
+		c.Check(g.Scan(d.v), IsNil)
+		c.Check(g.Geometry, DeepEquals, d.g)
+		v, err := g.Value()
+		c.Check(err, IsNil)
+		c.Check(v, DeepEquals, []byte(d.wkt), Commentf("%s", v))
+	}
+
+	gc := PostGISGeometryCollection{
+		Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3},
+			PostGISLineString{Layout: PostGISLayoutXYZ},
+		},
+		Layout: PostGISLayoutXYZ,
+	}
+	v, err := gc.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=4326;GEOMETRYCOLLECTION Z (POINT Z (1.00000000 2.00000000 3.00000000),LINESTRING Z EMPTY)`), Commentf("%s", v))
+
+	gc.Geometries[1] = PostGISLineString{Layout: PostGISLayoutXYM}
+	_, err = gc.Value()
+	c.Check(err, ErrorMatches, `PostGISGeometryCollection.Value: geometry 1 has layout XYM, expected XYZ`)
+	_, err = PostGISLineString{Points: []PostGISPoint{{}, {Layout: PostGISLayoutXYZM}}}.Value()
+	c.Check(err, ErrorMatches, `PostGISLineString.Value: point 1 has layout XYZM, expected XY`)
+	_, err = PostGISPoint{Layout: 4}.Value()
+	c.Check(err, ErrorMatches, `PostGISPoint.Value: invalid layout 4`)
+
+	c.Check(PostGISLayoutXYZM.HasZ(), Equals, true)
+	c.Check(PostGISLayoutXYM.HasZ(), Equals, false)
+	c.Check(PostGISLayoutXYM.HasM(), Equals, true)
+	c.Check(PostGISLayout(4).String(), Equals, "PostGISLayout(4)")
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, g := range []PostGISGeometry{
+		PostGISPoint{Lon: 1, Lat: 2, Z: 3, Layout: PostGISLayoutXYZ},
+		PostGISPoint{Lon: 1, Lat: 2, M: 4, SRID: 3857, Layout: PostGISLayoutXYM},
+		PostGISPoint{Lon: 1, Lat: 2, Z: 3, M: 4, Layout: PostGISLayoutXYZM},
+		PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0, Z: 10}, {Lon: 1, Lat: 1, Z: 20}}, Layout: PostGISLayoutXYZ},
+		PostGISPolygon{
+			Points: []PostGISPoint{{Lon: 0, Lat: 0, M: 1}, {Lon: 1, Lat: 0, M: 2}, {Lon: 1, Lat: 1, M: 3}, {Lon: 0, Lat: 0, M: 4}},
+			Layout: PostGISLayoutXYM,
+		},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3, M: 4},
+			PostGISMultiPoint{Points: []PostGISPoint{{Lon: 5, Lat: 6, Z: 7, M: 8}}},
+		}, Layout: PostGISLayoutXYZM},
+	} {
+		s.SetUpTest(c)
+
+		_, err = s.db.Exec("INSERT INTO pq_types (geometry) VALUES($1)", PostGISAnyGeometry{Geometry: g})
+		c.Assert(err, IsNil)
+
+		var g1 PostGISAnyGeometry
+		err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&g1)
+		c.Check(err, IsNil)
+		c.Check(g1.Geometry, DeepEquals, g)
+	}
+}
@@ -0,0 +1,71 @@
+package pq_types
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+)
+
+// PostGISLineString is wrapper for PostGIS LineString type.
+// SRID and Layout are handled like PostGISPoint's ones.
+type PostGISLineString struct {
+	Points []PostGISPoint
+	SRID   int
+	Layout PostGISLayout
+}
+
+// Length returns planar length of line string in units of its spatial reference system,
+// like PostGIS ST_Length for geometry.
+func (l PostGISLineString) Length() float64 {
+	var length float64
+	for i := 1; i < len(l.Points); i++ {
+		length += math.Hypot(l.Points[i].Lon-l.Points[i-1].Lon, l.Points[i].Lat-l.Points[i-1].Lat)
+	}
+	return length
+}
+
+// GeometryType implements PostGISGeometry interface.
+func (l PostGISLineString) GeometryType() string { return "LINESTRING" }
+
+func (l PostGISLineString) geometrySRID() int { return l.SRID }
+
+func (l PostGISLineString) geometryLayout() PostGISLayout { return l.Layout }
+
+// wktText returns line string as WKT text like "(1 2,3 4)" or "EMPTY".
+func (l PostGISLineString) wktText(layout PostGISLayout) string {
+	return wktPointsText(l.Points, layout)
+}
+
+// Value implements database/sql/driver Valuer interface.
+// It returns line string as EWKT with SRID.
+func (l PostGISLineString) Value() (driver.Value, error) {
+	v, err := postGISValue(l)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISLineString.Value: %s", err)
+	}
+	return v, nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout.
+func (l *PostGISLineString) Scan(value interface{}) error {
+	if value == nil {
+		*l = PostGISLineString{}
+		return nil
+	}
+
+	g, err := scanGeometry(value, wkbLineString)
+	if err != nil {
+		return fmt.Errorf("PostGISLineString.Scan: %w", err)
+	}
+
+	*l = g.(PostGISLineString)
+	return nil
+}
+
+// check interfaces
+var (
+	_ PostGISGeometry = PostGISLineString{}
+	_ sql.Scanner     = &PostGISLineString{}
+)
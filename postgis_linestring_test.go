@@ -0,0 +1,75 @@
+package pq_types
+
+import (
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISLineStringScanValue(c *C) {
+	var a PostGISLineString
+	b := []byte("0102000020E610000003000000000000000000C03F000000000000D03F000000000000C03F000000000000F03F000000000000E03F000000000000F03F")
+	c.Check(a.Scan(b), IsNil)
+	c.Check(a, DeepEquals, PostGISLineString{
+		Points: []PostGISPoint{
+			{Lon: 0.125, Lat: 0.25},
+			{Lon: 0.125, Lat: 1},
+			{Lon: 0.5, Lat: 1},
+		},
+	})
+	v, err := a.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=4326;LINESTRING(0.12500000 0.25000000,0.12500000 1.00000000,0.50000000 1.00000000)`), Commentf("%s", v))
+
+	c.Check(a.Scan([]byte("0102000020110F000000000000")), IsNil)
+	c.Check(a, DeepEquals, PostGISLineString{SRID: 3857})
+	v, err = a.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=3857;LINESTRING EMPTY`), Commentf("%s", v))
+
+	c.Check(a.Scan([]byte("0101000020E6100000000000000000C03F000000000000D03F")), ErrorMatches,
+		`PostGISLineString.Scan: invalid WKB at offset 0: expected LineString, got Point`)
+}
+
+func (s *TypesSuite) TestPostGISLineStringLength(c *C) {
+	c.Check(PostGISLineString{}.Length(), Equals, 0.0)
+	c.Check(PostGISLineString{Points: []PostGISPoint{{Lon: 1, Lat: 1}}}.Length(), Equals, 0.0)
+	c.Check(PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 3, Lat: 4}, {Lon: 3, Lat: -1}}}.Length(), Equals, 10.0)
+	l := PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 0}}}
+	c.Check(math.Abs(l.Length()-2*math.Sqrt2) < 1e-12, Equals, true)
+}
+
+func (s *TypesSuite) TestPostGISLineString(c *C) {
+	if s.skipPostGIS {
+		c.Skip("PostGIS not available")
+	}
+
+	for _, l := range []PostGISLineString{
+		{
+			Points: []PostGISPoint{
+				{Lon: 0.125, Lat: 0.25},
+				{Lon: 0.125, Lat: 1},
+				{Lon: 0.5, Lat: 1},
+				{Lon: 0.5, Lat: 0.25}}},
+		{
+			Points: []PostGISPoint{
+				{Lon: 0.0, Lat: 0.0},
+				{Lon: -50.555, Lat: -50.555}}},
+		{},
+	} {
+		s.SetUpTest(c)
+
+		_, err := s.db.Exec("INSERT INTO pq_types (linestring) VALUES($1)", l)
+		c.Assert(err, IsNil)
+
+		var l1 PostGISLineString
+		err = s.db.QueryRow("SELECT linestring FROM pq_types").Scan(&l1)
+		c.Check(err, IsNil)
+		c.Check(l1, DeepEquals, l)
+
+		var length float64
+		err = s.db.QueryRow("SELECT ST_Length(linestring) FROM pq_types").Scan(&length)
+		c.Check(err, IsNil)
+		c.Check(math.Abs(l1.Length()-length) < 1e-9, Equals, true, Commentf("%v != %v", l1.Length(), length))
+	}
+}
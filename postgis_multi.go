@@ -0,0 +1,184 @@
+package pq_types
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// PostGISMultiPoint is wrapper for PostGIS MultiPoint type.
+// SRID and Layout are handled like PostGISPoint's ones.
+type PostGISMultiPoint struct {
+	Points []PostGISPoint
+	SRID   int
+	Layout PostGISLayout
+}
+
+// GeometryType implements PostGISGeometry interface.
+func (mp PostGISMultiPoint) GeometryType() string { return "MULTIPOINT" }
+
+func (mp PostGISMultiPoint) geometrySRID() int { return mp.SRID }
+
+func (mp PostGISMultiPoint) geometryLayout() PostGISLayout { return mp.Layout }
+
+// Value implements database/sql/driver Valuer interface.
+// It returns multi point as EWKT with SRID.
+func (mp PostGISMultiPoint) Value() (driver.Value, error) {
+	v, err := postGISValue(mp)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISMultiPoint.Value: %s", err)
+	}
+	return v, nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout.
+func (mp *PostGISMultiPoint) Scan(value interface{}) error {
+	if value == nil {
+		*mp = PostGISMultiPoint{}
+		return nil
+	}
+
+	g, err := scanGeometry(value, wkbMultiPoint)
+	if err != nil {
+		return fmt.Errorf("PostGISMultiPoint.Scan: %w", err)
+	}
+
+	*mp = g.(PostGISMultiPoint)
+	return nil
+}
+
+// PostGISMultiLineString is wrapper for PostGIS MultiLineString type.
+// SRID and Layout are handled like PostGISPoint's ones.
+type PostGISMultiLineString struct {
+	LineStrings []PostGISLineString
+	SRID        int
+	Layout      PostGISLayout
+}
+
+// GeometryType implements PostGISGeometry interface.
+func (ml PostGISMultiLineString) GeometryType() string { return "MULTILINESTRING" }
+
+func (ml PostGISMultiLineString) geometrySRID() int { return ml.SRID }
+
+func (ml PostGISMultiLineString) geometryLayout() PostGISLayout { return ml.Layout }
+
+// Value implements database/sql/driver Valuer interface.
+// It returns multi line string as EWKT with SRID.
+func (ml PostGISMultiLineString) Value() (driver.Value, error) {
+	v, err := postGISValue(ml)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISMultiLineString.Value: %s", err)
+	}
+	return v, nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout.
+func (ml *PostGISMultiLineString) Scan(value interface{}) error {
+	if value == nil {
+		*ml = PostGISMultiLineString{}
+		return nil
+	}
+
+	g, err := scanGeometry(value, wkbMultiLineString)
+	if err != nil {
+		return fmt.Errorf("PostGISMultiLineString.Scan: %w", err)
+	}
+
+	*ml = g.(PostGISMultiLineString)
+	return nil
+}
+
+// PostGISMultiPolygon is wrapper for PostGIS MultiPolygon type.
+// SRID and Layout are handled like PostGISPoint's ones.
+type PostGISMultiPolygon struct {
+	Polygons []PostGISPolygon
+	SRID     int
+	Layout   PostGISLayout
+}
+
+// GeometryType implements PostGISGeometry interface.
+func (mp PostGISMultiPolygon) GeometryType() string { return "MULTIPOLYGON" }
+
+func (mp PostGISMultiPolygon) geometrySRID() int { return mp.SRID }
+
+func (mp PostGISMultiPolygon) geometryLayout() PostGISLayout { return mp.Layout }
+
+// Value implements database/sql/driver Valuer interface.
+// It returns multi polygon as EWKT with SRID.
+// Rings which are not closed are closed by repeating their first points.
+func (mp PostGISMultiPolygon) Value() (driver.Value, error) {
+	v, err := postGISValue(mp)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISMultiPolygon.Value: %s", err)
+	}
+	return v, nil
+}
+
+// Scan implements database/sql Scanner interface.
+// It expects EWKB or WKB, hex-encoded or raw, in any byte order, or WKT or EWKT, with any SRID and layout.
+func (mp *PostGISMultiPolygon) Scan(value interface{}) error {
+	if value == nil {
+		*mp = PostGISMultiPolygon{}
+		return nil
+	}
+
+	g, err := scanGeometry(value, wkbMultiPolygon)
+	if err != nil {
+		return fmt.Errorf("PostGISMultiPolygon.Scan: %w", err)
+	}
+
+	*mp = g.(PostGISMultiPolygon)
+	return nil
+}
+
+// wktText returns multi point as WKT text like "((1 2),(3 4))", "(EMPTY,(3 4))" or "EMPTY".
+func (mp PostGISMultiPoint) wktText(layout PostGISLayout) string {
+	if len(mp.Points) == 0 {
+		return "EMPTY"
+	}
+
+	parts := make([]string, len(mp.Points))
+	for i, p := range mp.Points {
+		parts[i] = p.wktText(layout)
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// wktText returns multi line string as WKT text like "((1 2,3 4),(5 6,7 8))" or "EMPTY".
+func (ml PostGISMultiLineString) wktText(layout PostGISLayout) string {
+	if len(ml.LineStrings) == 0 {
+		return "EMPTY"
+	}
+
+	parts := make([]string, len(ml.LineStrings))
+	for i, l := range ml.LineStrings {
+		parts[i] = wktPointsText(l.Points, layout)
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// wktText returns multi polygon as WKT text like "(((1 2,3 4,5 6,1 2)),((7 8,9 10,11 12,7 8)))" or "EMPTY".
+func (mp PostGISMultiPolygon) wktText(layout PostGISLayout) string {
+	if len(mp.Polygons) == 0 {
+		return "EMPTY"
+	}
+
+	parts := make([]string, len(mp.Polygons))
+	for i, p := range mp.Polygons {
+		parts[i] = p.wktText(layout)
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// check interfaces
+var (
+	_ PostGISGeometry = PostGISMultiPoint{}
+	_ sql.Scanner     = &PostGISMultiPoint{}
+	_ PostGISGeometry = PostGISMultiLineString{}
+	_ sql.Scanner     = &PostGISMultiLineString{}
+	_ PostGISGeometry = PostGISMultiPolygon{}
+	_ sql.Scanner     = &PostGISMultiPolygon{}
+)
@@ -0,0 +1,122 @@
+package pq_types
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISMultiPointScanValue(c *C) {
+	var a PostGISMultiPoint
+	// NDR with the second point in XDR
+	b := []byte("0104000020E6100000020000000101000000000000000000F03F0000000000000040000000000140080000000000004010000000000000")
+	c.Check(a.Scan(b), IsNil)
+	c.Check(a, DeepEquals, PostGISMultiPoint{
+		Points: []PostGISPoint{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}},
+	})
+	v, err := a.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=4326;MULTIPOINT((1.00000000 2.00000000),(3.00000000 4.00000000))`), Commentf("%s", v))
+
+	v, err = PostGISMultiPoint{SRID: 3857}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=3857;MULTIPOINT EMPTY`), Commentf("%s", v))
+
+	_, err = PostGISMultiPoint{Points: []PostGISPoint{{SRID: 3857}}}.Value()
+	c.Check(err, ErrorMatches, `PostGISMultiPoint.Value: point 0 has SRID 3857, expected 4326`)
+
+	c.Check(a.Scan("0104000000010000000101000080000000000000F03F00000000000000400000000000000840"), ErrorMatches,
+		`PostGISMultiPoint.Scan: invalid WKB at offset 9: Point dimensions do not match MultiPoint dimensions`)
+	c.Check(a.Scan("0104000000010000000102000000010000000000000000000000000000000000000000"), ErrorMatches,
+		`PostGISMultiPoint.Scan: invalid WKB at offset 9: expected Point, got LineString`)
+}
+
+func (s *TypesSuite) TestPostGISMultiLineStringScanValue(c *C) {
+	var a PostGISMultiLineString
+	// XDR with the second line string in NDR
+	b := []byte("002000000500000F1100000002000000000200000002000000000000000000000000000000003FF00000000000003FF0000000000000010200000003000000000000000000F03F000000000000F03F0000000000000040000000000000004000000000000008400000000000000840")
+	c.Check(a.Scan(b), IsNil)
+	c.Check(a, DeepEquals, PostGISMultiLineString{
+		LineStrings: []PostGISLineString{
+			{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}},
+			{Points: []PostGISPoint{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 3, Lat: 3}}},
+		},
+		SRID: 3857,
+	})
+	v, err := a.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=3857;MULTILINESTRING((0.00000000 0.00000000,1.00000000 1.00000000),(1.00000000 1.00000000,2.00000000 2.00000000,3.00000000 3.00000000))`), Commentf("%s", v))
+
+	a.LineStrings[1].SRID = 4326
+	_, err = a.Value()
+	c.Check(err, ErrorMatches, `PostGISMultiLineString.Value: line string 1 has SRID 4326, expected 3857`)
+	a.LineStrings[1].SRID = 0
+	a.LineStrings[0].Points[1].SRID = 4326
+	_, err = a.Value()
+	c.Check(err, ErrorMatches, `PostGISMultiLineString.Value: line string 0: point 1 has SRID 4326, expected 3857`)
+}
+
+func (s *TypesSuite) TestPostGISMultiPolygonScanValue(c *C) {
+	var a PostGISMultiPolygon
+	b := []byte("0106000000020000000103000000010000000400000000000000000000000000000000000000000000000000F03F0000000000000000000000000000F03F000000000000F03F00000000000000000000000000000000010300000000000000")
+	c.Check(a.Scan(b), IsNil)
+	c.Check(a, DeepEquals, PostGISMultiPolygon{
+		Polygons: []PostGISPolygon{
+			{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 0}}},
+			{},
+		},
+		SRID: PostGISUnknownSRID,
+	})
+	v, err := a.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=0;MULTIPOLYGON(((0.00000000 0.00000000,1.00000000 0.00000000,1.00000000 1.00000000,0.00000000 0.00000000)),EMPTY)`), Commentf("%s", v))
+
+	_, err = PostGISMultiPolygon{Polygons: []PostGISPolygon{{}, {SRID: 3857}}}.Value()
+	c.Check(err, ErrorMatches, `PostGISMultiPolygon.Value: polygon 1 has SRID 3857, expected 4326`)
+
+	c.Check(a.Scan("010600000001000000"), ErrorMatches,
+		`PostGISMultiPolygon.Scan: invalid WKB at offset 5: invalid number of polygons 1`)
+}
+
+func (s *TypesSuite) TestPostGISMulti(c *C) {
+	if s.skipPostGIS {
+		c.Skip("PostGIS not available")
+	}
+
+	for _, g := range []interface{}{
+		PostGISMultiPoint{},
+		PostGISMultiPoint{Points: []PostGISPoint{{Lon: 37.60889, Lat: 55.821913}, {Lon: -0.125, Lat: 51.5}}},
+		PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2}}, SRID: 3857},
+		PostGISMultiLineString{},
+		PostGISMultiLineString{LineStrings: []PostGISLineString{
+			{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}},
+			{Points: []PostGISPoint{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 3, Lat: 3}}},
+		}},
+		PostGISMultiPolygon{},
+		PostGISMultiPolygon{Polygons: []PostGISPolygon{
+			MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 1, Lat: 1}),
+			MakeEnvelope(PostGISPoint{Lon: 2, Lat: 2}, PostGISPoint{Lon: 3, Lat: 3}),
+		}, SRID: 3857},
+	} {
+		s.SetUpTest(c)
+
+		_, err := s.db.Exec("INSERT INTO pq_types (geometry) VALUES($1)", g)
+		c.Assert(err, IsNil)
+
+		var g1 interface{}
+		switch g.(type) {
+		case PostGISMultiPoint:
+			var mp PostGISMultiPoint
+			err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&mp)
+			g1 = mp
+		case PostGISMultiLineString:
+			var ml PostGISMultiLineString
+			err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&ml)
+			g1 = ml
+		case PostGISMultiPolygon:
+			var mp PostGISMultiPolygon
+			err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&mp)
+			g1 = mp
+		}
+		c.Check(err, IsNil)
+		c.Check(g1, DeepEquals, g)
+	}
+}
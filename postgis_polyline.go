@@ -0,0 +1,157 @@
+package pq_types
+
+import (
+	"fmt"
+	"math"
+)
+
+// polylineDefaultPrecision is the number of decimal digits used by Google's encoded polyline format and PostGIS by default.
+const polylineDefaultPrecision = 5
+
+// PostGISEncodePolyline returns points as Google's encoded polyline with the given number of decimal digits,
+// like PostGIS ST_AsEncodedPolyline. Zero or negative precision means 5 digits.
+// Only Lon and Lat are encoded; they should be finite.
+func PostGISEncodePolyline(points []PostGISPoint, precision int) string {
+	factor := polylineFactor(precision)
+	res := make([]byte, 0, len(points)*8)
+	var lastLat, lastLon int64
+	for _, p := range points {
+		lat, lon := int64(math.Round(p.Lat*factor)), int64(math.Round(p.Lon*factor))
+		res = appendPolylineValue(res, lat-lastLat)
+		res = appendPolylineValue(res, lon-lastLon)
+		lastLat, lastLon = lat, lon
+	}
+	return string(res)
+}
+
+// PostGISDecodePolyline returns points of Google's encoded polyline with the given number of decimal digits,
+// like PostGIS ST_LineFromEncodedPolyline. Zero or negative precision means 5 digits.
+// Points have Lon and Lat only; empty polyline returns nil.
+func PostGISDecodePolyline(s string, precision int) ([]PostGISPoint, error) {
+	points, err := decodePolyline(s, precision)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISDecodePolyline: %s", err)
+	}
+	return points, nil
+}
+
+// decodePolyline returns points of Google's encoded polyline with the given number of decimal digits.
+func decodePolyline(s string, precision int) ([]PostGISPoint, error) {
+	factor := polylineFactor(precision)
+	var res []PostGISPoint
+	var lat, lon int64
+	for pos := 0; pos < len(s); {
+		dLat, n, err := readPolylineValue(s[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("%s at offset %d", err, pos+n)
+		}
+		pos += n
+		if pos == len(s) {
+			return nil, fmt.Errorf("missing longitude at offset %d", pos)
+		}
+		dLon, n, err := readPolylineValue(s[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("%s at offset %d", err, pos+n)
+		}
+		pos += n
+
+		lat, lon = lat+dLat, lon+dLon
+		res = append(res, PostGISPoint{Lon: float64(lon) / factor, Lat: float64(lat) / factor})
+	}
+	return res, nil
+}
+
+// EncodedPolyline returns line string as Google's encoded polyline, like PostGIS ST_AsEncodedPolyline.
+// See PostGISEncodePolyline for details.
+func (l PostGISLineString) EncodedPolyline(precision int) string {
+	return PostGISEncodePolyline(l.Points, precision)
+}
+
+// EncodedPolylines returns polygon rings as Google's encoded polylines: exterior ring followed by holes.
+// Rings are closed. See PostGISEncodePolyline for details.
+func (p PostGISPolygon) EncodedPolylines(precision int) []string {
+	rings := p.Rings()
+	if rings == nil {
+		return nil
+	}
+	res := make([]string, len(rings))
+	for i, ring := range rings {
+		res[i] = PostGISEncodePolyline(closeRing(ring), precision)
+	}
+	return res
+}
+
+// PostGISLineFromEncodedPolyline returns line string with points of Google's encoded polyline,
+// like PostGIS ST_LineFromEncodedPolyline. See PostGISDecodePolyline for details.
+func PostGISLineFromEncodedPolyline(s string, precision int) (PostGISLineString, error) {
+	points, err := decodePolyline(s, precision)
+	if err != nil {
+		return PostGISLineString{}, fmt.Errorf("PostGISLineFromEncodedPolyline: %s", err)
+	}
+	return PostGISLineString{Points: points}, nil
+}
+
+// PostGISPolygonFromEncodedPolylines returns polygon with rings from Google's encoded polylines:
+// exterior ring followed by holes, as returned by PostGISPolygon.EncodedPolylines.
+// See PostGISDecodePolyline for details.
+func PostGISPolygonFromEncodedPolylines(rings []string, precision int) (PostGISPolygon, error) {
+	var res PostGISPolygon
+	for i, s := range rings {
+		points, err := decodePolyline(s, precision)
+		if err != nil {
+			return PostGISPolygon{}, fmt.Errorf("PostGISPolygonFromEncodedPolylines: ring %d: %s", i, err)
+		}
+		if i == 0 {
+			res.Points = points
+		} else {
+			res.Holes = append(res.Holes, points)
+		}
+	}
+	return res, nil
+}
+
+// polylineFactor returns multiplier for the given number of decimal digits.
+func polylineFactor(precision int) float64 {
+	if precision <= 0 {
+		precision = polylineDefaultPrecision
+	}
+	return math.Pow(10, float64(precision))
+}
+
+// appendPolylineValue appends signed value in encoded polyline format: zigzag-encoded 5-bit chunks
+// with continuation bit 0x20, offset by 63 to get printable characters.
+func appendPolylineValue(b []byte, v int64) []byte {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b = append(b, byte(0x20|u&0x1f)+63)
+		u >>= 5
+	}
+	return append(b, byte(u)+63)
+}
+
+// readPolylineValue reads signed value in encoded polyline format and returns it with the number of read bytes.
+func readPolylineValue(s string) (int64, int, error) {
+	var u uint64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 63 || c > 126 {
+			return 0, i, fmt.Errorf("invalid character %q", c)
+		}
+		if i >= 13 {
+			return 0, i, fmt.Errorf("value is too long")
+		}
+		c -= 63
+		u |= uint64(c&0x1f) << uint(5*i)
+		if c < 0x20 {
+			v := int64(u >> 1)
+			if u&1 != 0 {
+				v = ^v
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, len(s), fmt.Errorf("unexpected end of value")
+}
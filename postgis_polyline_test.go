@@ -0,0 +1,90 @@
+package pq_types
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISEncodedPolyline(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+
+	type testData struct {
+		l         PostGISLineString
+		precision int
+		polyline  string
+	}
+	for _, d := range []testData{
+		{PostGISLineString{Points: []PostGISPoint{pt(-120.2, 38.5), pt(-120.95, 40.7), pt(-126.453, 43.252)}}, 0, "_p~iF~ps|U_ulLnnqC_mqNvxq`@"},
+		{PostGISLineString{Points: []PostGISPoint{pt(-120.2, 38.5), pt(-120.95, 40.7), pt(-126.453, 43.252)}}, 5, "_p~iF~ps|U_ulLnnqC_mqNvxq`@"},
+		{PostGISLineString{Points: []PostGISPoint{pt(-120.2, 38.5), pt(-120.95, 40.7)}}, 6, "_izlhA~rlgdF_{geC~ywl@"},
+		{PostGISLineString{Points: []PostGISPoint{pt(0, 0), pt(0.00001, -0.00001)}}, 0, "??@A"},
+		{PostGISLineString{}, 0, ""},
+	} {
+		comment := Commentf("%v %d", d.l, d.precision)
+		c.Check(d.l.EncodedPolyline(d.precision), Equals, d.polyline, comment)
+		c.Check(PostGISEncodePolyline(d.l.Points, d.precision), Equals, d.polyline, comment)
+
+		l, err := PostGISLineFromEncodedPolyline(d.polyline, d.precision)
+		c.Check(err, IsNil, comment)
+		c.Check(l, DeepEquals, d.l, comment)
+		points, err := PostGISDecodePolyline(d.polyline, d.precision)
+		c.Check(err, IsNil, comment)
+		c.Check(points, DeepEquals, d.l.Points, comment)
+
+		if s.skipPostGIS || d.l.Points == nil {
+			continue
+		}
+
+		precision := d.precision
+		if precision == 0 {
+			precision = 5
+		}
+		var polyline string
+		var dbLine PostGISLineString
+		err = s.db.QueryRow("SELECT ST_AsEncodedPolyline($1::geometry, $2), ST_LineFromEncodedPolyline($3, $2)",
+			d.l, precision, d.polyline).Scan(&polyline, &dbLine)
+		c.Check(err, IsNil, comment)
+		c.Check(polyline, Equals, d.polyline, comment)
+		c.Check(dbLine, DeepEquals, d.l, comment)
+	}
+
+	for s, e := range map[string]string{
+		"_p~iF~ps|U_ulL":   "PostGISDecodePolyline: missing longitude at offset 14",
+		"_p~iF~ps|U_ulLn":  "PostGISDecodePolyline: unexpected end of value at offset 15",
+		"_p~iF ps|U":       "PostGISDecodePolyline: invalid character ' ' at offset 5",
+		"??~~~~~~~~~~~~~~": "PostGISDecodePolyline: value is too long at offset 15",
+	} {
+		_, err := PostGISDecodePolyline(s, 0)
+		if c.Check(err, NotNil, Commentf("%s", s)) {
+			c.Check(err.Error(), Equals, e)
+		}
+	}
+}
+
+func (s *TypesSuite) TestPostGISPolygonEncodedPolylines(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+
+	p := PostGISPolygon{
+		Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0), pt(0, 0)},
+		Holes:  [][]PostGISPoint{{pt(2, 2), pt(3, 2), pt(3, 3), pt(2, 2)}},
+	}
+	polylines := p.EncodedPolylines(0)
+	c.Check(polylines, DeepEquals, []string{
+		PostGISEncodePolyline(p.Points, 0),
+		PostGISEncodePolyline(p.Holes[0], 0),
+	})
+	decoded, err := PostGISPolygonFromEncodedPolylines(polylines, 0)
+	c.Check(err, IsNil)
+	c.Check(decoded, DeepEquals, p)
+
+	// rings are closed
+	open := PostGISPolygon{Points: p.Points[:4], Holes: [][]PostGISPoint{p.Holes[0][:3]}}
+	c.Check(open.EncodedPolylines(0), DeepEquals, polylines)
+
+	c.Check(PostGISPolygon{}.EncodedPolylines(0), IsNil)
+	decoded, err = PostGISPolygonFromEncodedPolylines(nil, 0)
+	c.Check(err, IsNil)
+	c.Check(decoded, DeepEquals, PostGISPolygon{})
+
+	_, err = PostGISPolygonFromEncodedPolylines([]string{polylines[0], "_p~iF"}, 0)
+	c.Check(err, ErrorMatches, "PostGISPolygonFromEncodedPolylines: ring 1: missing longitude at offset 5")
+}
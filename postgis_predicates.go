@@ -0,0 +1,661 @@
+package pq_types
+
+import (
+	"math"
+	"sort"
+)
+
+// PostGISIntersects returns true if geometries a and b have at least one common point, like PostGIS ST_Intersects.
+//
+// Spatial predicates work in plane with X and Y (or longitude and latitude) coordinates; Z and M are ignored.
+// Empty and nil geometries do not intersect anything. Multi geometries and geometry collections are handled as unions
+// of their elements; polygons in them are expected not to overlap, as in valid multi polygons.
+// SRIDs are not checked.
+//
+// If both geometries have SRID 4326 (or zero), they are handled as longitudes and latitudes in degrees:
+// edges spanning more than 180 degrees of longitude are treated as crossing the antimeridian, and elements are moved
+// by 360 degrees to be next to each other. That works for geometries less than 180 degrees wide,
+// but not for rings which enclose a pole.
+func PostGISIntersects(a, b PostGISGeometry) bool {
+	sa, sb := newPredShapes(a, b)
+	return sa.intersects(sb)
+}
+
+// PostGISDisjoint returns true if geometries a and b have no common points, like PostGIS ST_Disjoint.
+// It is the opposite of PostGISIntersects.
+func PostGISDisjoint(a, b PostGISGeometry) bool {
+	return !PostGISIntersects(a, b)
+}
+
+// PostGISContains returns true if no points of geometry b lie in the exterior of geometry a,
+// and at least one point of the interior of b lies in the interior of a, like PostGIS ST_Contains.
+// In particular, polygon does not contain line strings and points lying on its boundary.
+// Geometries are handled as described for PostGISIntersects.
+func PostGISContains(a, b PostGISGeometry) bool {
+	sa, sb := newPredShapes(a, b)
+	return sa.contains(sb)
+}
+
+// PostGISWithin returns true if geometry a is completely inside geometry b, like PostGIS ST_Within.
+// It is the same as PostGISContains(b, a).
+func PostGISWithin(a, b PostGISGeometry) bool {
+	return PostGISContains(b, a)
+}
+
+// PostGISTouches returns true if geometries a and b have at least one common point, but their interiors do not intersect,
+// like PostGIS ST_Touches. Geometries are handled as described for PostGISIntersects.
+func PostGISTouches(a, b PostGISGeometry) bool {
+	sa, sb := newPredShapes(a, b)
+	return sa.intersects(sb) && !sa.interiorsIntersect(sb)
+}
+
+// planePoint is a point used by spatial predicates.
+type planePoint struct {
+	x, y float64
+}
+
+// location is a location of point relative to geometry, ordered by priority.
+type location int
+
+const (
+	locExterior location = iota
+	locBoundary
+	locInterior
+)
+
+// predEdge is a segment of line string or polygon ring.
+type predEdge struct {
+	a, b         planePoint
+	ring         bool // polygon ring segment
+	interiorLeft bool // polygon interior is on the left side of a->b for ring segment
+}
+
+// predPiece is a part of edge between consecutive intersections with edges of other geometry.
+type predPiece struct {
+	edge     predEdge
+	mid      planePoint
+	overlaps []predEdge // edges of other geometry the piece lies on
+}
+
+// predShape is a geometry decomposed for spatial predicates.
+type predShape struct {
+	points   []planePoint
+	lines    [][]planePoint
+	polygons [][][]planePoint // closed rings, exterior ring first
+	edges    []predEdge
+	lineEnds map[planePoint]int // number of line string ends at point; boundary points have odd number
+}
+
+// newPredShapes decomposes geometries a and b, moving them next to each other if they use longitudes and latitudes.
+func newPredShapes(a, b PostGISGeometry) (*predShape, *predShape) {
+	sa, sb := new(predShape), new(predShape)
+	sa.add(a)
+	sb.add(b)
+
+	if isLonLat(a) && isLonLat(b) {
+		sa.unwrap()
+		sb.unwrap()
+		if ref, ok := sa.center(); ok {
+			sa.moveTo(ref)
+			sb.moveTo(ref)
+		}
+	}
+
+	sa.build()
+	sb.build()
+	return sa, sb
+}
+
+// isLonLat returns true if geometry g has SRID 4326 or zero.
+func isLonLat(g PostGISGeometry) bool {
+	return g == nil || g.geometrySRID() == 0 || g.geometrySRID() == PostGISDefaultSRID
+}
+
+// add adds geometry g elements to s.
+func (s *predShape) add(g PostGISGeometry) {
+	switch g := g.(type) {
+	case PostGISPoint:
+		s.points = append(s.points, planePoint{g.Lon, g.Lat})
+	case PostGISLineString:
+		line := planePoints(g.Points)
+		switch len(line) {
+		case 0:
+		case 1:
+			s.points = append(s.points, line[0])
+		default:
+			s.lines = append(s.lines, line)
+		}
+	case PostGISPolygon:
+		// skip degenerate rings, and polygons with degenerate exterior ring
+		var rings [][]planePoint
+		for i, r := range g.Rings() {
+			ring := planePoints(r)
+			if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+				ring = append(ring, ring[0])
+			}
+			if len(ring) < 4 {
+				if i == 0 {
+					return
+				}
+				continue
+			}
+			rings = append(rings, ring)
+		}
+		if len(rings) > 0 {
+			s.polygons = append(s.polygons, rings)
+		}
+	case PostGISMultiPoint:
+		for _, p := range g.Points {
+			s.add(p)
+		}
+	case PostGISMultiLineString:
+		for _, l := range g.LineStrings {
+			s.add(l)
+		}
+	case PostGISMultiPolygon:
+		for _, p := range g.Polygons {
+			s.add(p)
+		}
+	case PostGISGeometryCollection:
+		for _, e := range g.Geometries {
+			s.add(e)
+		}
+	}
+}
+
+// planePoints returns points without consecutive duplicates.
+func planePoints(points []PostGISPoint) []planePoint {
+	res := make([]planePoint, 0, len(points))
+	for _, p := range points {
+		pp := planePoint{p.Lon, p.Lat}
+		if len(res) == 0 || res[len(res)-1] != pp {
+			res = append(res, pp)
+		}
+	}
+	return res
+}
+
+// unwrap makes longitudes of line strings and rings continuous, so edges do not cross the antimeridian.
+func (s *predShape) unwrap() {
+	for _, line := range s.lines {
+		unwrapLongitudes(line)
+	}
+	for _, rings := range s.polygons {
+		for i, ring := range rings {
+			unwrapLongitudes(ring)
+			if i > 0 {
+				// keep holes next to exterior ring
+				moveLongitudes(ring, (bounds(rings[0])[0]+bounds(rings[0])[2])/2)
+			}
+		}
+	}
+}
+
+// unwrapLongitudes adds or subtracts 360 to longitudes after edges spanning more than 180 degrees.
+// Edges spanning exactly 360 degrees, like ones of world envelope, are kept.
+func unwrapLongitudes(points []planePoint) {
+	var offset float64
+	for i := 1; i < len(points); i++ {
+		d := points[i].x - (points[i-1].x - offset)
+		switch {
+		case d > 180 && d < 360:
+			offset -= 360
+		case d < -180 && d > -360:
+			offset += 360
+		}
+		points[i].x += offset
+	}
+}
+
+// center returns longitude of the center of the first element's bounding box.
+func (s *predShape) center() (float64, bool) {
+	switch {
+	case len(s.points) > 0:
+		return s.points[0].x, true
+	case len(s.lines) > 0:
+		b := bounds(s.lines[0])
+		return (b[0] + b[2]) / 2, true
+	case len(s.polygons) > 0:
+		b := bounds(s.polygons[0][0])
+		return (b[0] + b[2]) / 2, true
+	default:
+		return 0, false
+	}
+}
+
+// moveTo moves each element by a multiple of 360 degrees of longitude to be closest to the given longitude.
+func (s *predShape) moveTo(lon float64) {
+	for i := range s.points {
+		moveLongitudes(s.points[i:i+1], lon)
+	}
+	for _, line := range s.lines {
+		moveLongitudes(line, lon)
+	}
+	for _, rings := range s.polygons {
+		b := bounds(rings[0])
+		offset := 360 * math.Round((lon-(b[0]+b[2])/2)/360)
+		for _, ring := range rings {
+			for i := range ring {
+				ring[i].x += offset
+			}
+		}
+	}
+}
+
+// moveLongitudes moves points by a multiple of 360 degrees of longitude,
+// so the center of their bounding box is closest to the given longitude.
+func moveLongitudes(points []planePoint, lon float64) {
+	b := bounds(points)
+	offset := 360 * math.Round((lon-(b[0]+b[2])/2)/360)
+	for i := range points {
+		points[i].x += offset
+	}
+}
+
+// bounds returns bounding box of non-empty points as min x, min y, max x, max y.
+func bounds(points []planePoint) [4]float64 {
+	b := [4]float64{points[0].x, points[0].y, points[0].x, points[0].y}
+	for _, p := range points[1:] {
+		b[0], b[1] = math.Min(b[0], p.x), math.Min(b[1], p.y)
+		b[2], b[3] = math.Max(b[2], p.x), math.Max(b[3], p.y)
+	}
+	return b
+}
+
+// build fills edges and line ends.
+func (s *predShape) build() {
+	s.lineEnds = make(map[planePoint]int)
+	for _, line := range s.lines {
+		s.lineEnds[line[0]]++
+		s.lineEnds[line[len(line)-1]]++
+		for i := 1; i < len(line); i++ {
+			s.edges = append(s.edges, predEdge{a: line[i-1], b: line[i]})
+		}
+	}
+	for _, rings := range s.polygons {
+		for i, ring := range rings {
+			// interior is on the left side of counterclockwise exterior ring and clockwise holes
+			left := (ringArea(ring) > 0) == (i == 0)
+			for j := 1; j < len(ring); j++ {
+				s.edges = append(s.edges, predEdge{a: ring[j-1], b: ring[j], ring: true, interiorLeft: left})
+			}
+		}
+	}
+}
+
+// ringArea returns signed area of closed ring: positive for counterclockwise ring.
+func ringArea(ring []planePoint) float64 {
+	var sum float64
+	for i := 1; i < len(ring); i++ {
+		sum += ring[i-1].x*ring[i].y - ring[i].x*ring[i-1].y
+	}
+	return sum / 2
+}
+
+func (s *predShape) empty() bool {
+	return len(s.points) == 0 && len(s.lines) == 0 && len(s.polygons) == 0
+}
+
+// orient returns positive value if c is on the left side of a->b, negative for right side, and zero if they are collinear.
+func orient(a, b, c planePoint) float64 {
+	return (b.x-a.x)*(c.y-a.y) - (b.y-a.y)*(c.x-a.x)
+}
+
+// onSegment returns true if p lies on segment ab.
+func onSegment(p, a, b planePoint) bool {
+	return orient(a, b, p) == 0 &&
+		math.Min(a.x, b.x) <= p.x && p.x <= math.Max(a.x, b.x) &&
+		math.Min(a.y, b.y) <= p.y && p.y <= math.Max(a.y, b.y)
+}
+
+// segmentIntersection returns intersection of segments ab and cd: none (n is 0), point p (n is 1),
+// or overlap pq of collinear segments (n is 2). Segment ends are returned as is, without rounding errors.
+func segmentIntersection(a, b, c, d planePoint) (p, q planePoint, n int) {
+	d1, d2 := orient(c, d, a), orient(c, d, b)
+	d3, d4 := orient(a, b, c), orient(a, b, d)
+
+	if d1 == 0 && d2 == 0 {
+		// collinear: collect ends lying on both segments, return two most distant ones
+		var ends []planePoint
+		for _, e := range []planePoint{a, b, c, d} {
+			if onSegment(e, a, b) && onSegment(e, c, d) {
+				ends = append(ends, e)
+			}
+		}
+		if len(ends) == 0 {
+			return p, q, 0
+		}
+		p, q = ends[0], ends[0]
+		for _, e := range ends {
+			if math.Hypot(e.x-p.x, e.y-p.y) > math.Hypot(q.x-p.x, q.y-p.y) {
+				q = e
+			}
+		}
+		if p == q {
+			return p, q, 1
+		}
+		return p, q, 2
+	}
+
+	if (d1 > 0 && d2 > 0) || (d1 < 0 && d2 < 0) || (d3 > 0 && d4 > 0) || (d3 < 0 && d4 < 0) {
+		return p, q, 0
+	}
+	switch {
+	case d1 == 0:
+		return a, a, 1
+	case d2 == 0:
+		return b, b, 1
+	case d3 == 0:
+		return c, c, 1
+	case d4 == 0:
+		return d, d, 1
+	}
+	t := d1 / (d1 - d2)
+	p = planePoint{a.x + t*(b.x-a.x), a.y + t*(b.y-a.y)}
+	return p, p, 1
+}
+
+// edgeParam returns parameter of point p lying on edge e: 0 for e.a, 1 for e.b.
+func edgeParam(e predEdge, p planePoint) float64 {
+	dx, dy := e.b.x-e.a.x, e.b.y-e.a.y
+	return ((p.x-e.a.x)*dx + (p.y-e.a.y)*dy) / (dx*dx + dy*dy)
+}
+
+// splitEdges splits edges at intersections with other edges.
+func splitEdges(edges, other []predEdge) []predPiece {
+	type overlap struct {
+		t0, t1 float64
+		edge   predEdge
+	}
+
+	var res []predPiece
+	for _, e := range edges {
+		ts := []float64{0, 1}
+		var overlaps []overlap
+		for _, o := range other {
+			p, q, n := segmentIntersection(e.a, e.b, o.a, o.b)
+			switch n {
+			case 1:
+				ts = append(ts, edgeParam(e, p))
+			case 2:
+				t0, t1 := edgeParam(e, p), edgeParam(e, q)
+				if t0 > t1 {
+					t0, t1 = t1, t0
+				}
+				ts = append(ts, t0, t1)
+				overlaps = append(overlaps, overlap{t0, t1, o})
+			}
+		}
+
+		sort.Float64s(ts)
+		for i := 1; i < len(ts); i++ {
+			if ts[i] == ts[i-1] {
+				continue
+			}
+			t := (ts[i-1] + ts[i]) / 2
+			pc := predPiece{
+				edge: e,
+				mid:  planePoint{e.a.x + t*(e.b.x-e.a.x), e.a.y + t*(e.b.y-e.a.y)},
+			}
+			for _, o := range overlaps {
+				if o.t0 <= t && t <= o.t1 {
+					pc.overlaps = append(pc.overlaps, o.edge)
+				}
+			}
+			res = append(res, pc)
+		}
+	}
+	return res
+}
+
+// interiorsOnSameSide returns true if polygon interiors are on the same side of collinear ring edges e and o.
+func interiorsOnSameSide(e, o predEdge) bool {
+	sameDirection := (e.b.x-e.a.x)*(o.b.x-o.a.x)+(e.b.y-e.a.y)*(o.b.y-o.a.y) > 0
+	return (e.interiorLeft == o.interiorLeft) == sameDirection
+}
+
+// locateInRing returns location of point p relative to closed ring.
+func locateInRing(p planePoint, ring []planePoint) location {
+	var winding int
+	for i := 1; i < len(ring); i++ {
+		a, b := ring[i-1], ring[i]
+		if onSegment(p, a, b) {
+			return locBoundary
+		}
+		if a.y <= p.y {
+			if b.y > p.y && orient(a, b, p) > 0 {
+				winding++
+			}
+		} else if b.y <= p.y && orient(a, b, p) < 0 {
+			winding--
+		}
+	}
+	if winding != 0 {
+		return locInterior
+	}
+	return locExterior
+}
+
+// locateInPolygons returns location of point p relative to polygons of s.
+func (s *predShape) locateInPolygons(p planePoint) location {
+	res := locExterior
+	for _, rings := range s.polygons {
+		loc := locateInRing(p, rings[0])
+		for _, hole := range rings[1:] {
+			if loc != locInterior {
+				break
+			}
+			switch locateInRing(p, hole) {
+			case locBoundary:
+				loc = locBoundary
+			case locInterior:
+				loc = locExterior
+			}
+		}
+		if loc > res {
+			res = loc
+		}
+	}
+	return res
+}
+
+// locateInLines returns location of point p relative to line strings of s.
+func (s *predShape) locateInLines(p planePoint) location {
+	for _, line := range s.lines {
+		for i := 1; i < len(line); i++ {
+			if onSegment(p, line[i-1], line[i]) {
+				if s.lineEnds[p]%2 == 1 {
+					return locBoundary
+				}
+				return locInterior
+			}
+		}
+	}
+	return locExterior
+}
+
+// locate returns location of point p relative to s.
+func (s *predShape) locate(p planePoint) location {
+	res := s.locateInPolygons(p)
+	if res == locInterior {
+		return res
+	}
+	if loc := s.locateInLines(p); loc > res {
+		res = loc
+	}
+	for _, pp := range s.points {
+		if pp == p {
+			return locInterior
+		}
+	}
+	return res
+}
+
+// locatePiece returns location of the middle of piece split by edges of s, relative to polygons and line strings of s.
+func (s *predShape) locatePiece(pc predPiece) (area, line location) {
+	for _, o := range pc.overlaps {
+		if o.ring {
+			area = locBoundary
+		} else {
+			line = locInterior
+		}
+	}
+	if area == locExterior {
+		area = s.locateInPolygons(pc.mid)
+	}
+	if line == locExterior {
+		line = s.locateInLines(pc.mid)
+	}
+	return area, line
+}
+
+// intersects returns true if s and other have at least one common point.
+func (s *predShape) intersects(other *predShape) bool {
+	if s.empty() || other.empty() {
+		return false
+	}
+
+	for _, p := range s.points {
+		if other.locate(p) != locExterior {
+			return true
+		}
+	}
+	for _, p := range other.points {
+		if s.locate(p) != locExterior {
+			return true
+		}
+	}
+
+	for _, e := range s.edges {
+		for _, o := range other.edges {
+			if _, _, n := segmentIntersection(e.a, e.b, o.a, o.b); n != 0 {
+				return true
+			}
+		}
+	}
+
+	// line strings and polygons completely inside other polygons
+	for _, e := range s.edges {
+		if other.locateInPolygons(e.a) != locExterior {
+			return true
+		}
+	}
+	for _, e := range other.edges {
+		if s.locateInPolygons(e.a) != locExterior {
+			return true
+		}
+	}
+	return false
+}
+
+// interiorsIntersect returns true if interiors of s and other have at least one common point.
+func (s *predShape) interiorsIntersect(other *predShape) bool {
+	for _, p := range s.points {
+		if other.locate(p) == locInterior {
+			return true
+		}
+	}
+	for _, p := range other.points {
+		if s.locate(p) == locInterior {
+			return true
+		}
+	}
+
+	if s.edgesInInterior(other) || other.edgesInInterior(s) {
+		return true
+	}
+
+	// line strings crossing or touching each other not at their boundaries
+	for _, e := range s.edges {
+		if e.ring {
+			continue
+		}
+		for _, o := range other.edges {
+			if o.ring {
+				continue
+			}
+			if p, _, n := segmentIntersection(e.a, e.b, o.a, o.b); n == 1 && s.lineEnds[p]%2 == 0 && other.lineEnds[p]%2 == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// edgesInInterior returns true if parts of line strings of s lie in the interior of other,
+// or parts of polygon boundaries of s lie in the interior of other's polygons, or on their boundaries
+// with interiors on the same side.
+func (s *predShape) edgesInInterior(other *predShape) bool {
+	for _, pc := range splitEdges(s.edges, other.edges) {
+		area, line := other.locatePiece(pc)
+		if area == locInterior {
+			return true
+		}
+		if !pc.edge.ring {
+			if line == locInterior {
+				return true
+			}
+			continue
+		}
+		for _, o := range pc.overlaps {
+			if o.ring && interiorsOnSameSide(pc.edge, o) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contains returns true if no points of other lie in the exterior of s,
+// and at least one point of other's interior lies in the interior of s.
+func (s *predShape) contains(other *predShape) bool {
+	if s.empty() || other.empty() {
+		return false
+	}
+
+	for _, p := range other.points {
+		if s.locate(p) == locExterior {
+			return false
+		}
+	}
+
+	for _, pc := range splitEdges(other.edges, s.edges) {
+		area, line := s.locatePiece(pc)
+		if !pc.edge.ring {
+			if area == locExterior && line == locExterior {
+				return false
+			}
+			continue
+		}
+
+		// polygon near its boundary should be inside polygons of s
+		if area == locInterior {
+			continue
+		}
+		var inside bool
+		for _, o := range pc.overlaps {
+			if o.ring && interiorsOnSameSide(pc.edge, o) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return false
+		}
+	}
+
+	// polygon boundaries of s, including holes, should not lie in the interior of other's polygons
+	var rings []predEdge
+	for _, e := range s.edges {
+		if e.ring {
+			rings = append(rings, e)
+		}
+	}
+	for _, pc := range splitEdges(rings, other.edges) {
+		if area, _ := other.locatePiece(pc); area == locInterior {
+			return false
+		}
+	}
+
+	return s.interiorsIntersect(other)
+}
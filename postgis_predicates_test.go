@@ -0,0 +1,123 @@
+package pq_types
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISPredicates(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	line := func(points ...PostGISPoint) PostGISLineString { return PostGISLineString{Points: points} }
+	env := func(minLon, minLat, maxLon, maxLat float64) PostGISPolygon {
+		return MakeEnvelope(pt(minLon, minLat), pt(maxLon, maxLat))
+	}
+
+	square := env(0, 0, 10, 10)
+	ccwSquare := PostGISPolygon{Points: []PostGISPoint{pt(0, 0), pt(10, 0), pt(10, 10), pt(0, 10), pt(0, 0)}}
+	withHole := PostGISPolygon{Points: square.Points, Holes: [][]PostGISPoint{env(4, 4, 6, 6).Points}}
+
+	type testData struct {
+		a, b                          PostGISGeometry
+		intersects, contains, touches bool
+		planar                        bool // the same result in PostGIS
+	}
+	for i, d := range []testData{
+		// polygon and point
+		{square, pt(5, 5), true, true, false, true},
+		{square, pt(0, 5), true, false, true, true},
+		{square, pt(11, 5), false, false, false, true},
+		{withHole, pt(5, 5), false, false, false, true},
+		{withHole, pt(4, 5), true, false, true, true},
+		{withHole, pt(2, 2), true, true, false, true},
+
+		// polygon and line string
+		{square, line(pt(-1, 5), pt(11, 5)), true, false, false, true},
+		{square, line(pt(1, 1), pt(9, 9)), true, true, false, true},
+		{square, line(pt(0, 0), pt(0, 10)), true, false, true, true},
+		{square, line(pt(0, 0), pt(-5, -5)), true, false, true, true},
+		{withHole, line(pt(1, 5), pt(9, 5)), true, false, false, true},
+		{withHole, line(pt(4.5, 4.5), pt(5.5, 5.5)), false, false, false, true},
+
+		// polygon and polygon
+		{square, env(10, 0, 20, 10), true, false, true, true},
+		{square, env(5, 5, 15, 15), true, false, false, true},
+		{square, env(10, 10, 20, 20), true, false, true, true},
+		{square, env(11, 11, 20, 20), false, false, false, true},
+		{square, ccwSquare, true, true, false, true},
+		{square, env(2, 2, 8, 8), true, true, false, true},
+		{env(2, 2, 8, 8), square, true, false, false, true},
+		{square, env(0, 0, 5, 5), true, true, false, true},
+		{withHole, env(2, 2, 8, 8), true, false, false, true},
+		{withHole, env(4, 4, 6, 6), true, false, true, true},
+		{withHole, env(4.5, 4.5, 5.5, 5.5), false, false, false, true},
+		{withHole, PostGISPolygon{Points: ccwSquare.Points, Holes: [][]PostGISPoint{env(4, 4, 6, 6).Points}}, true, true, false, true},
+
+		// line strings and points
+		{line(pt(0, 0), pt(10, 10)), line(pt(0, 10), pt(10, 0)), true, false, false, true},
+		{line(pt(0, 0), pt(10, 0)), line(pt(10, 0), pt(20, 0)), true, false, true, true},
+		{line(pt(0, 0), pt(10, 0)), line(pt(5, 0), pt(5, 10)), true, false, true, true},
+		{line(pt(0, 0), pt(10, 0)), line(pt(5, 0), pt(15, 0)), true, false, false, true},
+		{line(pt(0, 0), pt(10, 0)), line(pt(2, 0), pt(8, 0)), true, true, false, true},
+		{line(pt(0, 0), pt(10, 0)), line(pt(0, 1), pt(10, 1)), false, false, false, true},
+		{line(pt(0, 0), pt(10, 0)), pt(0, 0), true, false, true, true},
+		{line(pt(0, 0), pt(10, 0)), pt(5, 0), true, true, false, true},
+		{line(pt(0, 0), pt(10, 0), pt(10, 10), pt(0, 0)), pt(0, 0), true, true, false, true},
+		{pt(1, 2), pt(1, 2), true, true, false, true},
+		{pt(1, 2), pt(2, 1), false, false, false, true},
+
+		// multi geometries and collections
+		{square, PostGISMultiPoint{Points: []PostGISPoint{pt(5, 5), pt(11, 11)}}, true, false, false, true},
+		{square, PostGISMultiPoint{Points: []PostGISPoint{pt(1, 1), pt(0, 0)}}, true, true, false, true},
+		{PostGISMultiPolygon{Polygons: []PostGISPolygon{square, env(20, 0, 30, 10)}}, line(pt(5, 5), pt(25, 5)), true, false, false, true},
+		{PostGISMultiPolygon{Polygons: []PostGISPolygon{square, env(20, 0, 30, 10)}}, line(pt(21, 5), pt(25, 5)), true, true, false, true},
+		{
+			PostGISMultiLineString{LineStrings: []PostGISLineString{line(pt(0, 0), pt(10, 0)), line(pt(10, 0), pt(20, 0))}},
+			pt(10, 0), true, true, false, true, // "mod 2" rule: point is not on boundary
+		},
+		{
+			PostGISMultiLineString{LineStrings: []PostGISLineString{line(pt(0, 0), pt(10, 0)), line(pt(10, 0), pt(20, 0))}},
+			pt(0, 0), true, false, true, true,
+		},
+		{PostGISGeometryCollection{Geometries: []PostGISGeometry{pt(50, 50), square}}, pt(50, 50), true, true, false, false},
+		{PostGISGeometryCollection{Geometries: []PostGISGeometry{pt(50, 50), square}}, pt(20, 20), false, false, false, false},
+
+		// empty geometries
+		{PostGISPolygon{}, pt(0, 0), false, false, false, true},
+		{square, PostGISLineString{}, false, false, false, true},
+		{nil, pt(0, 0), false, false, false, false},
+
+		// antimeridian
+		{PostGISPolygon{Points: []PostGISPoint{pt(170, -10), pt(-170, -10), pt(-170, 10), pt(170, 10), pt(170, -10)}},
+			pt(180, 0), true, true, false, false},
+		{PostGISPolygon{Points: []PostGISPoint{pt(170, -10), pt(-170, -10), pt(-170, 10), pt(170, 10), pt(170, -10)}},
+			pt(-175, 0), true, true, false, false},
+		{PostGISPolygon{Points: []PostGISPoint{pt(170, -10), pt(-170, -10), pt(-170, 10), pt(170, 10), pt(170, -10)}},
+			pt(0, 0), false, false, false, false},
+		{line(pt(179, 0), pt(-179, 0)), line(pt(180, -1), pt(180, 1)), true, false, false, false},
+		{line(pt(179, 0), pt(-179, 0)), pt(0, 0), false, false, false, false},
+		{env(175, -5, 180, 5), env(-180, -5, -175, 5), true, false, true, false},
+		{env(-180, -90, 180, 90), pt(-180, 0), true, false, true, true},
+		{env(-180, -90, 180, 90), pt(0, 0), true, true, false, true},
+
+		// no antimeridian handling for other SRIDs
+		{PostGISLineString{Points: []PostGISPoint{pt(179, 0), pt(-179, 0)}, SRID: 3857}, PostGISPoint{SRID: 3857}, true, true, false, true},
+	} {
+		comment := Commentf("%d: %#v %#v", i, d.a, d.b)
+		c.Check(PostGISIntersects(d.a, d.b), Equals, d.intersects, comment)
+		c.Check(PostGISIntersects(d.b, d.a), Equals, d.intersects, comment)
+		c.Check(PostGISDisjoint(d.a, d.b), Equals, !d.intersects, comment)
+		c.Check(PostGISContains(d.a, d.b), Equals, d.contains, comment)
+		c.Check(PostGISWithin(d.b, d.a), Equals, d.contains, comment)
+		c.Check(PostGISTouches(d.a, d.b), Equals, d.touches, comment)
+		c.Check(PostGISTouches(d.b, d.a), Equals, d.touches, comment)
+
+		if s.skipPostGIS || !d.planar {
+			continue
+		}
+
+		var intersects, contains, touches bool
+		err := s.db.QueryRow("SELECT ST_Intersects($1::geometry, $2::geometry), ST_Contains($1::geometry, $2::geometry), "+
+			"ST_Touches($1::geometry, $2::geometry)", PostGISEWKB{d.a}, PostGISEWKB{d.b}).Scan(&intersects, &contains, &touches)
+		c.Check(err, IsNil, comment)
+		c.Check([]bool{intersects, contains, touches}, DeepEquals, []bool{d.intersects, d.contains, d.touches}, comment)
+	}
+}
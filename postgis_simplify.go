@@ -0,0 +1,422 @@
+package pq_types
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Simplify returns line string simplified with Douglas-Peucker algorithm, like PostGIS ST_Simplify.
+// Points closer than tolerance to the simplified line are removed; the first and the last points are always kept.
+// Line string collapsed to two equal points is returned empty. Z and M of kept points, SRID and Layout are kept.
+func (l PostGISLineString) Simplify(tolerance float64) PostGISLineString {
+	points := douglasPeucker(l.Points, tolerance)
+	if len(points) == 2 && points[0].Lon == points[1].Lon && points[0].Lat == points[1].Lat {
+		points = nil
+	}
+	return PostGISLineString{Points: points, SRID: l.SRID, Layout: l.Layout}
+}
+
+// Simplify returns polygon with rings simplified with Douglas-Peucker algorithm, like PostGIS ST_Simplify.
+// Holes collapsed to less than four points are dropped; if the exterior ring collapses, empty polygon is returned.
+func (p PostGISPolygon) Simplify(tolerance float64) PostGISPolygon {
+	res := PostGISPolygon{SRID: p.SRID, Layout: p.Layout}
+	for i, ring := range p.Rings() {
+		ring = douglasPeucker(ring, tolerance)
+		switch {
+		case len(ring) < 4 && i == 0:
+			return res
+		case len(ring) < 4:
+			continue
+		case i == 0:
+			res.Points = ring
+		default:
+			res.Holes = append(res.Holes, ring)
+		}
+	}
+	return res
+}
+
+// SimplifyPreserveTopology returns line string simplified with Douglas-Peucker algorithm,
+// like PostGIS ST_SimplifyPreserveTopology: a part of line string is not replaced with a segment
+// crossing other parts of it, so simple line string stays simple.
+func (l PostGISLineString) SimplifyPreserveTopology(tolerance float64) PostGISLineString {
+	s := newTopoSimplifier([][]PostGISPoint{l.Points}, tolerance)
+	return PostGISLineString{Points: s.simplify(0, 2), SRID: l.SRID, Layout: l.Layout}
+}
+
+// SimplifyPreserveTopology returns polygon simplified with Douglas-Peucker algorithm,
+// like PostGIS ST_SimplifyPreserveTopology: rings keep at least four points and do not cross themselves
+// and each other, so valid polygon stays valid.
+func (p PostGISPolygon) SimplifyPreserveTopology(tolerance float64) PostGISPolygon {
+	res := PostGISPolygon{SRID: p.SRID, Layout: p.Layout}
+	rings := p.Rings()
+	s := newTopoSimplifier(rings, tolerance)
+	for i := range rings {
+		ring := s.simplify(i, 4)
+		if i == 0 {
+			res.Points = ring
+		} else {
+			res.Holes = append(res.Holes, ring)
+		}
+	}
+	return res
+}
+
+// SimplifyVW returns line string simplified with Visvalingam-Whyatt algorithm, like PostGIS ST_SimplifyVW:
+// points with effective area (area of triangle formed with neighbors at the moment of removal)
+// less than the given area are removed. The first and the last points are always kept.
+func (l PostGISLineString) SimplifyVW(area float64) PostGISLineString {
+	return PostGISLineString{Points: visvalingamWhyatt(l.Points, area, 2), SRID: l.SRID, Layout: l.Layout}
+}
+
+// SimplifyVW returns polygon with rings simplified with Visvalingam-Whyatt algorithm, like PostGIS ST_SimplifyVW.
+// Exterior ring keeps at least four points; holes collapsed to less than four points are dropped.
+func (p PostGISPolygon) SimplifyVW(area float64) PostGISPolygon {
+	res := PostGISPolygon{SRID: p.SRID, Layout: p.Layout}
+	for i, ring := range p.Rings() {
+		minPoints := 0
+		if i == 0 {
+			minPoints = 4
+		}
+		ring = visvalingamWhyatt(ring, area, minPoints)
+		switch {
+		case len(ring) < 4 && i == 0:
+			return res
+		case len(ring) < 4:
+			continue
+		case i == 0:
+			res.Points = ring
+		default:
+			res.Holes = append(res.Holes, ring)
+		}
+	}
+	return res
+}
+
+// douglasPeucker returns points kept by Douglas-Peucker algorithm.
+func douglasPeucker(points []PostGISPoint, tolerance float64) []PostGISPoint {
+	if len(points) < 3 {
+		return append([]PostGISPoint(nil), points...)
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	sections := [][2]int{{0, len(points) - 1}}
+	for len(sections) > 0 {
+		i, j := sections[len(sections)-1][0], sections[len(sections)-1][1]
+		sections = sections[:len(sections)-1]
+		if k, dist := furthestPoint(points, i, j); dist > tolerance {
+			keep[k] = true
+			sections = append(sections, [2]int{i, k}, [2]int{k, j})
+		}
+	}
+
+	var res []PostGISPoint
+	for i, p := range points {
+		if keep[i] {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// furthestPoint returns index of point between i and j furthest from segment connecting them, and its distance.
+// It returns -1 and -1 if there are no points between i and j.
+func furthestPoint(points []PostGISPoint, i, j int) (int, float64) {
+	a, b := planePoint{points[i].Lon, points[i].Lat}, planePoint{points[j].Lon, points[j].Lat}
+	index, max := -1, -1.0
+	for k := i + 1; k < j; k++ {
+		if dist := segmentDistance(planePoint{points[k].Lon, points[k].Lat}, a, b); dist > max {
+			index, max = k, dist
+		}
+	}
+	return index, max
+}
+
+// segmentDistance returns distance from point p to segment ab.
+func segmentDistance(p, a, b planePoint) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.x-a.x, p.y-a.y)
+	}
+	t := math.Max(0, math.Min(1, ((p.x-a.x)*dx+(p.y-a.y)*dy)/(dx*dx+dy*dy)))
+	return math.Hypot(p.x-a.x-t*dx, p.y-a.y-t*dy)
+}
+
+// topoSegment is a segment of line simplified by topoSimplifier.
+type topoSegment struct {
+	a, b    planePoint
+	line    int  // index of line
+	i       int  // index of the first point of input segment, or -1 for segment added by simplification
+	removed bool // input segment replaced by simplification
+}
+
+// topoSimplifier simplifies lines with Douglas-Peucker algorithm like GEOS TopologyPreservingSimplifier:
+// a section of line is not replaced with a segment intersecting interiors of other segments.
+type topoSimplifier struct {
+	lines     [][]PostGISPoint
+	tolerance float64
+	segments  [][]*topoSegment // input segments by line
+	grid      segmentGrid
+
+	// state of the line being simplified
+	keep   []bool
+	result int // number of result segments
+}
+
+func newTopoSimplifier(lines [][]PostGISPoint, tolerance float64) *topoSimplifier {
+	s := &topoSimplifier{
+		lines:     lines,
+		tolerance: tolerance,
+		segments:  make([][]*topoSegment, len(lines)),
+	}
+
+	var points []PostGISPoint
+	for _, line := range lines {
+		points = append(points, line...)
+	}
+	s.grid = newSegmentGrid(pointsEnvelope(points, 0), len(points))
+	for l, line := range lines {
+		for i := 1; i < len(line); i++ {
+			seg := &topoSegment{
+				a:    planePoint{line[i-1].Lon, line[i-1].Lat},
+				b:    planePoint{line[i].Lon, line[i].Lat},
+				line: l,
+				i:    i - 1,
+			}
+			s.segments[l] = append(s.segments[l], seg)
+			s.grid.add(seg)
+		}
+	}
+	return s
+}
+
+// simplify simplifies line with the given index and returns its points.
+// Lines with less than minPoints points are returned as is; others keep at least minPoints points.
+func (s *topoSimplifier) simplify(line int, minPoints int) []PostGISPoint {
+	points := s.lines[line]
+	if len(points) < 3 || len(points) < minPoints {
+		return append([]PostGISPoint(nil), points...)
+	}
+
+	s.keep, s.result = make([]bool, len(points)), 0
+	s.keep[0] = true
+	s.simplifySection(line, 0, len(points)-1, 0, minPoints)
+
+	var res []PostGISPoint
+	for i, p := range points {
+		if s.keep[i] {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// simplifySection replaces a section of line between points i and j with a single segment if possible,
+// or splits it at the furthest point and simplifies both parts.
+func (s *topoSimplifier) simplifySection(line, i, j, depth, minPoints int) {
+	depth++
+	if i+1 == j {
+		s.keep[j] = true
+		s.result++
+		return
+	}
+
+	points := s.lines[line]
+	k, dist := furthestPoint(points, i, j)
+	valid := dist <= s.tolerance
+
+	// result may still get too small
+	if s.result+1 < minPoints && depth+1 < minPoints {
+		valid = false
+	}
+
+	seg := &topoSegment{
+		a:    planePoint{points[i].Lon, points[i].Lat},
+		b:    planePoint{points[j].Lon, points[j].Lat},
+		line: line,
+		i:    -1,
+	}
+	if valid && s.hasBadIntersection(seg, i, j) {
+		valid = false
+	}
+
+	if !valid {
+		s.simplifySection(line, i, k, depth, minPoints)
+		s.simplifySection(line, k, j, depth, minPoints)
+		return
+	}
+
+	for _, input := range s.segments[line][i:j] {
+		input.removed = true
+	}
+	s.grid.add(seg)
+	s.keep[j] = true
+	s.result++
+}
+
+// hasBadIntersection returns true if candidate segment replacing the section of its line
+// between points i and j intersects interior of other segments, or other segments intersect its interior.
+func (s *topoSimplifier) hasBadIntersection(candidate *topoSegment, i, j int) bool {
+	return s.grid.query(candidate.a, candidate.b, func(seg *topoSegment) bool {
+		if seg.removed || (seg.line == candidate.line && seg.i >= i && seg.i < j) {
+			return false
+		}
+		return interiorsIntersect(seg.a, seg.b, candidate.a, candidate.b)
+	})
+}
+
+// interiorsIntersect returns true if segments ab and cd intersect at a point which is not an end of both of them.
+func interiorsIntersect(a, b, c, d planePoint) bool {
+	p, q, n := segmentIntersection(a, b, c, d)
+	for _, ip := range []planePoint{p, q}[:n] {
+		if (ip != a && ip != b) || (ip != c && ip != d) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentGrid is a uniform grid of cells with segments intersecting their bounding boxes.
+type segmentGrid struct {
+	min   planePoint
+	size  float64
+	cells map[[2]int][]*topoSegment
+}
+
+// newSegmentGrid returns grid covering the given box with about n cells.
+func newSegmentGrid(box PostGISBox2D, n int) segmentGrid {
+	size := math.Max(box.Max.Lon-box.Min.Lon, box.Max.Lat-box.Min.Lat) / math.Sqrt(float64(n)+1)
+	if size == 0 {
+		size = 1
+	}
+	return segmentGrid{
+		min:   planePoint{box.Min.Lon, box.Min.Lat},
+		size:  size,
+		cells: make(map[[2]int][]*topoSegment),
+	}
+}
+
+// cell returns grid cell indexes of point p.
+func (g *segmentGrid) cell(p planePoint) (int, int) {
+	return int(math.Floor((p.x - g.min.x) / g.size)), int(math.Floor((p.y - g.min.y) / g.size))
+}
+
+// add adds segment to all cells intersecting its bounding box.
+func (g *segmentGrid) add(seg *topoSegment) {
+	x0, y0 := g.cell(planePoint{math.Min(seg.a.x, seg.b.x), math.Min(seg.a.y, seg.b.y)})
+	x1, y1 := g.cell(planePoint{math.Max(seg.a.x, seg.b.x), math.Max(seg.a.y, seg.b.y)})
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			g.cells[[2]int{x, y}] = append(g.cells[[2]int{x, y}], seg)
+		}
+	}
+}
+
+// query calls f for segments in cells intersecting bounding box of segment ab until f returns true.
+// It returns true if f returned true. Segments may be passed to f more than once.
+func (g *segmentGrid) query(a, b planePoint, f func(*topoSegment) bool) bool {
+	x0, y0 := g.cell(planePoint{math.Min(a.x, b.x), math.Min(a.y, b.y)})
+	x1, y1 := g.cell(planePoint{math.Max(a.x, b.x), math.Max(a.y, b.y)})
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			for _, seg := range g.cells[[2]int{x, y}] {
+				if f(seg) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// vwPoint is a point considered for removal by Visvalingam-Whyatt algorithm.
+type vwPoint struct {
+	i, prev, next int
+	area          float64
+	index         int // index in heap
+}
+
+// vwHeap is a min-heap of points by area.
+type vwHeap []*vwPoint
+
+func (h vwHeap) Len() int { return len(h) }
+
+func (h vwHeap) Less(i, j int) bool {
+	if h[i].area != h[j].area {
+		return h[i].area < h[j].area
+	}
+	return h[i].i < h[j].i
+}
+
+func (h vwHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *vwHeap) Push(x interface{}) {
+	p := x.(*vwPoint)
+	p.index = len(*h)
+	*h = append(*h, p)
+}
+
+func (h *vwHeap) Pop() interface{} {
+	old := *h
+	p := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return p
+}
+
+// visvalingamWhyatt returns points with effective area not less than the given area.
+// The first and the last points are always kept; points are not removed if less than minPoints would remain.
+func visvalingamWhyatt(points []PostGISPoint, area float64, minPoints int) []PostGISPoint {
+	if len(points) < 3 {
+		return append([]PostGISPoint(nil), points...)
+	}
+
+	triangleArea := func(a, b, c int) float64 {
+		pa := planePoint{points[a].Lon, points[a].Lat}
+		return math.Abs(orient(pa, planePoint{points[b].Lon, points[b].Lat}, planePoint{points[c].Lon, points[c].Lat})) / 2
+	}
+
+	vws := make([]*vwPoint, len(points))
+	h := make(vwHeap, 0, len(points)-2)
+	for i := 1; i < len(points)-1; i++ {
+		vws[i] = &vwPoint{i: i, prev: i - 1, next: i + 1, area: triangleArea(i-1, i, i+1)}
+		h.Push(vws[i])
+	}
+	heap.Init(&h)
+
+	// effective areas; the first, the last and not removed points are always kept
+	effective := make([]float64, len(points))
+	for i := range effective {
+		effective[i] = math.Inf(1)
+	}
+	var last float64
+	for remaining := len(points); h.Len() > 0 && remaining > minPoints; remaining-- {
+		p := heap.Pop(&h).(*vwPoint)
+		last = math.Max(last, p.area)
+		effective[p.i] = last
+
+		for _, n := range []int{p.prev, p.next} {
+			if vws[n] == nil {
+				continue
+			}
+			if n == p.prev {
+				vws[n].next = p.next
+			} else {
+				vws[n].prev = p.prev
+			}
+			vws[n].area = triangleArea(vws[n].prev, n, vws[n].next)
+			heap.Fix(&h, vws[n].index)
+		}
+	}
+
+	var res []PostGISPoint
+	for i, p := range points {
+		if effective[i] >= area {
+			res = append(res, p)
+		}
+	}
+	return res
+}
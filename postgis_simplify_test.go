@@ -0,0 +1,113 @@
+package pq_types
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISSimplify(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	line := func(points ...PostGISPoint) PostGISLineString { return PostGISLineString{Points: points} }
+
+	wave := line(pt(0, 0), pt(1, 0.1), pt(2, 0), pt(3, 0.1), pt(4, 0))
+	// simple DP makes it self-intersecting by removing (5, 1)
+	hook := line(pt(0, 0), pt(5, 1), pt(10, 0), pt(10, -5), pt(6, 0.3))
+	// simple DP moves hole outside by removing (5, 11)
+	bump := PostGISPolygon{
+		Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(5, 11), pt(10, 10), pt(10, 0), pt(0, 0)},
+		Holes:  [][]PostGISPoint{{pt(4.8, 9.8), pt(5.2, 9.8), pt(5, 10.6), pt(4.8, 9.8)}},
+		SRID:   3857,
+	}
+	bumpSimplified := PostGISPolygon{
+		Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0), pt(0, 0)},
+		SRID:   3857,
+	}
+
+	type testData struct {
+		g, simplify, preserveTopology PostGISGeometry
+		tolerance                     float64
+	}
+	for _, d := range []testData{
+		{wave, line(pt(0, 0), pt(4, 0)), line(pt(0, 0), pt(4, 0)), 0.2},
+		{wave, wave, wave, 0.05},
+		{line(pt(0, 0), pt(1, 0.1), pt(0, 0)), PostGISLineString{}, line(pt(0, 0), pt(0, 0)), 2},
+		{
+			PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0, Z: 1}, {Lon: 1, Lat: 0, Z: 2}, {Lon: 2, Lat: 0, Z: 3}}, SRID: 3857, Layout: PostGISLayoutXYZ},
+			PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0, Z: 1}, {Lon: 2, Lat: 0, Z: 3}}, SRID: 3857, Layout: PostGISLayoutXYZ},
+			PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0, Z: 1}, {Lon: 2, Lat: 0, Z: 3}}, SRID: 3857, Layout: PostGISLayoutXYZ},
+			0,
+		},
+		{hook, line(pt(0, 0), pt(10, 0), pt(10, -5), pt(6, 0.3)), hook, 1.2},
+		{bump, bumpSimplified, bump, 1.5},
+		{bump, PostGISPolygon{Points: bump.Points, SRID: 3857}, bump, 0.5},
+		{bump, PostGISPolygon{SRID: 3857}, PostGISPolygon{Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(5, 11), pt(10, 10), pt(0, 0)},
+			Holes: bump.Holes, SRID: 3857}, 20},
+		{PostGISLineString{}, PostGISLineString{}, PostGISLineString{}, 1},
+		{PostGISPolygon{}, PostGISPolygon{}, PostGISPolygon{}, 1},
+	} {
+		comment := Commentf("%v %v", d.g, d.tolerance)
+		switch g := d.g.(type) {
+		case PostGISLineString:
+			c.Check(g.Simplify(d.tolerance), DeepEquals, d.simplify, comment)
+			c.Check(g.SimplifyPreserveTopology(d.tolerance), DeepEquals, d.preserveTopology, comment)
+		case PostGISPolygon:
+			c.Check(g.Simplify(d.tolerance), DeepEquals, d.simplify, comment)
+			c.Check(g.SimplifyPreserveTopology(d.tolerance), DeepEquals, d.preserveTopology, comment)
+		}
+
+		if s.skipPostGIS || d.g.geometrySRID() != 0 {
+			continue
+		}
+
+		var simplify, preserveTopology PostGISAnyGeometry
+		err := s.db.QueryRow("SELECT ST_Simplify($1::geometry, $2), ST_SimplifyPreserveTopology($1::geometry, $2)",
+			d.g, d.tolerance).Scan(&simplify, &preserveTopology)
+		c.Check(err, IsNil, comment)
+		c.Check(simplify.Geometry, DeepEquals, d.simplify, comment)
+		c.Check(preserveTopology.Geometry, DeepEquals, d.preserveTopology, comment)
+	}
+}
+
+func (s *TypesSuite) TestPostGISSimplifyVW(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	line := func(points ...PostGISPoint) PostGISLineString { return PostGISLineString{Points: points} }
+
+	// effective areas are 1, 10 and 20
+	spike := line(pt(0, 0), pt(1, 1), pt(2, 0), pt(3, 10), pt(4, 0))
+	// effective area of (5, 10.1) is 0.5, then (0, 10) goes with 50
+	square := PostGISPolygon{
+		Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(5, 10.1), pt(10, 10), pt(12, -1), pt(0, 0)},
+		Holes:  [][]PostGISPoint{MakeEnvelope(pt(4, 4), pt(4.1, 4.1)).Points},
+	}
+
+	type testData struct {
+		g, simplified PostGISGeometry
+		area          float64
+	}
+	for _, d := range []testData{
+		{spike, spike, 0},
+		{spike, line(pt(0, 0), pt(2, 0), pt(3, 10), pt(4, 0)), 2},
+		{spike, line(pt(0, 0), pt(3, 10), pt(4, 0)), 15},
+		{spike, line(pt(0, 0), pt(4, 0)), 25},
+		{square, square, 0.001},
+		{square, PostGISPolygon{Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(12, -1), pt(0, 0)}}, 1},
+		{square, PostGISPolygon{Points: []PostGISPoint{pt(0, 0), pt(10, 10), pt(12, -1), pt(0, 0)}}, 100},
+		{PostGISLineString{SRID: 3857}, PostGISLineString{SRID: 3857}, 1},
+	} {
+		comment := Commentf("%v %v", d.g, d.area)
+		switch g := d.g.(type) {
+		case PostGISLineString:
+			c.Check(g.SimplifyVW(d.area), DeepEquals, d.simplified, comment)
+		case PostGISPolygon:
+			c.Check(g.SimplifyVW(d.area), DeepEquals, d.simplified, comment)
+		}
+
+		if s.skipPostGIS || d.g.geometrySRID() != 0 {
+			continue
+		}
+
+		var simplified PostGISAnyGeometry
+		err := s.db.QueryRow("SELECT ST_SimplifyVW($1::geometry, $2)", d.g, d.area).Scan(&simplified)
+		c.Check(err, IsNil, comment)
+		c.Check(simplified.Geometry, DeepEquals, d.simplified, comment)
+	}
+}
@@ -1,6 +1,9 @@
 package pq_types
 
 import (
+	"database/sql/driver"
+	"math"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -148,3 +151,275 @@ func (s *TypesSuite) TestPostGISPolygon(c *C) {
 		c.Check(p1, DeepEquals, p)
 	}
 }
+
+func (s *TypesSuite) TestPostGISPolygonAsEnvelope(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	box := PostGISBox2D{Min: pt(1, 2), Max: pt(3, 4)}
+
+	type testData struct {
+		p        PostGISPolygon
+		envelope PostGISBox2D
+		ok       bool
+	}
+	for _, d := range []testData{
+		{MakeEnvelope(pt(1, 2), pt(3, 4)), box, true},
+		{PostGISPolygon{Points: []PostGISPoint{pt(1, 2), pt(3, 2), pt(3, 4), pt(1, 4), pt(1, 2)}}, box, true},
+		{PostGISPolygon{Points: []PostGISPoint{pt(3, 4), pt(1, 4), pt(1, 2), pt(3, 2), pt(3, 4)}}, box, true},
+		{PostGISPolygon{Points: []PostGISPoint{pt(3, 2), pt(3, 4), pt(1, 4), pt(1, 2), pt(3, 2)}}, box, true},
+		{
+			MakeEnvelope(PostGISPoint{Lon: 1, Lat: 2, SRID: 3857}, pt(3, 4)),
+			PostGISBox2D{Min: PostGISPoint{Lon: 1, Lat: 2, SRID: 3857}, Max: PostGISPoint{Lon: 3, Lat: 4, SRID: 3857}},
+			true,
+		},
+
+		// not rectangles
+		{PostGISPolygon{Points: []PostGISPoint{pt(1, 2), pt(3, 2), pt(3, 4), pt(1, 2)}}, box, false},
+		{PostGISPolygon{Points: []PostGISPoint{pt(1, 2), pt(3, 2), pt(3, 4), pt(2, 4), pt(1, 2)}}, box, false},
+		{PostGISPolygon{Points: []PostGISPoint{pt(1, 2), pt(3, 2), pt(3, 4), pt(1, 4), pt(1, 3)}}, box, false},
+		{PostGISPolygon{Points: []PostGISPoint{pt(1, 2), pt(3, 2), pt(3, 2), pt(1, 4), pt(1, 2)}}, box, false},
+		{PostGISPolygon{Points: MakeEnvelope(pt(1, 2), pt(3, 4)).Points, Holes: [][]PostGISPoint{MakeEnvelope(pt(1.5, 2.5), pt(2, 3)).Points}}, box, false},
+		{PostGISPolygon{}, PostGISBox2D{}, false},
+	} {
+		comment := Commentf("%v", d.p)
+		envelope, ok := d.p.AsEnvelope()
+		c.Check(envelope, DeepEquals, d.envelope, comment)
+		c.Check(ok, Equals, d.ok, comment)
+		c.Check(d.p.Min(), DeepEquals, d.envelope.Min, comment)
+		c.Check(d.p.Max(), DeepEquals, d.envelope.Max, comment)
+	}
+}
+
+func (s *TypesSuite) TestPostGISSRID(c *C) {
+	v, err := PostGISPoint{Lon: 37.60889, Lat: 55.821913, SRID: 3857}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=3857;POINT(37.60889000 55.82191300)`), Commentf("%s", v))
+
+	_, err = PostGISPoint{SRID: -2}.Value()
+	c.Check(err, ErrorMatches, `PostGISPoint.Value: invalid SRID -2`)
+
+	p := MakeEnvelope(PostGISPoint{Lon: 1, Lat: 2, SRID: 3857}, PostGISPoint{Lon: 3, Lat: 4, SRID: 3857})
+	c.Check(p.SRID, Equals, 3857)
+	v, err = p.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=3857;POLYGON((1.00000000 2.00000000,1.00000000 4.00000000,3.00000000 4.00000000,3.00000000 2.00000000,1.00000000 2.00000000))`), Commentf("%s", v))
+
+	p.SRID = 0
+	_, err = p.Value()
+	c.Check(err, ErrorMatches, `PostGISPolygon.Value: point 0 has SRID 3857, expected 4326`)
+	_, err = PostGISPolygon{SRID: 1000000}.Value()
+	c.Check(err, ErrorMatches, `PostGISPolygon.Value: invalid SRID 1000000`)
+
+	// SRID 0 is kept distinct from PostGISDefaultSRID
+	unknown := PostGISPoint{Lon: 1, Lat: 2, SRID: PostGISUnknownSRID}
+	v, err = unknown.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=0;POINT(1.00000000 2.00000000)`), Commentf("%s", v))
+	var p1 PostGISPoint
+	for _, value := range []interface{}{
+		v,
+		"010100002000000000000000000000F03F0000000000000040",
+	} {
+		c.Check(p1.Scan(value), IsNil)
+		c.Check(p1, DeepEquals, unknown, Commentf("%s", value))
+	}
+	v, err = PostGISEWKB{Geometry: unknown}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, "010100002000000000000000000000F03F0000000000000040")
+	c.Check(p1.Scan(v), IsNil)
+	c.Check(p1, DeepEquals, unknown)
+
+	// PostGIS omits SRID 0 in its output
+	c.Check(p1.Scan("0101000000000000000000F03F0000000000000040"), IsNil)
+	c.Check(p1, DeepEquals, unknown)
+	v, err = p1.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=0;POINT(1.00000000 2.00000000)`), Commentf("%s", v))
+	c.Check(p1.Scan("POINT(1 2)"), IsNil)
+	c.Check(p1, DeepEquals, unknown)
+
+	mp := PostGISMultiPoint{Points: []PostGISPoint{unknown, {Lon: 3, Lat: 4}}, SRID: PostGISUnknownSRID}
+	v, err = mp.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=0;MULTIPOINT((1.00000000 2.00000000),(3.00000000 4.00000000))`), Commentf("%s", v))
+	mp.SRID = 0
+	_, err = mp.Value()
+	c.Check(err, ErrorMatches, `PostGISMultiPoint.Value: point 0 has SRID -1, expected 4326`)
+
+	if s.skipPostGIS {
+		return
+	}
+
+	// SRID 0 is kept through Scan and Value
+	var srid int
+	err = s.db.QueryRow("SELECT 'POINT(1 2)'::geometry").Scan(&p1)
+	c.Check(err, IsNil)
+	c.Check(p1, DeepEquals, unknown)
+	for _, v := range []driver.Valuer{p1, PostGISEWKB{Geometry: p1}} {
+		err = s.db.QueryRow("SELECT ST_SRID($1::geometry), ST_AsText($1::geometry)", v).Scan(&srid, &p1)
+		c.Check(err, IsNil)
+		c.Check(srid, Equals, 0)
+		c.Check(p1, DeepEquals, unknown)
+	}
+
+	for _, v := range []interface{}{
+		PostGISPoint{Lon: 37.60889, Lat: 55.821913},
+		PostGISPoint{Lon: 4187103.5, Lat: 7509543.25, SRID: 3857},
+		PostGISPoint{Lon: 0.5, Lat: 0.25, SRID: 27700},
+		PostGISPolygon{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 1, Lat: 0}, {Lon: 0, Lat: 0}}, SRID: 3857},
+	} {
+		s.SetUpTest(c)
+
+		_, err = s.db.Exec("INSERT INTO pq_types (geometry) VALUES($1)", v)
+		c.Assert(err, IsNil)
+
+		var srid int
+		err = s.db.QueryRow("SELECT ST_SRID(geometry) FROM pq_types").Scan(&srid)
+		c.Check(err, IsNil)
+
+		switch v := v.(type) {
+		case PostGISPoint:
+			var p1 PostGISPoint
+			err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&p1)
+			c.Check(err, IsNil)
+			c.Check(p1, DeepEquals, v)
+			if v.SRID == 0 {
+				c.Check(srid, Equals, PostGISDefaultSRID)
+			} else {
+				c.Check(srid, Equals, v.SRID)
+			}
+		case PostGISPolygon:
+			var p1 PostGISPolygon
+			err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&p1)
+			c.Check(err, IsNil)
+			c.Check(p1, DeepEquals, v)
+			c.Check(srid, Equals, v.SRID)
+		}
+	}
+}
+
+func (s *TypesSuite) TestPostGISPolygonHoles(c *C) {
+	var p PostGISPolygon
+	c.Check(p.Scan("002000000300000F1100000003000000050000000000000000000000000000000040240000000000000000000000000000402400000000000040240000000000000000000000000000402400000000000000000000000000000000000000000000000000043FF00000000000003FF000000000000040000000000000003FF0000000000000400000000000000040000000000000003FF00000000000003FF00000000000000000000440140000000000004014000000000000401800000000000040140000000000004018000000000000401800000000000040140000000000004014000000000000"), IsNil)
+	c.Check(p, DeepEquals, PostGISPolygon{
+		Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 10}, {Lon: 0, Lat: 0}},
+		Holes: [][]PostGISPoint{
+			{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 1, Lat: 1}},
+			{{Lon: 5, Lat: 5}, {Lon: 6, Lat: 5}, {Lon: 6, Lat: 6}, {Lon: 5, Lat: 5}},
+		},
+		SRID: 3857,
+	})
+	c.Check(p.Rings(), DeepEquals, [][]PostGISPoint{p.Points, p.Holes[0], p.Holes[1]})
+	c.Check(PostGISPolygon{}.Rings(), IsNil)
+
+	v, err := p.Value()
+	c.Check(err, IsNil)
+	c.Check(v, DeepEquals, []byte(`SRID=3857;POLYGON((0.00000000 0.00000000,10.00000000 0.00000000,10.00000000 10.00000000,0.00000000 10.00000000,0.00000000 0.00000000),(1.00000000 1.00000000,2.00000000 1.00000000,2.00000000 2.00000000,1.00000000 1.00000000),(5.00000000 5.00000000,6.00000000 5.00000000,6.00000000 6.00000000,5.00000000 5.00000000))`), Commentf("%s", v))
+
+	p.Holes[1][2].SRID = 4326
+	_, err = p.Value()
+	c.Check(err, ErrorMatches, `PostGISPolygon.Value: hole 1: point 2 has SRID 4326, expected 3857`)
+	p.Holes[1] = nil
+	_, err = p.Value()
+	c.Check(err, ErrorMatches, `PostGISPolygon.Value: hole 1 is empty`)
+	_, err = PostGISPolygon{Holes: p.Holes[:1]}.Value()
+	c.Check(err, ErrorMatches, `PostGISPolygon.Value: polygon with holes has empty exterior ring`)
+	_, err = PostGISMultiPolygon{Polygons: []PostGISPolygon{{Holes: p.Holes[:1]}}}.Value()
+	c.Check(err, ErrorMatches, `PostGISMultiPolygon.Value: polygon 0: polygon with holes has empty exterior ring`)
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, p := range []PostGISPolygon{
+		{
+			Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 10}, {Lon: 0, Lat: 0}},
+			Holes:  [][]PostGISPoint{{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 1, Lat: 1}}},
+		},
+	} {
+		s.SetUpTest(c)
+
+		_, err = s.db.Exec("INSERT INTO pq_types (polygon) VALUES($1)", p)
+		c.Assert(err, IsNil)
+
+		var p1 PostGISPolygon
+		err = s.db.QueryRow("SELECT polygon FROM pq_types").Scan(&p1)
+		c.Check(err, IsNil)
+		c.Check(p1, DeepEquals, p)
+
+		var rings int
+		err = s.db.QueryRow("SELECT ST_NRings(polygon) FROM pq_types").Scan(&rings)
+		c.Check(err, IsNil)
+		c.Check(rings, Equals, 2)
+	}
+}
+
+func (s *TypesSuite) TestPostGISPolygonMeasurements(c *C) {
+	type testData struct {
+		p         PostGISPolygon
+		area      float64
+		perimeter float64
+		centroid  PostGISPoint
+		envelope  PostGISBox2D
+	}
+	for _, d := range []testData{
+		{
+			PostGISPolygon{
+				Points: MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 10, Lat: 10}).Points,
+				Holes:  [][]PostGISPoint{MakeEnvelope(PostGISPoint{Lon: 6, Lat: 4}, PostGISPoint{Lon: 8, Lat: 6}).Points},
+				SRID:   3857,
+			},
+			96, 48,
+			PostGISPoint{Lon: 472.0 / 96, Lat: 5, SRID: 3857},
+			PostGISBox2D{Min: PostGISPoint{Lon: 0, Lat: 0, SRID: 3857}, Max: PostGISPoint{Lon: 10, Lat: 10, SRID: 3857}},
+		},
+		{
+			// L-shaped, counterclockwise
+			PostGISPolygon{Points: []PostGISPoint{
+				{Lon: 0, Lat: 0}, {Lon: 2, Lat: 0}, {Lon: 2, Lat: 1}, {Lon: 1, Lat: 1}, {Lon: 1, Lat: 2}, {Lon: 0, Lat: 2}, {Lon: 0, Lat: 0},
+			}},
+			3, 8,
+			PostGISPoint{Lon: 2.5 / 3, Lat: 2.5 / 3},
+			PostGISBox2D{Min: PostGISPoint{Lon: 0, Lat: 0}, Max: PostGISPoint{Lon: 2, Lat: 2}},
+		},
+		{
+			// degenerate
+			PostGISPolygon{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 2, Lat: 0}, {Lon: 0, Lat: 0}, {Lon: 0, Lat: 0}}},
+			0, 4,
+			PostGISPoint{Lon: 1, Lat: 0},
+			PostGISBox2D{Min: PostGISPoint{Lon: 0, Lat: 0}, Max: PostGISPoint{Lon: 2, Lat: 0}},
+		},
+		{PostGISPolygon{SRID: 3857}, 0, 0, PostGISPoint{SRID: 3857}, PostGISBox2D{}},
+	} {
+		c.Check(d.p.Area(), Equals, d.area)
+		c.Check(d.p.Perimeter(), Equals, d.perimeter)
+		centroid := d.p.Centroid()
+		c.Check(math.Abs(centroid.Lon-d.centroid.Lon) < 1e-12 && math.Abs(centroid.Lat-d.centroid.Lat) < 1e-12, Equals, true,
+			Commentf("%v != %v", centroid, d.centroid))
+		c.Check(centroid.SRID, Equals, d.centroid.SRID)
+		c.Check(d.p.Envelope(), DeepEquals, d.envelope)
+	}
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, p := range []PostGISPolygon{
+		{
+			Points: MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: 10, Lat: 10}).Points,
+			Holes:  [][]PostGISPoint{MakeEnvelope(PostGISPoint{Lon: 6, Lat: 4}, PostGISPoint{Lon: 8, Lat: 6}).Points},
+		},
+		{Points: []PostGISPoint{{Lon: 37.5, Lat: 55.5}, {Lon: 37.9, Lat: 55.6}, {Lon: 37.6, Lat: 55.9}, {Lon: 37.5, Lat: 55.5}}},
+	} {
+		var area, perimeter float64
+		var centroid PostGISPoint
+		var envelope PostGISBox2D
+		err := s.db.QueryRow("SELECT ST_Area($1::geometry), ST_Perimeter($1::geometry), ST_Centroid($1::geometry), Box2D($1::geometry)",
+			p).Scan(&area, &perimeter, &centroid, &envelope)
+		c.Check(err, IsNil)
+		c.Check(math.Abs(p.Area()-area) < 1e-9, Equals, true, Commentf("%v != %v", p.Area(), area))
+		c.Check(math.Abs(p.Perimeter()-perimeter) < 1e-9, Equals, true, Commentf("%v != %v", p.Perimeter(), perimeter))
+		c1 := p.Centroid()
+		c.Check(math.Abs(c1.Lon-centroid.Lon) < 1e-9 && math.Abs(c1.Lat-centroid.Lat) < 1e-9, Equals, true, Commentf("%v != %v", c1, centroid))
+		c.Check(p.Envelope(), DeepEquals, envelope)
+	}
+}
@@ -0,0 +1,372 @@
+package pq_types
+
+import (
+	"fmt"
+	"math"
+)
+
+// SRIDs of spatial reference systems supported by PostGISTransform.
+const (
+	PostGISWebMercatorSRID  = 3857  // WGS 84 / Pseudo-Mercator, used by web maps
+	PostGISUTMNorthBaseSRID = 32600 // WGS 84 / UTM zone N north is PostGISUTMNorthBaseSRID + N
+	PostGISUTMSouthBaseSRID = 32700 // WGS 84 / UTM zone N south is PostGISUTMSouthBaseSRID + N
+)
+
+// PostGISTransform returns geometry g with coordinates transformed to spatial reference system with the given SRID,
+// like PostGIS ST_Transform. Supported systems are WGS 84 longitudes and latitudes (PostGISDefaultSRID, also used for
+// zero SRID), Web Mercator (PostGISWebMercatorSRID) and WGS 84 UTM zones 1-60 (SRIDs 32601-32660 for northern
+// and 32701-32760 for southern hemisphere). Transformations are done in Go without PROJ; UTM uses Krüger series
+// accurate to a few nanometers within 3900 km of the central meridian.
+//
+// Longitudes are returned in range [-180, 180]. It is an error to project poles to Web Mercator,
+// or points more than 90 degrees of longitude away from the central meridian to UTM.
+// SRIDs of the result and its elements with non-zero SRIDs are set to the given one,
+// with PostGISDefaultSRID stored as zero like Scan does. Z and M coordinates and empty points are kept.
+func PostGISTransform(g PostGISGeometry, srid int) (PostGISGeometry, error) {
+	from, _, err := checkTopGeometry(g)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISTransform: %s", err)
+	}
+	to, err := postGISSRID(srid)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISTransform: %s", err)
+	}
+
+	fromProj, err := findProjection(from)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISTransform: %s", err)
+	}
+	toProj, err := findProjection(to)
+	if err != nil {
+		return nil, fmt.Errorf("PostGISTransform: %s", err)
+	}
+
+	same := from == to
+	if to == PostGISDefaultSRID {
+		to = 0
+	}
+	res, err := transformGeometry(g, to, func(p PostGISPoint) (PostGISPoint, error) {
+		if same || isEmptyPoint(p) {
+			return p, nil
+		}
+		lon, lat, err := fromProj.inverse(p.Lon, p.Lat)
+		if err != nil {
+			return p, err
+		}
+		p.Lon, p.Lat, err = toProj.forward(lon, lat)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PostGISTransform: %s", err)
+	}
+	return res, nil
+}
+
+// transformGeometry returns geometry g with points transformed by f and SRIDs set to the given one.
+func transformGeometry(g PostGISGeometry, srid int, f func(PostGISPoint) (PostGISPoint, error)) (PostGISGeometry, error) {
+	point := func(p PostGISPoint) (PostGISPoint, error) {
+		p, err := f(p)
+		if p.SRID != 0 {
+			p.SRID = srid
+		}
+		return p, err
+	}
+	points := func(points []PostGISPoint) ([]PostGISPoint, error) {
+		if points == nil {
+			return nil, nil
+		}
+		res := make([]PostGISPoint, len(points))
+		for i, p := range points {
+			var err error
+			if res[i], err = point(p); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+	polygon := func(p PostGISPolygon) (PostGISPolygon, error) {
+		var err error
+		if p.Points, err = points(p.Points); err != nil {
+			return p, err
+		}
+		holes := p.Holes
+		if holes != nil {
+			p.Holes = make([][]PostGISPoint, len(holes))
+		}
+		for i, hole := range holes {
+			if p.Holes[i], err = points(hole); err != nil {
+				return p, err
+			}
+		}
+		return p, nil
+	}
+	nested := func(g PostGISGeometry) (PostGISGeometry, error) {
+		nestedSRID := srid
+		if g.geometrySRID() == 0 {
+			nestedSRID = 0
+		}
+		return transformGeometry(g, nestedSRID, f)
+	}
+
+	var err error
+	switch g := g.(type) {
+	case PostGISPoint:
+		g, err = f(g)
+		g.SRID = srid
+		return g, err
+	case PostGISLineString:
+		g.Points, err = points(g.Points)
+		g.SRID = srid
+		return g, err
+	case PostGISPolygon:
+		g, err = polygon(g)
+		g.SRID = srid
+		return g, err
+	case PostGISMultiPoint:
+		g.Points, err = points(g.Points)
+		g.SRID = srid
+		return g, err
+	case PostGISMultiLineString:
+		lineStrings := g.LineStrings
+		if lineStrings != nil {
+			g.LineStrings = make([]PostGISLineString, len(lineStrings))
+		}
+		for i, l := range lineStrings {
+			var e PostGISGeometry
+			if e, err = nested(l); err != nil {
+				return nil, err
+			}
+			g.LineStrings[i] = e.(PostGISLineString)
+		}
+		g.SRID = srid
+		return g, nil
+	case PostGISMultiPolygon:
+		polygons := g.Polygons
+		if polygons != nil {
+			g.Polygons = make([]PostGISPolygon, len(polygons))
+		}
+		for i, p := range polygons {
+			var e PostGISGeometry
+			if e, err = nested(p); err != nil {
+				return nil, err
+			}
+			g.Polygons[i] = e.(PostGISPolygon)
+		}
+		g.SRID = srid
+		return g, nil
+	case PostGISGeometryCollection:
+		geometries := g.Geometries
+		if geometries != nil {
+			g.Geometries = make([]PostGISGeometry, len(geometries))
+		}
+		for i, e := range geometries {
+			if g.Geometries[i], err = nested(e); err != nil {
+				return nil, err
+			}
+		}
+		g.SRID = srid
+		return g, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %T", g)
+}
+
+// projection converts longitudes and latitudes in degrees to and from coordinates of spatial reference system.
+type projection interface {
+	forward(lon, lat float64) (x, y float64, err error)
+	inverse(x, y float64) (lon, lat float64, err error)
+}
+
+// findProjection returns projection for SRID.
+func findProjection(srid int) (projection, error) {
+	switch {
+	case srid == PostGISDefaultSRID:
+		return lonLatProjection{}, nil
+	case srid == PostGISWebMercatorSRID:
+		return webMercatorProjection{}, nil
+	case srid > PostGISUTMNorthBaseSRID && srid <= PostGISUTMNorthBaseSRID+60:
+		return utmProjection{zone: srid - PostGISUTMNorthBaseSRID}, nil
+	case srid > PostGISUTMSouthBaseSRID && srid <= PostGISUTMSouthBaseSRID+60:
+		return utmProjection{zone: srid - PostGISUTMSouthBaseSRID, south: true}, nil
+	}
+	return nil, fmt.Errorf("unsupported SRID %d", srid)
+}
+
+// normalizeLongitude returns longitude in range [-180, 180].
+func normalizeLongitude(lon float64) float64 {
+	return math.Remainder(lon, 360)
+}
+
+// checkLatitude checks that latitude is in range [-90, 90].
+func checkLatitude(lat float64) error {
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude %v is out of range [-90, 90]", lat)
+	}
+	return nil
+}
+
+// lonLatProjection is identity projection for longitudes and latitudes.
+type lonLatProjection struct{}
+
+func (lonLatProjection) forward(lon, lat float64) (float64, float64, error) {
+	return normalizeLongitude(lon), lat, checkLatitude(lat)
+}
+
+func (lonLatProjection) inverse(lon, lat float64) (float64, float64, error) {
+	return lon, lat, checkLatitude(lat)
+}
+
+// webMercatorProjection is spherical Mercator projection used by web maps, EPSG:3857.
+type webMercatorProjection struct{}
+
+func (webMercatorProjection) forward(lon, lat float64) (float64, float64, error) {
+	if err := checkLatitude(lat); err != nil {
+		return 0, 0, err
+	}
+	if lat == -90 || lat == 90 {
+		return 0, 0, fmt.Errorf("latitude %v can't be projected to Web Mercator", lat)
+	}
+	return wgs84A * radians(normalizeLongitude(lon)), wgs84A * math.Atanh(math.Sin(radians(lat))), nil
+}
+
+func (webMercatorProjection) inverse(x, y float64) (float64, float64, error) {
+	return normalizeLongitude(degrees(x / wgs84A)), degrees(math.Atan(math.Sinh(y / wgs84A))), nil
+}
+
+// UTM parameters.
+const (
+	utmScale         = 0.9996
+	utmFalseEasting  = 500000
+	utmFalseNorthing = 10000000 // for southern hemisphere
+)
+
+// utmProjection is transverse Mercator projection of UTM zone on WGS 84 ellipsoid.
+type utmProjection struct {
+	zone  int
+	south bool
+}
+
+// centralMeridian returns longitude of the zone's central meridian.
+func (p utmProjection) centralMeridian() float64 {
+	return float64(6*p.zone - 183)
+}
+
+func (p utmProjection) forward(lon, lat float64) (float64, float64, error) {
+	if err := checkLatitude(lat); err != nil {
+		return 0, 0, err
+	}
+	dLon := normalizeLongitude(lon - p.centralMeridian())
+	if math.Abs(dLon) > 90 {
+		return 0, 0, fmt.Errorf("longitude %v is too far from UTM zone %d", lon, p.zone)
+	}
+
+	x, y := wgs84TransverseMercator.forward(radians(dLon), radians(lat))
+	x = utmScale*x + utmFalseEasting
+	y = utmScale * y
+	if p.south {
+		y += utmFalseNorthing
+	}
+	return x, y, nil
+}
+
+func (p utmProjection) inverse(x, y float64) (float64, float64, error) {
+	x = (x - utmFalseEasting) / utmScale
+	if p.south {
+		y -= utmFalseNorthing
+	}
+	y /= utmScale
+
+	lon, lat := wgs84TransverseMercator.inverse(x, y)
+	return normalizeLongitude(degrees(lon) + p.centralMeridian()), degrees(lat), nil
+}
+
+// transverseMercator is transverse Mercator projection with unit scale on the central meridian,
+// computed with Krüger series of the 6th order, see C. F. F. Karney, Transverse Mercator with an accuracy
+// of a few nanometers, J. Geodesy 85(8), 475-485 (2011).
+type transverseMercator struct {
+	e           float64    // eccentricity
+	a           float64    // rectifying radius
+	alpha, beta [6]float64 // series coefficients
+}
+
+var wgs84TransverseMercator = newTransverseMercator(wgs84A, wgs84F)
+
+func newTransverseMercator(a, f float64) *transverseMercator {
+	n := f / (2 - f)
+	n2, n3, n4, n5, n6 := n*n, n*n*n, n*n*n*n, n*n*n*n*n, n*n*n*n*n*n
+	return &transverseMercator{
+		e: math.Sqrt(f * (2 - f)),
+		a: a / (1 + n) * (1 + n2/4 + n4/64 + n6/256),
+		alpha: [6]float64{
+			n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180 - 127*n5/288 + 7891*n6/37800,
+			13*n2/48 - 3*n3/5 + 557*n4/1440 + 281*n5/630 - 1983433*n6/1935360,
+			61*n3/240 - 103*n4/140 + 15061*n5/26880 + 167603*n6/181440,
+			49561*n4/161280 - 179*n5/168 + 6601661*n6/7257600,
+			34729*n5/80640 - 3418889*n6/1995840,
+			212378941 * n6 / 319334400,
+		},
+		beta: [6]float64{
+			n/2 - 2*n2/3 + 37*n3/96 - n4/360 - 81*n5/512 + 96199*n6/604800,
+			n2/48 + n3/15 - 437*n4/1440 + 46*n5/105 - 1118711*n6/3870720,
+			17*n3/480 - 37*n4/840 - 209*n5/4480 + 5569*n6/90720,
+			4397*n4/161280 - 11*n5/504 - 830251*n6/7257600,
+			4583*n5/161280 - 108847*n6/3991680,
+			20648693 * n6 / 638668800,
+		},
+	}
+}
+
+// conformalTan returns tangent of conformal latitude for tangent of geographic latitude.
+func (tm *transverseMercator) conformalTan(tau float64) float64 {
+	sigma := math.Sinh(tm.e * math.Atanh(tm.e*tau/math.Sqrt(1+tau*tau)))
+	return tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+}
+
+// forward returns easting and northing for longitude relative to the central meridian and latitude in radians.
+func (tm *transverseMercator) forward(lon, lat float64) (float64, float64) {
+	var xi, eta float64
+	if math.Abs(lat) == math.Pi/2 {
+		xi = lat
+	} else {
+		tau := tm.conformalTan(math.Tan(lat))
+		xi = math.Atan2(tau, math.Cos(lon))
+		eta = math.Asinh(math.Sin(lon) / math.Hypot(tau, math.Cos(lon)))
+	}
+
+	x, y := eta, xi
+	for j, a := range tm.alpha {
+		k := float64(2 * (j + 1))
+		y += a * math.Sin(k*xi) * math.Cosh(k*eta)
+		x += a * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+	return tm.a * x, tm.a * y
+}
+
+// inverse returns longitude relative to the central meridian and latitude in radians for easting and northing.
+func (tm *transverseMercator) inverse(x, y float64) (float64, float64) {
+	xi, eta := y/tm.a, x/tm.a
+	xi1, eta1 := xi, eta
+	for j, b := range tm.beta {
+		k := float64(2 * (j + 1))
+		xi1 -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta1 -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	lon := math.Atan2(math.Sinh(eta1), math.Cos(xi1))
+	tau1 := math.Sin(xi1) / math.Hypot(math.Sinh(eta1), math.Cos(xi1))
+	if math.IsInf(tau1, 0) || math.Abs(tau1) > 1e15 {
+		return lon, math.Copysign(math.Pi/2, tau1)
+	}
+
+	// solve conformalTan(tau) = tau1 with Newton's method
+	tau := tau1
+	e2 := tm.e * tm.e
+	for i := 0; i < 5; i++ {
+		t1 := tm.conformalTan(tau)
+		d := (tau1 - t1) / math.Sqrt(1+t1*t1) * (1 + (1-e2)*tau*tau) / ((1 - e2) * math.Sqrt(1+tau*tau))
+		tau += d
+		if math.Abs(d) < 1e-14*math.Max(1, math.Abs(tau)) {
+			break
+		}
+	}
+	return lon, math.Atan(tau)
+}
@@ -0,0 +1,157 @@
+package pq_types
+
+import (
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISTransform(c *C) {
+	type testData struct {
+		lon, lat float64
+		srid     int
+		x, y     float64
+	}
+	for _, d := range []testData{
+		{0, 0, 3857, 0, 0},
+		{180, 0, 3857, 20037508.342789244, 0},
+		{-180, 0, 3857, -20037508.342789244, 0},
+		{360 + 37.6173, 0, 3857, 4187538.68, 0},
+		{0, 85.05112877980659, 3857, 0, 20037508.342789244},
+		// EPSG Guidance Note 7-2 example
+		{-(100 + 20.0/60), 24 + 22.0/60 + 54.433/3600, 3857, -11169055.58, 2800000.00},
+
+		{3, 0, 32631, 500000, 0},
+		{0, 0, 32631, 166021.4431, 0},
+		{7.5, 51.2, 32632, 395201.3104, 5673135.2412},
+		{7.5, -51.2, 32732, 395201.3104, 10000000 - 5673135.2412},
+		{-1.5, 51.2, 32630, 1000000 - 395201.3104, 5673135.2412},
+		// quarter meridian is 10001965.7293 m
+		{3, 90, 32631, 500000, 10001965.7293 * 0.9996},
+		{3, -90, 32731, 500000, 10000000 - 10001965.7293*0.9996},
+	} {
+		comment := Commentf("%v", d)
+		g, err := PostGISTransform(PostGISPoint{Lon: d.lon, Lat: d.lat}, d.srid)
+		c.Assert(err, IsNil, comment)
+		p := g.(PostGISPoint)
+		c.Check(p.SRID, Equals, d.srid, comment)
+		c.Check(math.Abs(p.Lon-d.x) < 0.01 && math.Abs(p.Lat-d.y) < 0.01, Equals, true, Commentf("%v: %.4f %.4f", d, p.Lon, p.Lat))
+
+		g, err = PostGISTransform(p, 0)
+		c.Assert(err, IsNil, comment)
+		p = g.(PostGISPoint)
+		c.Check(p.SRID, Equals, 0, comment)
+		lon := math.Remainder(d.lon, 360)
+		if math.Abs(d.lat) == 90 {
+			lon = p.Lon
+		}
+		c.Check(math.Abs(math.Remainder(p.Lon-lon, 360)) < 1e-9 && math.Abs(p.Lat-d.lat) < 1e-9, Equals, true,
+			Commentf("%v: %v %v", d, p.Lon, p.Lat))
+
+		if s.skipPostGIS {
+			continue
+		}
+
+		var x, y float64
+		err = s.db.QueryRow("SELECT ST_X(t), ST_Y(t) FROM ST_Transform(ST_SetSRID(ST_MakePoint($1, $2), 4326), $3::integer) t",
+			d.lon, d.lat, d.srid).Scan(&x, &y)
+		c.Check(err, IsNil, comment)
+		c.Check(math.Abs(x-d.x) < 0.01 && math.Abs(y-d.y) < 0.01, Equals, true, Commentf("%v: %.4f %.4f", d, x, y))
+	}
+}
+
+func (s *TypesSuite) TestPostGISTransformGeometries(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	points := func(g PostGISGeometry) []PostGISPoint {
+		var res []PostGISPoint
+		transformGeometry(g, 0, func(p PostGISPoint) (PostGISPoint, error) {
+			res = append(res, p)
+			return p, nil
+		})
+		return res
+	}
+	polygon := PostGISPolygon{
+		Points: MakeEnvelope(pt(37.5, 55.5), pt(37.7, 55.9)).Points,
+		Holes:  [][]PostGISPoint{MakeEnvelope(pt(37.55, 55.6), pt(37.6, 55.7)).Points},
+	}
+
+	for _, g := range []PostGISGeometry{
+		PostGISPoint{Lon: 37.6, Lat: 55.7, Z: 150, M: 1, Layout: PostGISLayoutXYZM},
+		PostGISLineString{Points: []PostGISPoint{pt(37.5, 55.5), {Lon: 37.6, Lat: 55.6, SRID: 4326}}},
+		polygon,
+		PostGISMultiPoint{Points: []PostGISPoint{pt(37.5, 55.5), pt(38, 56)}, SRID: 4326},
+		PostGISMultiLineString{LineStrings: []PostGISLineString{{Points: []PostGISPoint{pt(37.5, 55.5), pt(38, 56)}}}},
+		PostGISMultiPolygon{Polygons: []PostGISPolygon{polygon, {Points: MakeEnvelope(pt(38, 56), pt(39, 57)).Points, SRID: 4326}}},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{pt(37.6, 55.7), polygon}},
+		PostGISPolygon{},
+	} {
+		comment := Commentf("%v", g)
+		for _, srid := range []int{3857, 32637} {
+			projected, err := PostGISTransform(g, srid)
+			c.Assert(err, IsNil, comment)
+			c.Check(projected.geometrySRID(), Equals, srid, comment)
+			c.Check(checkGeometry(projected, srid, projected.geometryLayout()), IsNil, comment)
+
+			back, err := PostGISTransform(projected, 4326)
+			c.Assert(err, IsNil, comment)
+			c.Check(back.geometrySRID(), Equals, 0, comment)
+			c.Check(checkGeometry(back, PostGISDefaultSRID, back.geometryLayout()), IsNil, comment)
+
+			c.Check(back.GeometryType(), Equals, g.GeometryType(), comment)
+			before, after := points(g), points(back)
+			c.Assert(after, HasLen, len(before), comment)
+			for i, p := range before {
+				c.Check(math.Abs(after[i].Lon-p.Lon) < 1e-9 && math.Abs(after[i].Lat-p.Lat) < 1e-9, Equals, true,
+					Commentf("%v != %v", after[i], p))
+			}
+		}
+	}
+
+	// empty points are kept
+	empty := PostGISPoint{Lon: math.NaN(), Lat: math.NaN()}
+	for _, d := range []struct {
+		g   PostGISGeometry
+		wkt string
+	}{
+		{empty, "SRID=3857;POINT EMPTY"},
+		{PostGISMultiPoint{Points: []PostGISPoint{pt(0, 0), empty}}, "SRID=3857;MULTIPOINT((0.00000000 0.00000000),EMPTY)"},
+		{
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{empty, pt(0, 0)}},
+			"SRID=3857;GEOMETRYCOLLECTION(POINT EMPTY,POINT(0.00000000 0.00000000))",
+		},
+	} {
+		projected, err := PostGISTransform(d.g, 3857)
+		c.Assert(err, IsNil, Commentf("%s", d.wkt))
+		v, err := PostGISAnyGeometry{Geometry: projected}.Value()
+		c.Check(err, IsNil)
+		c.Check(string(v.([]byte)), Equals, d.wkt)
+	}
+
+	p, err := PostGISTransform(PostGISPoint{Lon: 37.6, Lat: 55.7, Z: 150, M: 1, Layout: PostGISLayoutXYZM}, 3857)
+	c.Assert(err, IsNil)
+	c.Check(p.(PostGISPoint).Z, Equals, 150.0)
+	c.Check(p.(PostGISPoint).M, Equals, 1.0)
+	c.Check(p.(PostGISPoint).Layout, Equals, PostGISLayoutXYZM)
+
+	p, err = PostGISTransform(p, 3857)
+	c.Assert(err, IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: p.(PostGISPoint).Lon, Lat: p.(PostGISPoint).Lat, Z: 150, M: 1, SRID: 3857, Layout: PostGISLayoutXYZM})
+
+	for _, d := range []struct {
+		g    PostGISGeometry
+		srid int
+		err  string
+	}{
+		{pt(0, 0), 2154, "PostGISTransform: unsupported SRID 2154"},
+		{PostGISPoint{SRID: 2154}, 4326, "PostGISTransform: unsupported SRID 2154"},
+		{pt(0, 0), -2, "PostGISTransform: invalid SRID -2"},
+		{pt(0, 0), PostGISUnknownSRID, "PostGISTransform: unsupported SRID 0"},
+		{pt(0, 90), 3857, "PostGISTransform: latitude 90 can't be projected to Web Mercator"},
+		{pt(0, 91), 3857, `PostGISTransform: latitude 91 is out of range \[-90, 90\]`},
+		{pt(100, 0), 32631, "PostGISTransform: longitude 100 is too far from UTM zone 31"},
+		{PostGISLineString{Points: []PostGISPoint{{SRID: 3857}}}, 4326, "PostGISTransform: point 0 has SRID 3857, expected 4326"},
+	} {
+		_, err := PostGISTransform(d.g, d.srid)
+		c.Check(err, ErrorMatches, d.err)
+	}
+}
@@ -0,0 +1,707 @@
+package pq_types
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// TWKB metadata flags.
+const (
+	twkbBox      = 0x01
+	twkbSize     = 0x02
+	twkbIDList   = 0x04
+	twkbExtended = 0x08
+	twkbEmpty    = 0x10
+)
+
+// PostGISTWKBOptions describes how geometry is encoded as Tiny WKB, like arguments of PostGIS ST_AsTWKB.
+type PostGISTWKBOptions struct {
+	Precision  int  // number of decimal digits for X and Y in range [-7, 7]; negative values round to tens, hundreds, etc.
+	PrecisionZ int  // number of decimal digits for Z in range [0, 7]
+	PrecisionM int  // number of decimal digits for M in range [0, 7]
+	WithSizes  bool // include sizes of geometries
+	WithBoxes  bool // include bounding boxes of geometries
+}
+
+// MarshalPostGISTWKB returns geometry of any type as Tiny WKB, like PostGIS ST_AsTWKB.
+//
+// Coordinates are rounded to the given precisions and written as deltas from previous points,
+// so encoded geometries are much smaller than WKB. Like PostGIS, it skips points which are equal
+// to previous ones after rounding, keeping at least 2 points in line strings and 4 points in rings.
+// TWKB has no SRID, so geometry SRID is only checked. Points with NaN Lon and Lat are empty.
+func MarshalPostGISTWKB(g PostGISGeometry, opts PostGISTWKBOptions) ([]byte, error) {
+	_, layout, err := checkTopGeometry(g)
+	if err != nil {
+		return nil, fmt.Errorf("MarshalPostGISTWKB: %s", err)
+	}
+	if opts.Precision < -7 || opts.Precision > 7 {
+		return nil, fmt.Errorf("MarshalPostGISTWKB: precision %d is out of range [-7, 7]", opts.Precision)
+	}
+	if layout.HasZ() && (opts.PrecisionZ < 0 || opts.PrecisionZ > 7) {
+		return nil, fmt.Errorf("MarshalPostGISTWKB: Z precision %d is out of range [0, 7]", opts.PrecisionZ)
+	}
+	if layout.HasM() && (opts.PrecisionM < 0 || opts.PrecisionM > 7) {
+		return nil, fmt.Errorf("MarshalPostGISTWKB: M precision %d is out of range [0, 7]", opts.PrecisionM)
+	}
+
+	b, err := newTWKBWriter(&opts, layout).marshal(g)
+	if err != nil {
+		return nil, fmt.Errorf("MarshalPostGISTWKB: %s", err)
+	}
+	return b, nil
+}
+
+// ParsePostGISTWKB decodes Tiny WKB geometry of any type, like PostGIS ST_GeomFromTWKB.
+// Returned geometry has zero SRID; layout is set for top-level geometry only, like for WKB.
+// ID lists are skipped. Errors are of type *TWKBError.
+func ParsePostGISTWKB(b []byte) (PostGISGeometry, error) {
+	r := &twkbReader{b: b}
+	g, _, err := r.readGeometry()
+	if err != nil {
+		return nil, err
+	}
+	if r.pos != len(r.b) {
+		return nil, r.errorf(r.pos, "unexpected %d bytes after geometry", len(r.b)-r.pos)
+	}
+	return g, nil
+}
+
+// TWKBError is returned when TWKB data can't be decoded.
+type TWKBError struct {
+	Offset int    // offset in data
+	Reason string // description of the problem
+}
+
+// Error implements error interface.
+func (e *TWKBError) Error() string {
+	return fmt.Sprintf("invalid TWKB at offset %d: %s", e.Offset, e.Reason)
+}
+
+// twkbWriter writes a single TWKB geometry. Each point is written as deltas from the previous one.
+type twkbWriter struct {
+	opts     *PostGISTWKBOptions
+	layout   PostGISLayout
+	dims     int
+	factors  [4]float64
+	last     [4]int64
+	min, max [4]int64
+	body     []byte
+}
+
+// newTWKBWriter returns writer for geometry with the given layout.
+func newTWKBWriter(opts *PostGISTWKBOptions, layout PostGISLayout) *twkbWriter {
+	w := &twkbWriter{opts: opts, layout: layout, dims: 2}
+	w.factors[0] = math.Pow(10, float64(opts.Precision))
+	w.factors[1] = w.factors[0]
+	if layout.HasZ() {
+		w.factors[w.dims] = math.Pow(10, float64(opts.PrecisionZ))
+		w.dims++
+	}
+	if layout.HasM() {
+		w.factors[w.dims] = math.Pow(10, float64(opts.PrecisionM))
+		w.dims++
+	}
+	for i := range w.min {
+		w.min[i], w.max[i] = math.MaxInt64, math.MinInt64
+	}
+	return w
+}
+
+// marshal returns geometry g with header, size and bounding box.
+func (w *twkbWriter) marshal(g PostGISGeometry) ([]byte, error) {
+	precision := w.opts.Precision << 1
+	if w.opts.Precision < 0 {
+		precision = ^precision
+	}
+	header := []byte{byte(twkbType(g)) | byte(precision)<<4, 0}
+	if w.opts.WithSizes {
+		header[1] |= twkbSize
+	}
+	if w.layout != PostGISLayoutXY {
+		header[1] |= twkbExtended
+		var ext byte
+		if w.layout.HasZ() {
+			ext |= 0x01 | byte(w.opts.PrecisionZ)<<2
+		}
+		if w.layout.HasM() {
+			ext |= 0x02 | byte(w.opts.PrecisionM)<<5
+		}
+		header = append(header, ext)
+	}
+	if twkbIsEmpty(g) {
+		header[1] |= twkbEmpty
+		if w.opts.WithSizes {
+			header = append(header, 0)
+		}
+		return header, nil
+	}
+
+	if err := w.writeGeometry(g); err != nil {
+		return nil, err
+	}
+	var box []byte
+	if w.opts.WithBoxes {
+		header[1] |= twkbBox
+		for i := 0; i < w.dims; i++ {
+			box = appendVarint(box, w.min[i])
+			box = appendVarint(box, w.max[i]-w.min[i])
+		}
+	}
+	if w.opts.WithSizes {
+		header = appendUvarint(header, uint64(len(box)+len(w.body)))
+	}
+	return append(append(header, box...), w.body...), nil
+}
+
+// writeGeometry writes body of geometry g.
+func (w *twkbWriter) writeGeometry(g PostGISGeometry) error {
+	switch g := g.(type) {
+	case PostGISPoint:
+		return w.writePoints([]PostGISPoint{g}, 1, false)
+	case PostGISLineString:
+		return w.writePoints(g.Points, 2, true)
+	case PostGISPolygon:
+		return w.writeRings(g.Rings())
+	case PostGISMultiPoint:
+		var points []PostGISPoint
+		for _, p := range g.Points {
+			if !twkbIsEmpty(p) {
+				points = append(points, p)
+			}
+		}
+		w.body = appendUvarint(w.body, uint64(len(points)))
+		for _, p := range points {
+			if err := w.writePoints([]PostGISPoint{p}, 1, false); err != nil {
+				return err
+			}
+		}
+	case PostGISMultiLineString:
+		w.body = appendUvarint(w.body, uint64(len(g.LineStrings)))
+		for _, l := range g.LineStrings {
+			if err := w.writePoints(l.Points, 2, true); err != nil {
+				return err
+			}
+		}
+	case PostGISMultiPolygon:
+		w.body = appendUvarint(w.body, uint64(len(g.Polygons)))
+		for _, p := range g.Polygons {
+			if err := w.writeRings(p.Rings()); err != nil {
+				return err
+			}
+		}
+	case PostGISGeometryCollection:
+		w.body = appendUvarint(w.body, uint64(len(g.Geometries)))
+		for _, e := range g.Geometries {
+			child := newTWKBWriter(w.opts, w.layout)
+			b, err := child.marshal(e)
+			if err != nil {
+				return err
+			}
+			w.body = append(w.body, b...)
+			for i := 0; i < w.dims; i++ {
+				if child.min[i] < w.min[i] {
+					w.min[i] = child.min[i]
+				}
+				if child.max[i] > w.max[i] {
+					w.max[i] = child.max[i]
+				}
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unexpected geometry %T", g))
+	}
+	return nil
+}
+
+// writeRings writes number of rings and closed rings.
+func (w *twkbWriter) writeRings(rings [][]PostGISPoint) error {
+	w.body = appendUvarint(w.body, uint64(len(rings)))
+	for _, ring := range rings {
+		if err := w.writePoints(closeRing(ring), 4, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePoints writes points, with their number if withCount is true.
+// Points equal to previous ones after rounding are skipped while more than minPoints points are left.
+func (w *twkbWriter) writePoints(points []PostGISPoint, minPoints int, withCount bool) error {
+	var coords []byte
+	var n int
+	left := len(points)
+	for i, p := range points {
+		q, err := w.quantize(p)
+		if err != nil {
+			return err
+		}
+		if i > 0 && q == w.last && left > minPoints {
+			left--
+			continue
+		}
+
+		n++
+		for j := 0; j < w.dims; j++ {
+			coords = appendVarint(coords, q[j]-w.last[j])
+			if q[j] < w.min[j] {
+				w.min[j] = q[j]
+			}
+			if q[j] > w.max[j] {
+				w.max[j] = q[j]
+			}
+		}
+		w.last = q
+	}
+
+	if withCount {
+		w.body = appendUvarint(w.body, uint64(n))
+	}
+	w.body = append(w.body, coords...)
+	return nil
+}
+
+// quantize returns point coordinates multiplied by precision factors and rounded.
+func (w *twkbWriter) quantize(p PostGISPoint) ([4]int64, error) {
+	values := [4]float64{p.Lon, p.Lat}
+	dims := 2
+	if w.layout.HasZ() {
+		values[dims] = p.Z
+		dims++
+	}
+	if w.layout.HasM() {
+		values[dims] = p.M
+	}
+
+	var res [4]int64
+	for i := 0; i < w.dims; i++ {
+		v := math.Round(values[i] * w.factors[i])
+		if math.IsNaN(v) || math.Abs(v) >= math.MaxInt64 {
+			return res, fmt.Errorf("coordinate %v can't be encoded", values[i])
+		}
+		res[i] = int64(v)
+	}
+	return res, nil
+}
+
+// twkbType returns TWKB type of geometry, which is the same as WKB type.
+func twkbType(g PostGISGeometry) uint32 {
+	switch g.(type) {
+	case PostGISPoint:
+		return wkbPoint
+	case PostGISLineString:
+		return wkbLineString
+	case PostGISPolygon:
+		return wkbPolygon
+	case PostGISMultiPoint:
+		return wkbMultiPoint
+	case PostGISMultiLineString:
+		return wkbMultiLineString
+	case PostGISMultiPolygon:
+		return wkbMultiPolygon
+	case PostGISGeometryCollection:
+		return wkbGeometryCollection
+	default:
+		panic(fmt.Sprintf("unexpected geometry %T", g))
+	}
+}
+
+// twkbIsEmpty returns true if geometry is empty, like PostGIS lwgeom_is_empty:
+// point with NaN coordinates, or multi geometry or collection with empty elements only.
+func twkbIsEmpty(g PostGISGeometry) bool {
+	switch g := g.(type) {
+	case PostGISPoint:
+		return isEmptyPoint(g)
+	case PostGISLineString:
+		return len(g.Points) == 0
+	case PostGISPolygon:
+		return len(g.Points) == 0
+	case PostGISMultiPoint:
+		for _, p := range g.Points {
+			if !twkbIsEmpty(p) {
+				return false
+			}
+		}
+	case PostGISMultiLineString:
+		for _, l := range g.LineStrings {
+			if !twkbIsEmpty(l) {
+				return false
+			}
+		}
+	case PostGISMultiPolygon:
+		for _, p := range g.Polygons {
+			if !twkbIsEmpty(p) {
+				return false
+			}
+		}
+	case PostGISGeometryCollection:
+		for _, e := range g.Geometries {
+			if !twkbIsEmpty(e) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// appendVarint appends zigzag-encoded signed varint.
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// twkbReader reads TWKB.
+type twkbReader struct {
+	b   []byte
+	pos int
+}
+
+// twkbHeader is a decoded geometry header with decoding state.
+type twkbHeader struct {
+	Offset     int
+	Type       uint32
+	Flags      byte
+	Layout     PostGISLayout
+	Dims       int
+	Precisions [4]int
+	last       [4]int64
+}
+
+func (r *twkbReader) errorf(offset int, format string, args ...interface{}) error {
+	return &TWKBError{Offset: offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (r *twkbReader) readByte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, r.errorf(r.pos, "unexpected end of data")
+	}
+	r.pos++
+	return r.b[r.pos-1], nil
+}
+
+func (r *twkbReader) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		return 0, r.errorf(r.pos, "invalid varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *twkbReader) readVarint() (int64, error) {
+	v, n := binary.Varint(r.b[r.pos:])
+	if n <= 0 {
+		return 0, r.errorf(r.pos, "invalid varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+// readCount reads number of elements, each at least minSize bytes long.
+func (r *twkbReader) readCount(what string, minSize int) (int, error) {
+	offset := r.pos
+	n, err := r.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(r.b)-r.pos)/uint64(minSize) {
+		return 0, r.errorf(offset, "invalid number of %s %d", what, n)
+	}
+	return int(n), nil
+}
+
+// readHeader reads geometry header, size and bounding box.
+func (r *twkbReader) readHeader() (twkbHeader, error) {
+	h := twkbHeader{Offset: r.pos, Dims: 2}
+	b, err := r.readByte()
+	if err != nil {
+		return h, err
+	}
+	h.Type = uint32(b & 0x0f)
+	h.Precisions[0] = int(b>>5) ^ -int(b>>4&1)
+	h.Precisions[1] = h.Precisions[0]
+	if h.Flags, err = r.readByte(); err != nil {
+		return h, err
+	}
+
+	if h.Flags&twkbExtended != 0 {
+		if b, err = r.readByte(); err != nil {
+			return h, err
+		}
+		if b&0x01 != 0 {
+			h.Layout |= PostGISLayoutXYZ
+			h.Precisions[h.Dims] = int(b >> 2 & 0x07)
+			h.Dims++
+		}
+		if b&0x02 != 0 {
+			h.Layout |= PostGISLayoutXYM
+			h.Precisions[h.Dims] = int(b >> 5)
+			h.Dims++
+		}
+	}
+	return h, nil
+}
+
+// readGeometry reads geometry with header and returns it with the header.
+func (r *twkbReader) readGeometry() (PostGISGeometry, twkbHeader, error) {
+	h, err := r.readHeader()
+	if err != nil {
+		return nil, h, err
+	}
+	if h.Type < wkbPoint || h.Type > wkbGeometryCollection {
+		return nil, h, r.errorf(h.Offset, "unsupported geometry type %d", h.Type)
+	}
+
+	end := -1
+	if h.Flags&twkbSize != 0 {
+		offset := r.pos
+		size, err := r.readUvarint()
+		if err != nil {
+			return nil, h, err
+		}
+		if size > uint64(len(r.b)-r.pos) {
+			return nil, h, r.errorf(offset, "invalid size %d", size)
+		}
+		end = r.pos + int(size)
+	}
+
+	var g PostGISGeometry
+	if h.Flags&twkbEmpty != 0 {
+		g = emptyTWKBGeometry(h)
+	} else {
+		if h.Flags&twkbBox != 0 {
+			for i := 0; i < 2*h.Dims; i++ {
+				if _, err = r.readVarint(); err != nil {
+					return nil, h, err
+				}
+			}
+		}
+		if g, err = r.readBody(&h); err != nil {
+			return nil, h, err
+		}
+	}
+
+	if end >= 0 && r.pos != end {
+		return nil, h, r.errorf(h.Offset, "%s size does not match its data", wkbTypeName(h.Type))
+	}
+	return withTWKBLayout(g, h.Layout), h, nil
+}
+
+// emptyTWKBGeometry returns empty geometry of type from header h.
+func emptyTWKBGeometry(h twkbHeader) PostGISGeometry {
+	switch h.Type {
+	case wkbPoint:
+		p := PostGISPoint{Lon: math.NaN(), Lat: math.NaN()}
+		if h.Layout.HasZ() {
+			p.Z = math.NaN()
+		}
+		if h.Layout.HasM() {
+			p.M = math.NaN()
+		}
+		return p
+	case wkbLineString:
+		return PostGISLineString{}
+	case wkbPolygon:
+		return PostGISPolygon{}
+	case wkbMultiPoint:
+		return PostGISMultiPoint{}
+	case wkbMultiLineString:
+		return PostGISMultiLineString{}
+	case wkbMultiPolygon:
+		return PostGISMultiPolygon{}
+	default:
+		return PostGISGeometryCollection{}
+	}
+}
+
+// withTWKBLayout returns geometry with the given layout.
+func withTWKBLayout(g PostGISGeometry, layout PostGISLayout) PostGISGeometry {
+	switch g := g.(type) {
+	case PostGISPoint:
+		g.Layout = layout
+		return g
+	case PostGISLineString:
+		g.Layout = layout
+		return g
+	case PostGISPolygon:
+		g.Layout = layout
+		return g
+	case PostGISMultiPoint:
+		g.Layout = layout
+		return g
+	case PostGISMultiLineString:
+		g.Layout = layout
+		return g
+	case PostGISMultiPolygon:
+		g.Layout = layout
+		return g
+	case PostGISGeometryCollection:
+		g.Layout = layout
+		return g
+	}
+	return g
+}
+
+// readIDList skips ID list of multi geometry or collection with n elements if header h has it.
+func (r *twkbReader) readIDList(h *twkbHeader, n int) error {
+	if h.Flags&twkbIDList == 0 {
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		if _, err := r.readVarint(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBody reads geometry after header h.
+func (r *twkbReader) readBody(h *twkbHeader) (PostGISGeometry, error) {
+	switch h.Type {
+	case wkbPoint:
+		return r.readPoint(h)
+	case wkbLineString:
+		points, err := r.readPoints(h)
+		return PostGISLineString{Points: points}, err
+	case wkbPolygon:
+		return r.readPolygon(h)
+	case wkbMultiPoint:
+		var mp PostGISMultiPoint
+		n, err := r.readCount("points", h.Dims)
+		if err != nil || n == 0 {
+			return mp, err
+		}
+		if err = r.readIDList(h, n); err != nil {
+			return mp, err
+		}
+		mp.Points = make([]PostGISPoint, n)
+		for i := range mp.Points {
+			if mp.Points[i], err = r.readPoint(h); err != nil {
+				return mp, err
+			}
+		}
+		return mp, nil
+	case wkbMultiLineString:
+		var ml PostGISMultiLineString
+		n, err := r.readCount("line strings", 1)
+		if err != nil || n == 0 {
+			return ml, err
+		}
+		if err = r.readIDList(h, n); err != nil {
+			return ml, err
+		}
+		ml.LineStrings = make([]PostGISLineString, n)
+		for i := range ml.LineStrings {
+			if ml.LineStrings[i].Points, err = r.readPoints(h); err != nil {
+				return ml, err
+			}
+		}
+		return ml, nil
+	case wkbMultiPolygon:
+		var mp PostGISMultiPolygon
+		n, err := r.readCount("polygons", 1)
+		if err != nil || n == 0 {
+			return mp, err
+		}
+		if err = r.readIDList(h, n); err != nil {
+			return mp, err
+		}
+		mp.Polygons = make([]PostGISPolygon, n)
+		for i := range mp.Polygons {
+			if mp.Polygons[i], err = r.readPolygon(h); err != nil {
+				return mp, err
+			}
+		}
+		return mp, nil
+	default:
+		var gc PostGISGeometryCollection
+		n, err := r.readCount("geometries", 2)
+		if err != nil || n == 0 {
+			return gc, err
+		}
+		if err = r.readIDList(h, n); err != nil {
+			return gc, err
+		}
+		gc.Geometries = make([]PostGISGeometry, n)
+		for i := range gc.Geometries {
+			g, eh, err := r.readGeometry()
+			if err != nil {
+				return gc, err
+			}
+			if eh.Layout != h.Layout {
+				return gc, r.errorf(eh.Offset, "%s dimensions do not match %s dimensions", wkbTypeName(eh.Type), wkbTypeName(h.Type))
+			}
+			gc.Geometries[i] = withTWKBLayout(g, PostGISLayoutXY)
+		}
+		return gc, nil
+	}
+}
+
+// readPoint reads point coordinates as deltas from the previous point of geometry with header h.
+func (r *twkbReader) readPoint(h *twkbHeader) (PostGISPoint, error) {
+	var values [4]float64
+	for i := 0; i < h.Dims; i++ {
+		d, err := r.readVarint()
+		if err != nil {
+			return PostGISPoint{}, err
+		}
+		h.last[i] += d
+		if h.Precisions[i] >= 0 {
+			values[i] = float64(h.last[i]) / math.Pow(10, float64(h.Precisions[i]))
+		} else {
+			values[i] = float64(h.last[i]) * math.Pow(10, float64(-h.Precisions[i]))
+		}
+	}
+
+	p := PostGISPoint{Lon: values[0], Lat: values[1]}
+	dims := 2
+	if h.Layout.HasZ() {
+		p.Z = values[dims]
+		dims++
+	}
+	if h.Layout.HasM() {
+		p.M = values[dims]
+	}
+	return p, nil
+}
+
+// readPoints reads number of points and their coordinates.
+func (r *twkbReader) readPoints(h *twkbHeader) ([]PostGISPoint, error) {
+	n, err := r.readCount("points", h.Dims)
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	points := make([]PostGISPoint, n)
+	for i := range points {
+		if points[i], err = r.readPoint(h); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+// readPolygon reads polygon rings: the first one is exterior, others are holes.
+func (r *twkbReader) readPolygon(h *twkbHeader) (PostGISPolygon, error) {
+	var p PostGISPolygon
+	n, err := r.readCount("rings", 1)
+	if err != nil || n == 0 {
+		return p, err
+	}
+	if p.Points, err = r.readPoints(h); err != nil {
+		return p, err
+	}
+	if n > 1 {
+		p.Holes = make([][]PostGISPoint, n-1)
+		for i := range p.Holes {
+			if p.Holes[i], err = r.readPoints(h); err != nil {
+				return p, err
+			}
+		}
+	}
+	return p, nil
+}
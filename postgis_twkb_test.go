@@ -0,0 +1,180 @@
+package pq_types
+
+import (
+	"encoding/hex"
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISTWKB(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	line := func(points ...PostGISPoint) PostGISLineString { return PostGISLineString{Points: points} }
+
+	type testData struct {
+		g       PostGISGeometry
+		opts    PostGISTWKBOptions
+		twkb    string
+		decoded PostGISGeometry
+	}
+	for _, d := range []testData{
+		{line(pt(1, 1), pt(5, 5)), PostGISTWKBOptions{}, "02000202020808", nil},
+		{line(pt(1, 1), pt(5, 5)), PostGISTWKBOptions{WithSizes: true, WithBoxes: true}, "020309020802080202020808", nil},
+		{pt(1.23, -4.56), PostGISTWKBOptions{Precision: 2}, "4100f6018f07", nil},
+		{pt(123, 456), PostGISTWKBOptions{Precision: -1}, "1100185c", pt(120, 460)},
+		{
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3.14159, Layout: PostGISLayoutXYZ},
+			PostGISTWKBOptions{PrecisionZ: 2},
+			"0108090204f404",
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3.14, Layout: PostGISLayoutXYZ},
+		},
+		{
+			PostGISPoint{Lon: 1, Lat: 2, M: 3, Layout: PostGISLayoutXYM},
+			PostGISTWKBOptions{PrecisionM: 1},
+			"01082202043c",
+			nil,
+		},
+		// duplicate points after rounding are removed, but line keeps 2 points
+		{line(pt(0, 0), pt(0.1, 0.1), pt(1, 1)), PostGISTWKBOptions{}, "02000200000202", line(pt(0, 0), pt(1, 1))},
+		{line(pt(0, 0), pt(0.1, 0.1)), PostGISTWKBOptions{}, "02000200000000", line(pt(0, 0), pt(0, 0))},
+		{
+			PostGISPolygon{Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0)}, SRID: 3857},
+			PostGISTWKBOptions{},
+			"0300010500000014140000131300",
+			PostGISPolygon{Points: []PostGISPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0), pt(0, 0)}},
+		},
+		{PostGISMultiPoint{Points: []PostGISPoint{pt(1, 2), pt(3, 4)}}, PostGISTWKBOptions{}, "04000202040404", nil},
+		{
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{pt(1, 2), line(pt(3, 4), pt(5, 6))}},
+			PostGISTWKBOptions{WithBoxes: true},
+			"0701020804080201010200040002040201060408040206080404",
+			nil,
+		},
+		{PostGISLineString{}, PostGISTWKBOptions{}, "0210", nil},
+		{PostGISLineString{}, PostGISTWKBOptions{WithSizes: true}, "021200", nil},
+		{PostGISMultiPolygon{}, PostGISTWKBOptions{}, "0610", nil},
+	} {
+		comment := Commentf("%v %+v", d.g, d.opts)
+		b, err := MarshalPostGISTWKB(d.g, d.opts)
+		c.Check(err, IsNil, comment)
+		c.Check(hex.EncodeToString(b), Equals, d.twkb, comment)
+
+		decoded := d.decoded
+		if decoded == nil {
+			decoded = d.g
+		}
+		g, err := ParsePostGISTWKB(b)
+		c.Check(err, IsNil, comment)
+		c.Check(g, DeepEquals, decoded, comment)
+
+		if s.skipPostGIS {
+			continue
+		}
+
+		var dbTWKB []byte
+		var dbGeometry PostGISAnyGeometry
+		err = s.db.QueryRow("SELECT ST_AsTWKB($1::geometry, $2, $3, $4, $5, $6), ST_SetSRID(ST_GeomFromTWKB($7), 4326)",
+			d.g, d.opts.Precision, d.opts.PrecisionZ, d.opts.PrecisionM, d.opts.WithSizes, d.opts.WithBoxes, b,
+		).Scan(&dbTWKB, &dbGeometry)
+		c.Check(err, IsNil, comment)
+		c.Check(hex.EncodeToString(dbTWKB), Equals, d.twkb, comment)
+		c.Check(dbGeometry.Geometry, DeepEquals, decoded, comment)
+	}
+}
+
+func (s *TypesSuite) TestPostGISTWKBRoundTrip(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	square := []PostGISPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0), pt(0, 0)}
+	hole := []PostGISPoint{pt(2, 2), pt(3, 2), pt(3, 3), pt(2, 2)}
+
+	for _, g := range []PostGISGeometry{
+		PostGISLineString{Points: []PostGISPoint{pt(-180, -90), pt(180, 90), pt(37.61756, 55.75222)}},
+		PostGISPolygon{Points: square, Holes: [][]PostGISPoint{hole}},
+		PostGISMultiLineString{LineStrings: []PostGISLineString{{Points: square[:2]}, {Points: hole[:3]}}},
+		PostGISMultiPolygon{Polygons: []PostGISPolygon{{Points: square}, {Points: hole}}},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{
+			pt(1, 2),
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{PostGISPolygon{Points: hole}}},
+		}},
+		PostGISLineString{
+			Points: []PostGISPoint{{Lon: 1, Lat: 2, Z: 3, M: 4}, {Lon: 5, Lat: 6, Z: 7, M: 8}},
+			Layout: PostGISLayoutXYZM,
+		},
+	} {
+		for _, opts := range []PostGISTWKBOptions{{Precision: 5}, {Precision: 7, WithSizes: true, WithBoxes: true}} {
+			comment := Commentf("%v %+v", g, opts)
+			b, err := MarshalPostGISTWKB(g, opts)
+			c.Check(err, IsNil, comment)
+			decoded, err := ParsePostGISTWKB(b)
+			c.Check(err, IsNil, comment)
+			c.Check(decoded, DeepEquals, g, comment)
+		}
+	}
+
+	b, err := MarshalPostGISTWKB(PostGISPoint{Lon: math.NaN(), Lat: math.NaN()}, PostGISTWKBOptions{})
+	c.Check(err, IsNil)
+	c.Check(hex.EncodeToString(b), Equals, "0110")
+	g, err := ParsePostGISTWKB(b)
+	c.Check(err, IsNil)
+	p, ok := g.(PostGISPoint)
+	c.Check(ok, Equals, true)
+	c.Check(math.IsNaN(p.Lon) && math.IsNaN(p.Lat), Equals, true)
+
+	// empty points of multi point are skipped
+	b, err = MarshalPostGISTWKB(PostGISMultiPoint{Points: []PostGISPoint{{Lon: math.NaN(), Lat: math.NaN()}, pt(1, 2)}}, PostGISTWKBOptions{})
+	c.Check(err, IsNil)
+	g, err = ParsePostGISTWKB(b)
+	c.Check(err, IsNil)
+	c.Check(g, DeepEquals, PostGISMultiPoint{Points: []PostGISPoint{pt(1, 2)}})
+}
+
+func (s *TypesSuite) TestPostGISTWKBErrors(c *C) {
+	type testData struct {
+		g    PostGISGeometry
+		opts PostGISTWKBOptions
+		err  string
+	}
+	for _, d := range []testData{
+		{PostGISPoint{Lon: 1, Lat: 2}, PostGISTWKBOptions{Precision: 8}, "MarshalPostGISTWKB: precision 8 is out of range [-7, 7]"},
+		{PostGISPoint{Lon: 1, Lat: 2}, PostGISTWKBOptions{Precision: -8}, "MarshalPostGISTWKB: precision -8 is out of range [-7, 7]"},
+		{
+			PostGISPoint{Lon: 1, Lat: 2, Z: 3, Layout: PostGISLayoutXYZ},
+			PostGISTWKBOptions{PrecisionZ: -1},
+			"MarshalPostGISTWKB: Z precision -1 is out of range [0, 7]",
+		},
+		{
+			PostGISPoint{Lon: 1, Lat: 2, M: 3, Layout: PostGISLayoutXYM},
+			PostGISTWKBOptions{PrecisionM: 8},
+			"MarshalPostGISTWKB: M precision 8 is out of range [0, 7]",
+		},
+		{PostGISPoint{Lon: math.Inf(1), Lat: 2}, PostGISTWKBOptions{}, "MarshalPostGISTWKB: coordinate +Inf can't be encoded"},
+		{PostGISPoint{Lon: 1e300, Lat: 2}, PostGISTWKBOptions{}, "MarshalPostGISTWKB: coordinate 1e+300 can't be encoded"},
+		{PostGISPoint{Lon: 1, Lat: 2, SRID: -2}, PostGISTWKBOptions{}, "MarshalPostGISTWKB: invalid SRID -2"},
+	} {
+		_, err := MarshalPostGISTWKB(d.g, d.opts)
+		if c.Check(err, NotNil, Commentf("%v %+v", d.g, d.opts)) {
+			c.Check(err.Error(), Equals, d.err)
+		}
+	}
+
+	for data, e := range map[string]string{
+		"":                   "invalid TWKB at offset 0: unexpected end of data",
+		"02":                 "invalid TWKB at offset 1: unexpected end of data",
+		"0800":               "invalid TWKB at offset 0: unsupported geometry type 8",
+		"02000202020288":     "invalid TWKB at offset 6: invalid varint",
+		"0200ff":             "invalid TWKB at offset 2: invalid varint",
+		"020005020202":       "invalid TWKB at offset 2: invalid number of points 5",
+		"0200020202080800":   "invalid TWKB at offset 7: unexpected 1 bytes after geometry",
+		"0202040202020808":   "invalid TWKB at offset 0: LineString size does not match its data",
+		"0202100202020808":   "invalid TWKB at offset 2: invalid size 16",
+		"070001010801020406": "invalid TWKB at offset 3: Point dimensions do not match GeometryCollection dimensions",
+	} {
+		b, err := hex.DecodeString(data)
+		c.Assert(err, IsNil)
+		_, err = ParsePostGISTWKB(b)
+		c.Check(err, FitsTypeOf, &TWKBError{}, Commentf("%s", data))
+		if err != nil {
+			c.Check(err.Error(), Equals, e, Commentf("%s", data))
+		}
+	}
+}
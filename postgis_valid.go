@@ -0,0 +1,355 @@
+package pq_types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// PostGISValidationError describes why geometry is not valid, like PostGIS ST_IsValidDetail.
+type PostGISValidationError struct {
+	Reason   string       // reason like "Self-intersection" or "Hole lies outside shell"
+	Location PostGISPoint // location of the problem with Lon and Lat only
+}
+
+// Error returns reason with location like PostGIS ST_IsValidReason, for example "Self-intersection[5 5]".
+func (e *PostGISValidationError) Error() string {
+	return fmt.Sprintf("%s[%s %s]", e.Reason,
+		strconv.FormatFloat(e.Location.Lon, 'g', 15, 64), strconv.FormatFloat(e.Location.Lat, 'g', 15, 64))
+}
+
+// invalid returns *PostGISValidationError with the given reason and location.
+func invalid(reason string, p planePoint) error {
+	return &PostGISValidationError{Reason: reason, Location: PostGISPoint{Lon: p.x, Lat: p.y}}
+}
+
+// Validate returns nil if polygon is valid according to OGC rules, like PostGIS ST_IsValid,
+// or *PostGISValidationError for the first found problem with reasons used by PostGIS ST_IsValidReason:
+// "Invalid Coordinate", "Ring is not closed", "Too few points in geometry component", "Self-intersection",
+// "Ring Self-intersection", "Hole lies outside shell", "Nested holes" and "Interior is disconnected".
+// Empty polygon is valid. Coordinates are handled in plane; Z and M are ignored.
+func (p PostGISPolygon) Validate() error {
+	return validatePolygons([]PostGISPolygon{p})
+}
+
+// IsValid returns true if polygon is valid, like PostGIS ST_IsValid. See Validate for details.
+func (p PostGISPolygon) IsValid() bool {
+	return p.Validate() == nil
+}
+
+// Validate returns nil if multi polygon is valid according to OGC rules, like PostGIS ST_IsValid,
+// or *PostGISValidationError for the first found problem. In addition to problems of single polygons
+// described for PostGISPolygon.Validate, polygons must not overlap ("Self-intersection")
+// and must not be inside each other ("Nested shells"); they may touch at points.
+func (mp PostGISMultiPolygon) Validate() error {
+	return validatePolygons(mp.Polygons)
+}
+
+// IsValid returns true if multi polygon is valid, like PostGIS ST_IsValid. See Validate for details.
+func (mp PostGISMultiPolygon) IsValid() bool {
+	return mp.Validate() == nil
+}
+
+// Validate returns nil if polygon is valid. See PostGISPolygon.Validate for details.
+func (p PostGISGeographyPolygon) Validate() error {
+	return PostGISPolygon(p).Validate()
+}
+
+// IsValid returns true if polygon is valid. See PostGISPolygon.Validate for details.
+func (p PostGISGeographyPolygon) IsValid() bool {
+	return PostGISPolygon(p).IsValid()
+}
+
+// ForceRHR returns polygon with rings oriented by right-hand rule, like PostGIS ST_ForceRHR:
+// exterior ring is clockwise and holes are counterclockwise, so the interior is on the right of each ring.
+// Rings with zero area are kept as is.
+func (p PostGISPolygon) ForceRHR() PostGISPolygon {
+	res := PostGISPolygon{SRID: p.SRID, Layout: p.Layout}
+	for i, ring := range p.Rings() {
+		area := planarRingArea(ring)
+		if (i == 0 && area > 0) || (i != 0 && area < 0) {
+			ring = reverseRing(ring)
+		}
+		if i == 0 {
+			res.Points = ring
+		} else {
+			res.Holes = append(res.Holes, ring)
+		}
+	}
+	return res
+}
+
+// ForceRHR returns multi polygon with rings of all polygons oriented by right-hand rule, like PostGIS ST_ForceRHR.
+// See PostGISPolygon.ForceRHR for details.
+func (mp PostGISMultiPolygon) ForceRHR() PostGISMultiPolygon {
+	res := PostGISMultiPolygon{SRID: mp.SRID, Layout: mp.Layout}
+	if mp.Polygons != nil {
+		res.Polygons = make([]PostGISPolygon, len(mp.Polygons))
+		for i, p := range mp.Polygons {
+			res.Polygons[i] = p.ForceRHR()
+		}
+	}
+	return res
+}
+
+// reverseRing returns a reversed copy of ring.
+func reverseRing(ring []PostGISPoint) []PostGISPoint {
+	res := make([]PostGISPoint, len(ring))
+	for i, p := range ring {
+		res[len(ring)-1-i] = p
+	}
+	return res
+}
+
+// closeRing returns ring with its first point appended if it is not closed.
+func closeRing(ring []PostGISPoint) []PostGISPoint {
+	if len(ring) == 0 {
+		return ring
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first.Lon == last.Lon && first.Lat == last.Lat && first.Z == last.Z && first.M == last.M {
+		return ring
+	}
+	return append(ring[:len(ring):len(ring)], first)
+}
+
+// validRing is a polygon ring prepared for validation.
+type validRing struct {
+	points  []planePoint // without consecutive duplicates
+	polygon int          // index of polygon
+	shell   bool         // exterior ring
+}
+
+// validTouch is a point where two rings of the same polygon touch each other.
+type validTouch struct {
+	rings [2]int
+	p     planePoint
+}
+
+// validatePolygons checks polygons of (multi) polygon in the same order as GEOS does.
+func validatePolygons(polygons []PostGISPolygon) error {
+	var rings []validRing
+	for i, p := range polygons {
+		for j, ring := range p.Rings() {
+			for _, pt := range ring {
+				if math.IsNaN(pt.Lon) || math.IsInf(pt.Lon, 0) || math.IsNaN(pt.Lat) || math.IsInf(pt.Lat, 0) {
+					return invalid("Invalid Coordinate", planePoint{pt.Lon, pt.Lat})
+				}
+			}
+			rings = append(rings, validRing{points: planePoints(ring), polygon: i, shell: j == 0})
+		}
+	}
+
+	for _, r := range rings {
+		if len(r.points) > 0 && r.points[0] != r.points[len(r.points)-1] {
+			return invalid("Ring is not closed", r.points[0])
+		}
+	}
+	for _, r := range rings {
+		if len(r.points) < 4 {
+			var p planePoint
+			if len(r.points) > 0 {
+				p = r.points[0]
+			}
+			return invalid("Too few points in geometry component", p)
+		}
+	}
+
+	touches, err := validateIntersections(rings)
+	if err != nil {
+		return err
+	}
+
+	for i, shell := range rings {
+		if !shell.shell {
+			continue
+		}
+		var holes []validRing
+		for _, r := range rings[i+1:] {
+			if r.shell {
+				break
+			}
+			holes = append(holes, r)
+		}
+
+		for _, hole := range holes {
+			if p, ok := pointNotOnRing(hole.points, shell.points); ok && locateInRing(p, shell.points) == locExterior {
+				return invalid("Hole lies outside shell", p)
+			}
+		}
+		for j, outer := range holes {
+			for k, inner := range holes {
+				if j == k {
+					continue
+				}
+				if p, ok := pointNotOnRing(inner.points, outer.points); ok && locateInRing(p, outer.points) == locInterior {
+					return invalid("Nested holes", p)
+				}
+			}
+		}
+	}
+
+	for i, outer := range rings {
+		if !outer.shell {
+			continue
+		}
+		for _, inner := range rings {
+			if !inner.shell || inner.polygon == outer.polygon {
+				continue
+			}
+			p, ok := pointNotOnRing(inner.points, outer.points)
+			if !ok || locateInRing(p, outer.points) != locInterior {
+				continue
+			}
+			inHole := false
+			for _, hole := range rings[i+1:] {
+				if hole.shell {
+					break
+				}
+				if locateInRing(p, hole.points) != locExterior {
+					inHole = true
+					break
+				}
+			}
+			if !inHole {
+				return invalid("Nested shells", p)
+			}
+		}
+	}
+
+	// rings touching each other form a cycle separating a part of interior
+	parents := make([]int, len(rings))
+	for i := range parents {
+		parents[i] = i
+	}
+	root := func(i int) int {
+		for parents[i] != i {
+			i = parents[i]
+		}
+		return i
+	}
+	for _, t := range touches {
+		a, b := root(t.rings[0]), root(t.rings[1])
+		if a == b {
+			return invalid("Interior is disconnected", t.p)
+		}
+		parents[a] = b
+	}
+
+	return nil
+}
+
+// validateIntersections checks intersections of ring segments and returns touches of different rings of the same polygon.
+func validateIntersections(rings []validRing) ([]validTouch, error) {
+	var points []PostGISPoint
+	var segments []*topoSegment
+	for r, ring := range rings {
+		for i := 1; i < len(ring.points); i++ {
+			segments = append(segments, &topoSegment{a: ring.points[i-1], b: ring.points[i], line: r, i: i - 1})
+			points = append(points, PostGISPoint{Lon: ring.points[i].x, Lat: ring.points[i].y})
+		}
+	}
+	grid := newSegmentGrid(pointsEnvelope(points, 0), len(points))
+	for _, s := range segments {
+		grid.add(s)
+	}
+
+	// self-intersections are reported before self-touches of rings
+	var touches []validTouch
+	var ringErr error
+	seen := make(map[validTouch]bool)
+	for _, s := range segments {
+		var err error
+		grid.query(s.a, s.b, func(t *topoSegment) bool {
+			if t.line < s.line || (t.line == s.line && t.i <= s.i) {
+				return false
+			}
+
+			p, _, n := segmentIntersection(s.a, s.b, t.a, t.b)
+			sameRing := s.line == t.line
+			adjacent := sameRing && (t.i == s.i+1 || (s.i == 0 && t.i == len(rings[s.line].points)-2))
+			switch {
+			case n == 0:
+				return false
+			case n == 2:
+				err = invalid("Self-intersection", p)
+				return true
+			case adjacent:
+				return false
+			case p != s.a && p != s.b && p != t.a && p != t.b:
+				err = invalid("Self-intersection", p)
+				return true
+			case sameRing:
+				if ringErr == nil {
+					ringErr = invalid("Ring Self-intersection", p)
+				}
+			case ringsCross(p, rings[s.line].points, rings[t.line].points):
+				err = invalid("Self-intersection", p)
+				return true
+			case rings[s.line].polygon == rings[t.line].polygon:
+				touch := validTouch{rings: [2]int{s.line, t.line}, p: p}
+				if !seen[touch] {
+					seen[touch] = true
+					touches = append(touches, touch)
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return touches, ringErr
+}
+
+// ringsCross returns true if rings a and b touching at point p cross each other there.
+func ringsCross(p planePoint, a, b []planePoint) bool {
+	raysA, raysB := ringRays(p, a), ringRays(p, b)
+	if len(raysA) != 2 || len(raysB) != 2 {
+		return false
+	}
+
+	// angle of ray counterclockwise from the first ray of a
+	angle := func(q planePoint) float64 {
+		a := math.Atan2(q.y-p.y, q.x-p.x) - math.Atan2(raysA[0].y-p.y, raysA[0].x-p.x)
+		if a < 0 {
+			a += 2 * math.Pi
+		}
+		return a
+	}
+	limit := angle(raysA[1])
+	return (angle(raysB[0]) < limit) != (angle(raysB[1]) < limit)
+}
+
+// ringRays returns ends of ring segments going out of point p lying on ring.
+func ringRays(p planePoint, ring []planePoint) []planePoint {
+	var res []planePoint
+	for i := 1; i < len(ring); i++ {
+		a, b := ring[i-1], ring[i]
+		switch {
+		case p == a:
+			res = append(res, b)
+		case p == b:
+			res = append(res, a)
+		case onSegment(p, a, b):
+			res = append(res, a, b)
+		}
+	}
+	return res
+}
+
+// pointNotOnRing returns a vertex of ring, or a middle of its segment, not lying on other ring.
+func pointNotOnRing(ring, other []planePoint) (planePoint, bool) {
+	for _, p := range ring {
+		if locateInRing(p, other) != locBoundary {
+			return p, true
+		}
+	}
+	for i := 1; i < len(ring); i++ {
+		p := planePoint{(ring[i-1].x + ring[i].x) / 2, (ring[i-1].y + ring[i].y) / 2}
+		if locateInRing(p, other) != locBoundary {
+			return p, true
+		}
+	}
+	return planePoint{}, false
+}
@@ -0,0 +1,177 @@
+package pq_types
+
+import (
+	"math"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestPostGISValidate(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	ring := func(points ...PostGISPoint) []PostGISPoint { return points }
+	env := func(minLon, minLat, maxLon, maxLat float64) []PostGISPoint {
+		return MakeEnvelope(pt(minLon, minLat), pt(maxLon, maxLat)).Points
+	}
+	polygon := func(rings ...[]PostGISPoint) PostGISPolygon {
+		return PostGISPolygon{Points: rings[0], Holes: rings[1:]}
+	}
+
+	type testData struct {
+		g      PostGISGeometry
+		reason string
+		planar bool // the same reason in PostGIS, location may differ
+	}
+	for i, d := range []testData{
+		{PostGISPolygon{}, "", true},
+		{polygon(env(0, 0, 10, 10)), "", true},
+		{polygon(env(0, 0, 10, 10), env(2, 2, 4, 4), env(6, 6, 8, 8)), "", true},
+		{polygon(ring(pt(0, 0), pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0), pt(0, 0))), "", true},
+
+		// hole touching shell and other hole at single points
+		{polygon(env(0, 0, 10, 10), ring(pt(0, 5), pt(5, 8), pt(5, 2), pt(0, 5))), "", true},
+		{polygon(env(0, 0, 10, 10), env(2, 2, 4, 4), env(4, 4, 6, 6)), "", true},
+
+		{polygon(ring(pt(0, 0), pt(0, math.NaN()), pt(10, 10), pt(0, 0))), "Invalid Coordinate[0 NaN]", false},
+		{polygon(ring(pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0))), "Ring is not closed[0 0]", false},
+		{polygon(ring(pt(0, 0), pt(0, 10), pt(0, 0))), "Too few points in geometry component[0 0]", false},
+		{polygon(ring(pt(0, 0), pt(0, 10), pt(0, 10), pt(0, 0))), "Too few points in geometry component[0 0]", false},
+		{polygon(ring(pt(0, 0), pt(10, 10), pt(10, 0), pt(0, 10), pt(0, 0))), "Self-intersection[5 5]", true},
+		{polygon(ring(pt(0, 0), pt(0, 10), pt(5, 10), pt(5, 15), pt(5, 5), pt(10, 0), pt(0, 0))), "Self-intersection[5 10]", false},
+		{
+			polygon(ring(pt(0, 0), pt(0, 10), pt(10, 10), pt(5, 5), pt(10, 0), pt(5, 5), pt(0, 0))),
+			"Self-intersection[5 5]", false,
+		},
+		{polygon(ring(pt(0, 0), pt(0, 10), pt(5, 0), pt(10, 10), pt(10, 0), pt(0, 0))), "Ring Self-intersection[5 0]", true},
+		{polygon(env(0, 0, 10, 10), env(5, 5, 15, 15)), "Self-intersection[5 10]", true},
+		{polygon(env(0, 0, 10, 10), ring(pt(0, 5), pt(5, 8), pt(-5, 5), pt(5, 2), pt(0, 5))), "Self-intersection[0 3.5]", true},
+		{polygon(env(0, 0, 10, 10), env(0, 2, 4, 4)), "Self-intersection[0 2]", true},
+		{polygon(env(0, 0, 10, 10), env(20, 20, 30, 30)), "Hole lies outside shell[20 20]", true},
+		{polygon(env(0, 0, 10, 10), env(2, 2, 8, 8), env(4, 4, 6, 6)), "Nested holes[4 4]", true},
+		{polygon(env(0, 0, 10, 10), ring(pt(0, 5), pt(5, 10), pt(5, 0), pt(0, 5))), "Interior is disconnected[5 10]", true},
+		{
+			polygon(env(0, 0, 10, 10), ring(pt(0, 5), pt(4, 8), pt(4, 2), pt(0, 5)), ring(pt(4, 2), pt(4, 8), pt(10, 5), pt(4, 2))),
+			"Self-intersection[4 8]", true,
+		},
+		{
+			polygon(env(0, 0, 10, 10), ring(pt(0, 5), pt(4, 8), pt(4, 2), pt(0, 5)), ring(pt(4, 2), pt(6, 5), pt(4, 8), pt(10, 5), pt(4, 2))),
+			"Interior is disconnected[4 8]", true,
+		},
+
+		// multi polygons
+		{PostGISMultiPolygon{}, "", true},
+		{PostGISMultiPolygon{Polygons: []PostGISPolygon{polygon(env(0, 0, 10, 10)), polygon(env(10, 10, 20, 20))}}, "", true},
+		{
+			PostGISMultiPolygon{Polygons: []PostGISPolygon{polygon(env(0, 0, 10, 10), env(2, 2, 8, 8)), polygon(env(4, 4, 6, 6))}},
+			"", true,
+		},
+		{
+			PostGISMultiPolygon{Polygons: []PostGISPolygon{polygon(env(0, 0, 10, 10)), polygon(env(10, 0, 20, 10))}},
+			"Self-intersection[10 10]", true,
+		},
+		{
+			PostGISMultiPolygon{Polygons: []PostGISPolygon{polygon(env(0, 0, 10, 10)), polygon(env(5, 5, 15, 15))}},
+			"Self-intersection[5 10]", true,
+		},
+		{
+			PostGISMultiPolygon{Polygons: []PostGISPolygon{polygon(env(0, 0, 10, 10)), polygon(env(4, 4, 6, 6))}},
+			"Nested shells[4 4]", true,
+		},
+		{
+			PostGISMultiPolygon{Polygons: []PostGISPolygon{polygon(env(0, 0, 10, 10)), polygon(ring(pt(0, 0), pt(5, 20), pt(10, 0), pt(0, 0)))}},
+			"Self-intersection[2.5 10]", true,
+		},
+	} {
+		comment := Commentf("%d: %v", i, d.g)
+		var err error
+		switch g := d.g.(type) {
+		case PostGISPolygon:
+			err = g.Validate()
+			c.Check(g.IsValid(), Equals, d.reason == "", comment)
+		case PostGISMultiPolygon:
+			err = g.Validate()
+			c.Check(g.IsValid(), Equals, d.reason == "", comment)
+		}
+		if d.reason == "" {
+			c.Check(err, IsNil, comment)
+		} else {
+			c.Assert(err, FitsTypeOf, &PostGISValidationError{}, comment)
+			c.Check(err.Error(), Equals, d.reason, comment)
+		}
+
+		if s.skipPostGIS || !d.planar {
+			continue
+		}
+
+		var reason string
+		err = s.db.QueryRow("SELECT ST_IsValidReason($1::geometry)", PostGISEWKB{d.g}).Scan(&reason)
+		c.Check(err, IsNil, comment)
+		if d.reason == "" {
+			d.reason = "Valid Geometry"
+		}
+		c.Check(strings.SplitN(reason, "[", 2)[0], Equals, strings.SplitN(d.reason, "[", 2)[0], comment)
+	}
+}
+
+func (s *TypesSuite) TestPostGISForceRHR(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	cw := []PostGISPoint{pt(0, 0), pt(0, 10), pt(10, 10), pt(10, 0), pt(0, 0)}
+	ccw := []PostGISPoint{pt(0, 0), pt(10, 0), pt(10, 10), pt(0, 10), pt(0, 0)}
+	holeCW := []PostGISPoint{pt(2, 2), pt(2, 4), pt(4, 4), pt(4, 2), pt(2, 2)}
+	holeCCW := []PostGISPoint{pt(2, 2), pt(4, 2), pt(4, 4), pt(2, 4), pt(2, 2)}
+	rhr := PostGISPolygon{Points: cw, Holes: [][]PostGISPoint{holeCCW}, SRID: 3857}
+
+	c.Check(rhr.ForceRHR(), DeepEquals, rhr)
+	p := PostGISPolygon{Points: ccw, Holes: [][]PostGISPoint{holeCW}, SRID: 3857}
+	c.Check(p.ForceRHR(), DeepEquals, rhr)
+	c.Check(p.Points, DeepEquals, ccw) // not modified
+	c.Check(PostGISPolygon{Layout: PostGISLayoutXYZ}.ForceRHR(), DeepEquals, PostGISPolygon{Layout: PostGISLayoutXYZ})
+
+	mp := PostGISMultiPolygon{Polygons: []PostGISPolygon{p, {Points: cw}}}
+	c.Check(mp.ForceRHR(), DeepEquals, PostGISMultiPolygon{Polygons: []PostGISPolygon{rhr, {Points: cw}}})
+
+	if s.skipPostGIS {
+		return
+	}
+
+	var p1 PostGISPolygon
+	err := s.db.QueryRow("SELECT ST_ForceRHR($1::geometry)", PostGISPolygon{Points: ccw, Holes: [][]PostGISPoint{holeCW}}).Scan(&p1)
+	c.Check(err, IsNil)
+	c.Check(p1, DeepEquals, PostGISPolygon{Points: cw, Holes: [][]PostGISPoint{holeCCW}})
+}
+
+func (s *TypesSuite) TestPostGISPolygonClosing(c *C) {
+	pt := func(lon, lat float64) PostGISPoint { return PostGISPoint{Lon: lon, Lat: lat} }
+	open := []PostGISPoint{pt(0, 0), pt(0, 1), pt(1, 1), pt(1, 0)}
+	p := PostGISPolygon{Points: open, Holes: [][]PostGISPoint{{pt(0.25, 0.25), pt(0.5, 0.5), pt(0.5, 0.25)}}}
+	closed := PostGISPolygon{
+		Points: []PostGISPoint{pt(0, 0), pt(0, 1), pt(1, 1), pt(1, 0), pt(0, 0)},
+		Holes:  [][]PostGISPoint{{pt(0.25, 0.25), pt(0.5, 0.5), pt(0.5, 0.25), pt(0.25, 0.25)}},
+	}
+	c.Check(p.Validate(), ErrorMatches, `Ring is not closed\[0 0\]`)
+
+	v, err := p.Value()
+	c.Check(err, IsNil)
+	c.Check(string(v.([]byte)), Equals, "SRID=4326;POLYGON((0.00000000 0.00000000,0.00000000 1.00000000,1.00000000 1.00000000,"+
+		"1.00000000 0.00000000,0.00000000 0.00000000),(0.25000000 0.25000000,0.50000000 0.50000000,0.50000000 0.25000000,0.25000000 0.25000000))")
+	c.Check(p.Points, DeepEquals, open) // not modified
+
+	b, err := PostGISEWKB{p}.MarshalBinary()
+	c.Check(err, IsNil)
+	var g PostGISEWKB
+	c.Check(g.UnmarshalBinary(b), IsNil)
+	c.Check(g.Geometry, DeepEquals, closed)
+
+	v, err = PostGISMultiPolygon{Polygons: []PostGISPolygon{p}}.Value()
+	c.Check(err, IsNil)
+	c.Check(string(v.([]byte)), Matches, `SRID=4326;MULTIPOLYGON\(\(\(0\.00000000 0\.00000000,.*,0\.00000000 0\.00000000\),.*,0\.25000000 0\.25000000\)\)\)`)
+
+	if s.skipPostGIS {
+		return
+	}
+
+	var p1 PostGISPolygon
+	err = s.db.QueryRow("SELECT $1::geometry", p).Scan(&p1)
+	c.Check(err, IsNil)
+	c.Check(p1, DeepEquals, closed)
+}
@@ -0,0 +1,616 @@
+package pq_types
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// WKB geometry types.
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// EWKB type flags.
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// WKBError is returned when WKB or EWKB data can't be decoded.
+type WKBError struct {
+	Offset int    // offset in binary (not hex-encoded) data
+	Reason string // description of the problem
+}
+
+// Error implements error interface.
+func (e *WKBError) Error() string {
+	return fmt.Sprintf("invalid WKB at offset %d: %s", e.Offset, e.Reason)
+}
+
+// wkbHeader is a decoded geometry header.
+type wkbHeader struct {
+	Offset  int    // offset of header
+	Type    uint32 // base geometry type, without dimension and SRID flags
+	HasZ    bool
+	HasM    bool
+	HasSRID bool
+	SRID    uint32
+}
+
+// wkbReader reads WKB and EWKB in both byte orders.
+// Byte order is set by each geometry header.
+type wkbReader struct {
+	b     []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+// newWKBReader returns reader for Scan value: hex-encoded (text format) or raw (binary format) []byte or string.
+func newWKBReader(value interface{}) (*wkbReader, error) {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return nil, fmt.Errorf("expected []byte or string, got %T (%v)", value, value)
+	}
+
+	// raw WKB starts with byte order 0 or 1, hex-encoded - with '0'
+	if len(b) > 0 && b[0] == '0' {
+		raw := make([]byte, hex.DecodedLen(len(b)))
+		if _, err := hex.Decode(raw, b); err != nil {
+			return nil, fmt.Errorf("invalid hex-encoded WKB: %s", err)
+		}
+		b = raw
+	}
+
+	return &wkbReader{b: b}, nil
+}
+
+func (r *wkbReader) errorf(offset int, format string, args ...interface{}) error {
+	return &WKBError{Offset: offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (r *wkbReader) need(n int) error {
+	if len(r.b)-r.pos < n {
+		return r.errorf(r.pos, "unexpected end of data, need %d bytes, have %d", n, len(r.b)-r.pos)
+	}
+	return nil
+}
+
+func (r *wkbReader) readUint32() (uint32, error) {
+	if err := r.need(4); err != nil {
+		return 0, err
+	}
+	v := r.order.Uint32(r.b[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *wkbReader) readFloat64() (float64, error) {
+	if err := r.need(8); err != nil {
+		return 0, err
+	}
+	v := math.Float64frombits(r.order.Uint64(r.b[r.pos:]))
+	r.pos += 8
+	return v, nil
+}
+
+// readHeader reads byte order, geometry type with EWKB or ISO dimension flags, and optional SRID.
+func (r *wkbReader) readHeader() (wkbHeader, error) {
+	h := wkbHeader{Offset: r.pos}
+	if err := r.need(1); err != nil {
+		return h, err
+	}
+	switch r.b[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return h, r.errorf(r.pos, "invalid byte order %#02x", r.b[r.pos])
+	}
+	r.pos++
+
+	t, err := r.readUint32()
+	if err != nil {
+		return h, err
+	}
+
+	// EWKB flags
+	h.HasZ = t&ewkbZ != 0
+	h.HasM = t&ewkbM != 0
+	h.HasSRID = t&ewkbSRID != 0
+	t &^= ewkbZ | ewkbM | ewkbSRID
+
+	// ISO dimensions
+	switch t / 1000 {
+	case 0:
+	case 1:
+		h.HasZ = true
+	case 2:
+		h.HasM = true
+	case 3:
+		h.HasZ, h.HasM = true, true
+	default:
+		return h, r.errorf(h.Offset+1, "invalid geometry type %d", t)
+	}
+	h.Type = t % 1000
+	if h.Type < wkbPoint || h.Type > wkbGeometryCollection {
+		return h, r.errorf(h.Offset+1, "unsupported geometry type %d", h.Type)
+	}
+
+	if h.HasSRID {
+		if h.SRID, err = r.readUint32(); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+// srid returns SRID for geometry types: PostGISDefaultSRID and absent SRID are returned as zero,
+// and SRID 0 as PostGISUnknownSRID. Absent SRID of top-level geometry is replaced by readTopGeometry.
+func (h wkbHeader) srid() int {
+	switch {
+	case !h.HasSRID || h.SRID == PostGISDefaultSRID:
+		return 0
+	case h.SRID == 0:
+		return PostGISUnknownSRID
+	}
+	return int(h.SRID)
+}
+
+// layout returns geometry layout.
+func (h wkbHeader) layout() PostGISLayout {
+	var l PostGISLayout
+	if h.HasZ {
+		l |= PostGISLayoutXYZ
+	}
+	if h.HasM {
+		l |= PostGISLayoutXYM
+	}
+	return l
+}
+
+// readExpectedHeader reads header and checks that it has the given type (any type if t is zero).
+func (r *wkbReader) readExpectedHeader(t uint32) (wkbHeader, error) {
+	h, err := r.readHeader()
+	if err != nil {
+		return h, err
+	}
+	if t != 0 && h.Type != t {
+		return h, r.errorf(h.Offset, "expected %s, got %s", wkbTypeName(t), wkbTypeName(h.Type))
+	}
+	return h, nil
+}
+
+// readPoint reads point coordinates, including Z and M if header h has them.
+func (r *wkbReader) readPoint(h wkbHeader) (PostGISPoint, error) {
+	var p PostGISPoint
+	var err error
+	if p.Lon, err = r.readFloat64(); err != nil {
+		return p, err
+	}
+	if p.Lat, err = r.readFloat64(); err != nil {
+		return p, err
+	}
+	if h.HasZ {
+		if p.Z, err = r.readFloat64(); err != nil {
+			return p, err
+		}
+	}
+	if h.HasM {
+		if p.M, err = r.readFloat64(); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// readCount reads number of elements, each at least minSize bytes long.
+func (r *wkbReader) readCount(what string, minSize int) (int, error) {
+	offset := r.pos
+	n, err := r.readUint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n)*uint64(minSize) > uint64(len(r.b)-r.pos) {
+		return 0, r.errorf(offset, "invalid number of %s %d", what, n)
+	}
+	return int(n), nil
+}
+
+// readElementHeader reads header of element of multi geometry or collection with header h
+// and checks that it has the given type (any type if t is zero).
+func (r *wkbReader) readElementHeader(h wkbHeader, t uint32) (wkbHeader, error) {
+	eh, err := r.readExpectedHeader(t)
+	if err != nil {
+		return eh, err
+	}
+	if eh.HasZ != h.HasZ || eh.HasM != h.HasM {
+		return eh, r.errorf(eh.Offset, "%s dimensions do not match %s dimensions", wkbTypeName(eh.Type), wkbTypeName(h.Type))
+	}
+	return eh, nil
+}
+
+// readPoints reads number of points and their coordinates.
+func (r *wkbReader) readPoints(h wkbHeader) ([]PostGISPoint, error) {
+	n, err := r.readCount("points", 16)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]PostGISPoint, n)
+	for i := range points {
+		if points[i], err = r.readPoint(h); err != nil {
+			return nil, err
+		}
+	}
+	return points, nil
+}
+
+// readLineString reads line string points.
+func (r *wkbReader) readLineString(h wkbHeader) (PostGISLineString, error) {
+	var l PostGISLineString
+	points, err := r.readPoints(h)
+	if err != nil {
+		return l, err
+	}
+	if len(points) > 0 {
+		l.Points = points
+	}
+	return l, nil
+}
+
+// readPolygon reads polygon rings: the first one is exterior, others are holes.
+func (r *wkbReader) readPolygon(h wkbHeader) (PostGISPolygon, error) {
+	var p PostGISPolygon
+	n, err := r.readCount("rings", 4)
+	if err != nil || n == 0 {
+		return p, err
+	}
+
+	if p.Points, err = r.readPoints(h); err != nil {
+		return p, err
+	}
+	if n > 1 {
+		p.Holes = make([][]PostGISPoint, n-1)
+		for i := range p.Holes {
+			if p.Holes[i], err = r.readPoints(h); err != nil {
+				return p, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// readMultiPoint reads multi point elements.
+func (r *wkbReader) readMultiPoint(h wkbHeader) (PostGISMultiPoint, error) {
+	var mp PostGISMultiPoint
+	n, err := r.readCount("points", 21)
+	if err != nil || n == 0 {
+		return mp, err
+	}
+
+	mp.Points = make([]PostGISPoint, n)
+	for i := range mp.Points {
+		eh, err := r.readElementHeader(h, wkbPoint)
+		if err != nil {
+			return mp, err
+		}
+		if mp.Points[i], err = r.readPoint(eh); err != nil {
+			return mp, err
+		}
+	}
+	return mp, nil
+}
+
+// readMultiLineString reads multi line string elements.
+func (r *wkbReader) readMultiLineString(h wkbHeader) (PostGISMultiLineString, error) {
+	var ml PostGISMultiLineString
+	n, err := r.readCount("line strings", 9)
+	if err != nil || n == 0 {
+		return ml, err
+	}
+
+	ml.LineStrings = make([]PostGISLineString, n)
+	for i := range ml.LineStrings {
+		eh, err := r.readElementHeader(h, wkbLineString)
+		if err != nil {
+			return ml, err
+		}
+		if ml.LineStrings[i], err = r.readLineString(eh); err != nil {
+			return ml, err
+		}
+	}
+	return ml, nil
+}
+
+// readMultiPolygon reads multi polygon elements.
+func (r *wkbReader) readMultiPolygon(h wkbHeader) (PostGISMultiPolygon, error) {
+	var mp PostGISMultiPolygon
+	n, err := r.readCount("polygons", 9)
+	if err != nil || n == 0 {
+		return mp, err
+	}
+
+	mp.Polygons = make([]PostGISPolygon, n)
+	for i := range mp.Polygons {
+		eh, err := r.readElementHeader(h, wkbPolygon)
+		if err != nil {
+			return mp, err
+		}
+		if mp.Polygons[i], err = r.readPolygon(eh); err != nil {
+			return mp, err
+		}
+	}
+	return mp, nil
+}
+
+// readGeometryCollection reads geometry collection elements.
+func (r *wkbReader) readGeometryCollection(h wkbHeader) (PostGISGeometryCollection, error) {
+	var gc PostGISGeometryCollection
+	n, err := r.readCount("geometries", 9)
+	if err != nil || n == 0 {
+		return gc, err
+	}
+
+	gc.Geometries = make([]PostGISGeometry, n)
+	for i := range gc.Geometries {
+		eh, err := r.readElementHeader(h, 0)
+		if err != nil {
+			return gc, err
+		}
+		if gc.Geometries[i], err = r.readGeometry(eh, PostGISLayoutXY); err != nil {
+			return gc, err
+		}
+	}
+	return gc, nil
+}
+
+// readGeometry reads geometry of any type after header h, sets its SRID from header and layout to the given one.
+// Layout of nested geometries is left zero.
+func (r *wkbReader) readGeometry(h wkbHeader, layout PostGISLayout) (PostGISGeometry, error) {
+	switch h.Type {
+	case wkbPoint:
+		g, err := r.readPoint(h)
+		g.SRID, g.Layout = h.srid(), layout
+		return g, err
+	case wkbLineString:
+		g, err := r.readLineString(h)
+		g.SRID, g.Layout = h.srid(), layout
+		return g, err
+	case wkbPolygon:
+		g, err := r.readPolygon(h)
+		g.SRID, g.Layout = h.srid(), layout
+		return g, err
+	case wkbMultiPoint:
+		g, err := r.readMultiPoint(h)
+		g.SRID, g.Layout = h.srid(), layout
+		return g, err
+	case wkbMultiLineString:
+		g, err := r.readMultiLineString(h)
+		g.SRID, g.Layout = h.srid(), layout
+		return g, err
+	case wkbMultiPolygon:
+		g, err := r.readMultiPolygon(h)
+		g.SRID, g.Layout = h.srid(), layout
+		return g, err
+	case wkbGeometryCollection:
+		g, err := r.readGeometryCollection(h)
+		g.SRID, g.Layout = h.srid(), layout
+		return g, err
+	default:
+		return nil, r.errorf(h.Offset, "unsupported geometry type %d", h.Type)
+	}
+}
+
+// scanGeometry decodes Scan value with geometry of type t, or of any type if t is zero.
+// Value can be WKB, EWKB, WKT or EWKT.
+func scanGeometry(value interface{}, t uint32) (PostGISGeometry, error) {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) > 0 && isWKTLetter(v[0]) {
+			return parseWKT(string(v), t)
+		}
+	case string:
+		if len(v) > 0 && isWKTLetter(v[0]) {
+			return parseWKT(v, t)
+		}
+	}
+
+	r, err := newWKBReader(value)
+	if err != nil {
+		return nil, err
+	}
+	return r.readTopGeometry(t)
+}
+
+// readWKB decodes raw WKB or EWKB with geometry of any type.
+func readWKB(b []byte) (PostGISGeometry, error) {
+	r := &wkbReader{b: b}
+	return r.readTopGeometry(0)
+}
+
+// readTopGeometry reads top-level geometry of type t, or of any type if t is zero, and checks that all data was read.
+func (r *wkbReader) readTopGeometry(t uint32) (PostGISGeometry, error) {
+	h, err := r.readExpectedHeader(t)
+	if err != nil {
+		return nil, err
+	}
+	g, err := r.readGeometry(h, h.layout())
+	if err != nil {
+		return nil, err
+	}
+	if err = r.end(); err != nil {
+		return nil, err
+	}
+	if !h.HasSRID {
+		// PostGIS omits SRID 0
+		g = withSRID(g, PostGISUnknownSRID)
+	}
+	return g, nil
+}
+
+// wkbWriter writes EWKB in little-endian (NDR) byte order.
+type wkbWriter struct {
+	b []byte
+}
+
+func (w *wkbWriter) writeUint32(v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	w.b = append(w.b, buf[:]...)
+}
+
+func (w *wkbWriter) writeFloat64(v float64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+	w.b = append(w.b, buf[:]...)
+}
+
+// wkbNoSRID is passed to wkbWriter instead of SRID for geometries written without SRID.
+const wkbNoSRID = -1
+
+// writeHeader writes geometry header with type t and layout flags, and with SRID if it is not wkbNoSRID.
+func (w *wkbWriter) writeHeader(t uint32, layout PostGISLayout, srid int) {
+	if layout.HasZ() {
+		t |= ewkbZ
+	}
+	if layout.HasM() {
+		t |= ewkbM
+	}
+	if srid != wkbNoSRID {
+		t |= ewkbSRID
+	}
+	w.b = append(w.b, 1)
+	w.writeUint32(t)
+	if srid != wkbNoSRID {
+		w.writeUint32(uint32(srid))
+	}
+}
+
+// writePoint writes point coordinates, including Z and M if layout has them.
+func (w *wkbWriter) writePoint(p PostGISPoint, layout PostGISLayout) {
+	w.writeFloat64(p.Lon)
+	w.writeFloat64(p.Lat)
+	if layout.HasZ() {
+		w.writeFloat64(p.Z)
+	}
+	if layout.HasM() {
+		w.writeFloat64(p.M)
+	}
+}
+
+func (w *wkbWriter) writePoints(points []PostGISPoint, layout PostGISLayout) {
+	w.writeUint32(uint32(len(points)))
+	for _, p := range points {
+		w.writePoint(p, layout)
+	}
+}
+
+func (w *wkbWriter) writeRings(rings [][]PostGISPoint, layout PostGISLayout) {
+	w.writeUint32(uint32(len(rings)))
+	for _, r := range rings {
+		w.writePoints(closeRing(r), layout)
+	}
+}
+
+// writeGeometry writes geometry g with the given layout, and with SRID if it is not wkbNoSRID.
+// Nested geometries are written without SRID.
+func (w *wkbWriter) writeGeometry(g PostGISGeometry, layout PostGISLayout, srid int) {
+	switch g := g.(type) {
+	case PostGISPoint:
+		w.writeHeader(wkbPoint, layout, srid)
+		w.writePoint(g, layout)
+	case PostGISLineString:
+		w.writeHeader(wkbLineString, layout, srid)
+		w.writePoints(g.Points, layout)
+	case PostGISPolygon:
+		w.writeHeader(wkbPolygon, layout, srid)
+		w.writeRings(g.Rings(), layout)
+	case PostGISMultiPoint:
+		w.writeHeader(wkbMultiPoint, layout, srid)
+		w.writeUint32(uint32(len(g.Points)))
+		for _, p := range g.Points {
+			w.writeGeometry(p, layout, wkbNoSRID)
+		}
+	case PostGISMultiLineString:
+		w.writeHeader(wkbMultiLineString, layout, srid)
+		w.writeUint32(uint32(len(g.LineStrings)))
+		for _, l := range g.LineStrings {
+			w.writeGeometry(l, layout, wkbNoSRID)
+		}
+	case PostGISMultiPolygon:
+		w.writeHeader(wkbMultiPolygon, layout, srid)
+		w.writeUint32(uint32(len(g.Polygons)))
+		for _, p := range g.Polygons {
+			w.writeGeometry(p, layout, wkbNoSRID)
+		}
+	case PostGISGeometryCollection:
+		w.writeHeader(wkbGeometryCollection, layout, srid)
+		w.writeUint32(uint32(len(g.Geometries)))
+		for _, e := range g.Geometries {
+			w.writeGeometry(e, layout, wkbNoSRID)
+		}
+	default:
+		panic(fmt.Sprintf("unexpected geometry %T", g))
+	}
+}
+
+// marshalEWKB checks SRIDs and layouts of geometry g and returns it as raw little-endian EWKB.
+// SRID is always included, PostGISDefaultSRID for zero.
+func marshalEWKB(g PostGISGeometry) ([]byte, error) {
+	srid, layout, err := checkTopGeometry(g)
+	if err != nil {
+		return nil, err
+	}
+	var w wkbWriter
+	w.writeGeometry(g, layout, srid)
+	return w.b, nil
+}
+
+// end checks that all data was read.
+func (r *wkbReader) end() error {
+	if r.pos != len(r.b) {
+		return r.errorf(r.pos, "unexpected %d bytes after geometry", len(r.b)-r.pos)
+	}
+	return nil
+}
+
+func wkbTypeName(t uint32) string {
+	switch t {
+	case wkbPoint:
+		return "Point"
+	case wkbLineString:
+		return "LineString"
+	case wkbPolygon:
+		return "Polygon"
+	case wkbMultiPoint:
+		return "MultiPoint"
+	case wkbMultiLineString:
+		return "MultiLineString"
+	case wkbMultiPolygon:
+		return "MultiPolygon"
+	case wkbGeometryCollection:
+		return "GeometryCollection"
+	default:
+		return fmt.Sprintf("geometry type %d", t)
+	}
+}
+
+// check interfaces
+var (
+	_ error = &WKBError{}
+)
@@ -0,0 +1,213 @@
+package pq_types
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestWKBReaderHeader(c *C) {
+	type testData struct {
+		hex string
+		h   wkbHeader
+	}
+	for _, d := range []testData{
+		{"0101000000", wkbHeader{Type: wkbPoint}},
+		{"0000000001", wkbHeader{Type: wkbPoint}},
+		{"0103000020E6100000", wkbHeader{Type: wkbPolygon, HasSRID: true, SRID: 4326}},
+		{"0020000003000010E6", wkbHeader{Type: wkbPolygon, HasSRID: true, SRID: 4326}},
+		{"01020000A0110F0000", wkbHeader{Type: wkbLineString, HasZ: true, HasSRID: true, SRID: 3857}},
+		{"0101000040", wkbHeader{Type: wkbPoint, HasM: true}},
+		{"01010000C0", wkbHeader{Type: wkbPoint, HasZ: true, HasM: true}},
+		{"01EA030000", wkbHeader{Type: wkbLineString, HasZ: true}},
+		{"01D3070000", wkbHeader{Type: wkbPolygon, HasM: true}},
+		{"01BF0B0000", wkbHeader{Type: wkbGeometryCollection, HasZ: true, HasM: true}},
+	} {
+		r, err := newWKBReader(d.hex)
+		c.Assert(err, IsNil)
+		h, err := r.readHeader()
+		c.Check(err, IsNil)
+		c.Check(h, DeepEquals, d.h, Commentf("%s", d.hex))
+		c.Check(r.end(), IsNil)
+	}
+
+	for hex, msg := range map[string]string{
+		"":               `invalid WKB at offset 0: unexpected end of data, need 1 bytes, have 0`,
+		"02":             `invalid WKB at offset 0: invalid byte order 0x02`,
+		"010100":         `invalid WKB at offset 1: unexpected end of data, need 4 bytes, have 2`,
+		"0108000000":     `invalid WKB at offset 1: unsupported geometry type 8`,
+		"01A10F0000":     `invalid WKB at offset 1: invalid geometry type 4001`,
+		"0101000020E610": `invalid WKB at offset 5: unexpected end of data, need 4 bytes, have 2`,
+	} {
+		r, err := newWKBReader(hex)
+		c.Assert(err, IsNil)
+		_, err = r.readHeader()
+		c.Check(err, ErrorMatches, msg, Commentf("%s", hex))
+	}
+}
+
+func (s *TypesSuite) TestPostGISPointScanWKB(c *C) {
+	for _, v := range []interface{}{
+		// EWKB, XDR
+		[]byte("0020000001000010E64042CDF01B866E44404BE93471F79421"),
+		// raw EWKB, NDR
+		mustDecodeHex("0101000020E6100000446E861BF0CD42402194F77134E94B40"),
+	} {
+		p := PostGISPoint{Lon: -1, Lat: -1}
+		c.Check(p.Scan(v), IsNil)
+		c.Check(p, DeepEquals, PostGISPoint{Lon: 37.6088900, Lat: 55.8219130})
+	}
+
+	p := PostGISPoint{Lon: -1, Lat: -1}
+	c.Check(p.Scan("0101000020110F0000446E861BF0CD42402194F77134E94B40"), IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: 37.6088900, Lat: 55.8219130, SRID: 3857})
+
+	// WKB, NDR: PostGIS omits SRID 0
+	c.Check(p.Scan("0101000000446E861BF0CD42402194F77134E94B40"), IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: 37.6088900, Lat: 55.8219130, SRID: PostGISUnknownSRID})
+
+	for v, msg := range map[string]string{
+		"0103000020E6100000": `PostGISPoint.Scan: invalid WKB at offset 0: expected Point, got Polygon`,
+		"01E9030000000000000000F03F00000000000000400000000000": `PostGISPoint.Scan: invalid WKB at offset 21: unexpected end of data, need 8 bytes, have 5`,
+		"0101000000446E861BF0CD4240":                           `PostGISPoint.Scan: invalid WKB at offset 13: unexpected end of data, need 8 bytes, have 0`,
+		"0101000000446E861BF0CD42402194F77134E94B4000":         `PostGISPoint.Scan: invalid WKB at offset 21: unexpected 1 bytes after geometry`,
+		"0101000000446E861BF0CD42402194F77134E94B4":            `PostGISPoint.Scan: invalid hex-encoded WKB: .*`,
+	} {
+		c.Check(p.Scan(v), ErrorMatches, msg, Commentf("%s", v))
+	}
+	c.Check(p.Scan(42), ErrorMatches, `PostGISPoint.Scan: expected \[\]byte or string, got int \(42\)`)
+
+	err := p.Scan("0101000000")
+	var wkbErr *WKBError
+	c.Assert(errors.As(err, &wkbErr), Equals, true)
+	c.Check(wkbErr, DeepEquals, &WKBError{Offset: 5, Reason: "unexpected end of data, need 8 bytes, have 0"})
+}
+
+func (s *TypesSuite) TestPostGISPolygonScanWKB(c *C) {
+	var p PostGISPolygon
+	c.Check(p.Scan("00000000030000000100000004000000000000000000000000000000003FF000000000000000000000000000003FF00000000000003FF000000000000000000000000000000000000000000000"), IsNil)
+	c.Check(p, DeepEquals, PostGISPolygon{
+		Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 0}},
+		SRID:   PostGISUnknownSRID,
+	})
+
+	c.Check(p.Scan("010300000000000000"), IsNil)
+	c.Check(p, DeepEquals, PostGISPolygon{SRID: PostGISUnknownSRID})
+
+	for v, msg := range map[string]string{
+		"0103000000FFFFFFFF":                 `PostGISPolygon.Scan: invalid WKB at offset 5: invalid number of rings 4294967295`,
+		"010300000001000000FFFFFF00":         `PostGISPolygon.Scan: invalid WKB at offset 9: invalid number of points 16777215`,
+		"0103000000020000000000000001000000": `PostGISPolygon.Scan: invalid WKB at offset 13: invalid number of points 1`,
+	} {
+		c.Check(p.Scan(v), ErrorMatches, msg, Commentf("%s", v))
+	}
+}
+
+func (s *TypesSuite) TestPostGISEWKB(c *C) {
+	type testData struct {
+		g PostGISGeometry
+		v string
+	}
+	for _, d := range []testData{
+		{PostGISPoint{Lon: 1, Lat: 2}, "0101000020E6100000000000000000F03F0000000000000040"},
+		{PostGISPoint{Lon: 1, Lat: 2, Z: 3, SRID: 3857, Layout: PostGISLayoutXYZ}, "01010000A0110F0000000000000000F03F00000000000000400000000000000840"},
+		{PostGISLineString{}, "0102000020E610000000000000"},
+		{
+			PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2, M: 4}}, Layout: PostGISLayoutXYM},
+			"0104000060E6100000010000000101000040000000000000F03F00000000000000400000000000001040",
+		},
+	} {
+		v, err := PostGISEWKB{Geometry: d.g}.Value()
+		c.Check(err, IsNil)
+		c.Check(v, Equals, d.v)
+	}
+
+	v, err := PostGISEWKB{}.Value()
+	c.Check(err, IsNil)
+	c.Check(v, IsNil)
+	_, err = PostGISEWKB{Geometry: PostGISLineString{Points: []PostGISPoint{{SRID: 3857}}}}.Value()
+	c.Check(err, ErrorMatches, `PostGISEWKB.Value: point 0 has SRID 3857, expected 4326`)
+	_, err = PostGISEWKB{}.MarshalBinary()
+	c.Check(err, ErrorMatches, `PostGISEWKB.MarshalBinary: nil geometry`)
+
+	// coordinates which can't be represented with 8 decimal digits
+	x := 0.1 + 0.2
+	y := math.Nextafter(55.821913, 90)
+	for _, g := range []PostGISGeometry{
+		PostGISPoint{Lon: x, Lat: y},
+		PostGISPoint{Lon: x, Lat: y, Z: 1e-9, M: -x, SRID: 3857, Layout: PostGISLayoutXYZM},
+		PostGISLineString{Points: []PostGISPoint{{Lon: x, Lat: y}, {Lon: -x, Lat: -y}}},
+		PostGISPolygon{
+			Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 0}},
+			Holes:  [][]PostGISPoint{{{Lon: x, Lat: x}, {Lon: 2, Lat: x}, {Lon: 2, Lat: 2}, {Lon: x, Lat: x}}},
+		},
+		PostGISPolygon{},
+		PostGISMultiLineString{LineStrings: []PostGISLineString{{Points: []PostGISPoint{{Lon: x, Lat: y}, {Lon: 1, Lat: 1}}}, {}}},
+		PostGISMultiPolygon{Polygons: []PostGISPolygon{MakeEnvelope(PostGISPoint{Lon: 0, Lat: 0}, PostGISPoint{Lon: x, Lat: y})}},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: x, Lat: y, Z: 3},
+			PostGISGeometryCollection{Geometries: []PostGISGeometry{PostGISLineString{}}},
+		}, SRID: 3857, Layout: PostGISLayoutXYZ},
+	} {
+		v, err := PostGISEWKB{Geometry: g}.Value()
+		c.Check(err, IsNil)
+		var g1 PostGISEWKB
+		c.Check(g1.Scan(v), IsNil)
+		c.Check(g1.Geometry, DeepEquals, g, Commentf("%s", v))
+
+		b, err := PostGISEWKB{Geometry: g}.MarshalBinary()
+		c.Check(err, IsNil)
+		c.Check(b, DeepEquals, mustDecodeHex(v.(string)))
+		var g2 PostGISEWKB
+		c.Check(g2.UnmarshalBinary(b), IsNil)
+		c.Check(g2.Geometry, DeepEquals, g)
+	}
+
+	var g PostGISEWKB
+	c.Check(g.UnmarshalBinary([]byte("0101000000")), ErrorMatches, `PostGISEWKB.UnmarshalBinary: invalid WKB at offset 0: invalid byte order 0x30`)
+	c.Check(g.Scan(42), ErrorMatches, `PostGISEWKB.Scan: expected \[\]byte or string, got int \(42\)`)
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, g := range []PostGISGeometry{
+		PostGISPoint{Lon: x, Lat: y},
+		PostGISPoint{Lon: x, Lat: y, Z: 1e-9, M: -x, SRID: 3857, Layout: PostGISLayoutXYZM},
+		PostGISPolygon{
+			Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 0}},
+			Holes:  [][]PostGISPoint{{{Lon: x, Lat: x}, {Lon: 2, Lat: x}, {Lon: 2, Lat: 2}, {Lon: x, Lat: x}}},
+		},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: x, Lat: y, Z: 3},
+			PostGISMultiPoint{Points: []PostGISPoint{{Lon: -x, Lat: -y, Z: 4}}},
+		}, SRID: 3857, Layout: PostGISLayoutXYZ},
+	} {
+		s.SetUpTest(c)
+
+		_, err := s.db.Exec("INSERT INTO pq_types (geometry) VALUES($1)", PostGISEWKB{Geometry: g})
+		c.Assert(err, IsNil)
+
+		var g1 PostGISEWKB
+		err = s.db.QueryRow("SELECT geometry FROM pq_types").Scan(&g1)
+		c.Check(err, IsNil)
+		c.Check(g1.Geometry, DeepEquals, g)
+
+		var b []byte
+		err = s.db.QueryRow("SELECT ST_AsEWKB(geometry, 'NDR') FROM pq_types").Scan(&b)
+		c.Check(err, IsNil)
+		var g2 PostGISEWKB
+		c.Check(g2.UnmarshalBinary(b), IsNil)
+		c.Check(g2.Geometry, DeepEquals, g)
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,537 @@
+package pq_types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WKTError is returned when WKT or EWKT text can't be parsed.
+type WKTError struct {
+	Offset int    // byte offset in text
+	Reason string // description of the problem
+}
+
+// Error implements error interface.
+func (e *WKTError) Error() string {
+	return fmt.Sprintf("invalid WKT at offset %d: %s", e.Offset, e.Reason)
+}
+
+// wktTypes maps WKT geometry tags to WKB geometry types.
+var wktTypes = map[string]uint32{
+	"POINT":              wkbPoint,
+	"LINESTRING":         wkbLineString,
+	"POLYGON":            wkbPolygon,
+	"MULTIPOINT":         wkbMultiPoint,
+	"MULTILINESTRING":    wkbMultiLineString,
+	"MULTIPOLYGON":       wkbMultiPolygon,
+	"GEOMETRYCOLLECTION": wkbGeometryCollection,
+}
+
+// ParsePostGISWKT parses WKT or EWKT geometry of any type, like outputs of PostGIS ST_AsText and ST_AsEWKT.
+//
+// Tags and EMPTY are case-insensitive. Layout is taken from ISO dimensions ("POINT Z (1 2 3)"),
+// EWKT dimensions ("POINTM(1 2 3)"), or the number of coordinates ("POINT(1 2 3)" is XYZ).
+// Returned geometry SRID follows PostGISPoint rules: PostGISDefaultSRID is stored as zero,
+// and SRID 0 or missing SRID as PostGISUnknownSRID.
+// Errors are of type *WKTError.
+func ParsePostGISWKT(s string) (PostGISGeometry, error) {
+	return parseWKT(s, 0)
+}
+
+// parseWKT parses WKT or EWKT geometry of type t, or of any type if t is zero.
+func parseWKT(s string, t uint32) (PostGISGeometry, error) {
+	p := &wktParser{s: s}
+
+	srid := PostGISUnknownSRID
+	p.skipSpace()
+	if len(s)-p.pos > 5 && strings.EqualFold(s[p.pos:p.pos+5], "SRID=") {
+		p.pos += 5
+		start := p.pos
+		for p.pos < len(s) && s[p.pos] >= '0' && s[p.pos] <= '9' {
+			p.pos++
+		}
+		v, err := strconv.Atoi(s[start:p.pos])
+		if err != nil || v > postGISMaxSRID {
+			return nil, p.errorf(start, "invalid SRID %q", s[start:p.pos])
+		}
+		if err = p.expect(';'); err != nil {
+			return nil, err
+		}
+		if v == PostGISDefaultSRID {
+			srid = 0
+		} else if v != 0 {
+			srid = v
+		}
+	}
+
+	g, err := p.parseGeometry(t, true)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(s) {
+		return nil, p.errorf(p.pos, "unexpected %q after geometry", s[p.pos:])
+	}
+
+	return withSRID(g, srid), nil
+}
+
+// wktParser is a recursive descent WKT parser.
+// Layout is the same for all geometries and positions in the text.
+type wktParser struct {
+	s           string
+	pos         int
+	layout      PostGISLayout
+	layoutKnown bool
+}
+
+func (p *wktParser) errorf(offset int, format string, args ...interface{}) error {
+	return &WKTError{Offset: offset, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek skips spaces and returns the next byte, or zero at the end of text.
+func (p *wktParser) peek() byte {
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// expect skips spaces and reads byte c.
+func (p *wktParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.unexpected(fmt.Sprintf("%q", c))
+	}
+	p.pos++
+	return nil
+}
+
+// unexpected returns error for unexpected data at the current position.
+func (p *wktParser) unexpected(expected string) error {
+	if p.pos == len(p.s) {
+		return p.errorf(p.pos, "expected %s, got end of text", expected)
+	}
+	end := p.pos + 1
+	for end < len(p.s) && isWKTLetter(p.s[end]) && isWKTLetter(p.s[p.pos]) {
+		end++
+	}
+	return p.errorf(p.pos, "expected %s, got %q", expected, p.s[p.pos:end])
+}
+
+// word skips spaces and reads upper-cased word of letters, or returns empty string.
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isWKTLetter(p.s[p.pos]) {
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+func isWKTLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// setLayout sets layout from explicit dimensions at offset.
+func (p *wktParser) setLayout(offset int, l PostGISLayout) error {
+	if p.layoutKnown && p.layout != l {
+		return p.errorf(offset, "mixed dimensions %s and %s", p.layout, l)
+	}
+	p.layout, p.layoutKnown = l, true
+	return nil
+}
+
+// parseTag reads geometry tag with optional dimensions, and optional EMPTY.
+func (p *wktParser) parseTag(expected uint32) (t uint32, empty bool, err error) {
+	offset := p.peekOffset()
+	tag := p.word()
+	if tag == "" {
+		return 0, false, p.unexpected("geometry type")
+	}
+
+	// EWKT dimensions: POINTM, also accept POINTZ and POINTZM
+	var dims string
+	var ok bool
+	if t, ok = wktTypes[tag]; !ok {
+		for _, d := range []string{"ZM", "Z", "M"} {
+			if strings.HasSuffix(tag, d) {
+				if t, ok = wktTypes[strings.TrimSuffix(tag, d)]; ok {
+					dims = d
+					break
+				}
+			}
+		}
+	}
+	if !ok {
+		return 0, false, p.errorf(offset, "unknown geometry type %q", p.s[offset:p.pos])
+	}
+	if expected != 0 && t != expected {
+		return 0, false, p.errorf(offset, "expected %s, got %s", wkbTypeName(expected), wkbTypeName(t))
+	}
+
+	// ISO dimensions
+	wordOffset := p.peekOffset()
+	word := p.word()
+	switch word {
+	case "Z", "M", "ZM":
+		if dims != "" {
+			return 0, false, p.errorf(wordOffset, "unexpected %q after %q", p.s[wordOffset:p.pos], p.s[offset:wordOffset])
+		}
+		dims = word
+		wordOffset = p.peekOffset()
+		word = p.word()
+	}
+	if dims != "" {
+		layout := map[string]PostGISLayout{"Z": PostGISLayoutXYZ, "M": PostGISLayoutXYM, "ZM": PostGISLayoutXYZM}[dims]
+		if err = p.setLayout(offset, layout); err != nil {
+			return 0, false, err
+		}
+	}
+
+	switch word {
+	case "":
+		return t, false, nil
+	case "EMPTY":
+		return t, true, nil
+	default:
+		p.pos = wordOffset
+		return 0, false, p.unexpected(`"(" or "EMPTY"`)
+	}
+}
+
+// peekOffset skips spaces and returns current offset.
+func (p *wktParser) peekOffset() int {
+	p.skipSpace()
+	return p.pos
+}
+
+// parseGeometry parses tagged geometry of type t (any type if t is zero).
+// Layout is set only for top-level geometry.
+func (p *wktParser) parseGeometry(t uint32, top bool) (PostGISGeometry, error) {
+	t, empty, err := p.parseTag(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var g PostGISGeometry
+	switch t {
+	case wkbPoint:
+		var pt PostGISPoint
+		if empty {
+			pt = p.emptyPoint()
+		} else {
+			if err = p.expect('('); err != nil {
+				return nil, err
+			}
+			if pt, err = p.parsePosition(); err != nil {
+				return nil, err
+			}
+			if err = p.expect(')'); err != nil {
+				return nil, err
+			}
+		}
+		if top {
+			pt.Layout = p.layout
+		}
+		g = pt
+
+	case wkbLineString:
+		var l PostGISLineString
+		if !empty {
+			if l.Points, err = p.parsePositions(); err != nil {
+				return nil, err
+			}
+		}
+		if top {
+			l.Layout = p.layout
+		}
+		g = l
+
+	case wkbPolygon:
+		var pl PostGISPolygon
+		if !empty {
+			if pl, err = p.parsePolygon(); err != nil {
+				return nil, err
+			}
+		}
+		if top {
+			pl.Layout = p.layout
+		}
+		g = pl
+
+	case wkbMultiPoint:
+		var mp PostGISMultiPoint
+		if !empty {
+			if mp, err = p.parseMultiPoint(); err != nil {
+				return nil, err
+			}
+		}
+		if top {
+			mp.Layout = p.layout
+		}
+		g = mp
+
+	case wkbMultiLineString:
+		var ml PostGISMultiLineString
+		if !empty {
+			err = p.parseList(func() error {
+				var l PostGISLineString
+				if p.emptyElement() {
+					ml.LineStrings = append(ml.LineStrings, l)
+					return nil
+				}
+				points, err := p.parsePositions()
+				l.Points = points
+				ml.LineStrings = append(ml.LineStrings, l)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if top {
+			ml.Layout = p.layout
+		}
+		g = ml
+
+	case wkbMultiPolygon:
+		var mp PostGISMultiPolygon
+		if !empty {
+			err = p.parseList(func() error {
+				if p.emptyElement() {
+					mp.Polygons = append(mp.Polygons, PostGISPolygon{})
+					return nil
+				}
+				pl, err := p.parsePolygon()
+				mp.Polygons = append(mp.Polygons, pl)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if top {
+			mp.Layout = p.layout
+		}
+		g = mp
+
+	case wkbGeometryCollection:
+		var gc PostGISGeometryCollection
+		if !empty {
+			err = p.parseList(func() error {
+				e, err := p.parseGeometry(0, false)
+				gc.Geometries = append(gc.Geometries, e)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		if top {
+			gc.Layout = p.layout
+		}
+		g = gc
+	}
+	return g, nil
+}
+
+// parseList parses "(" element {"," element} ")" calling parse for each element.
+func (p *wktParser) parseList(parse func() error) error {
+	if err := p.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := parse(); err != nil {
+			return err
+		}
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	return p.expect(')')
+}
+
+// emptyElement reads EMPTY element of multi geometry, if it is the next word.
+func (p *wktParser) emptyElement() bool {
+	offset := p.peekOffset()
+	if p.word() == "EMPTY" {
+		return true
+	}
+	p.pos = offset
+	return false
+}
+
+// emptyPoint returns empty point with NaN coordinates, like POINT EMPTY in WKB.
+func (p *wktParser) emptyPoint() PostGISPoint {
+	pt := PostGISPoint{Lon: math.NaN(), Lat: math.NaN()}
+	if p.layout.HasZ() {
+		pt.Z = math.NaN()
+	}
+	if p.layout.HasM() {
+		pt.M = math.NaN()
+	}
+	return pt
+}
+
+// parsePositions parses "(" position {"," position} ")".
+func (p *wktParser) parsePositions() ([]PostGISPoint, error) {
+	var points []PostGISPoint
+	err := p.parseList(func() error {
+		pt, err := p.parsePosition()
+		points = append(points, pt)
+		return err
+	})
+	return points, err
+}
+
+// parsePolygon parses polygon rings.
+func (p *wktParser) parsePolygon() (PostGISPolygon, error) {
+	var pl PostGISPolygon
+	first := true
+	err := p.parseList(func() error {
+		ring, err := p.parsePositions()
+		if first {
+			pl.Points, first = ring, false
+		} else {
+			pl.Holes = append(pl.Holes, ring)
+		}
+		return err
+	})
+	return pl, err
+}
+
+// parseMultiPoint parses multi point points with or without parentheses: "((1 2),(3 4))" or "(1 2,3 4)".
+// Empty points are allowed: "(EMPTY,(3 4))".
+func (p *wktParser) parseMultiPoint() (PostGISMultiPoint, error) {
+	var mp PostGISMultiPoint
+	err := p.parseList(func() error {
+		if p.emptyElement() {
+			mp.Points = append(mp.Points, p.emptyPoint())
+			return nil
+		}
+		parens := p.peek() == '('
+		if parens {
+			p.pos++
+		}
+		pt, err := p.parsePosition()
+		if err != nil {
+			return err
+		}
+		if parens {
+			if err = p.expect(')'); err != nil {
+				return err
+			}
+		}
+		mp.Points = append(mp.Points, pt)
+		return nil
+	})
+	return mp, err
+}
+
+// parsePosition parses space-separated coordinates and checks their number against layout.
+func (p *wktParser) parsePosition() (PostGISPoint, error) {
+	var pt PostGISPoint
+	offset := p.peekOffset()
+	var coords []float64
+	for {
+		c := p.peek()
+		if c != '-' && c != '+' && c != '.' && (c < '0' || c > '9') {
+			break
+		}
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte("0123456789+-.eE", p.s[p.pos]) >= 0 {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		if err != nil {
+			return pt, p.errorf(start, "invalid number %q", p.s[start:p.pos])
+		}
+		coords = append(coords, v)
+	}
+	if len(coords) == 0 {
+		return pt, p.unexpected("number")
+	}
+
+	if !p.layoutKnown {
+		switch len(coords) {
+		case 2:
+			p.layout = PostGISLayoutXY
+		case 3:
+			p.layout = PostGISLayoutXYZ
+		case 4:
+			p.layout = PostGISLayoutXYZM
+		default:
+			return pt, p.errorf(offset, "expected 2, 3 or 4 coordinates, got %d", len(coords))
+		}
+		p.layoutKnown = true
+	}
+
+	n := 2
+	if p.layout.HasZ() {
+		n++
+	}
+	if p.layout.HasM() {
+		n++
+	}
+	if len(coords) != n {
+		return pt, p.errorf(offset, "expected %d coordinates for %s layout, got %d", n, p.layout, len(coords))
+	}
+
+	pt.Lon, pt.Lat = coords[0], coords[1]
+	switch p.layout {
+	case PostGISLayoutXYZ:
+		pt.Z = coords[2]
+	case PostGISLayoutXYM:
+		pt.M = coords[2]
+	case PostGISLayoutXYZM:
+		pt.Z, pt.M = coords[2], coords[3]
+	}
+	return pt, nil
+}
+
+// parseBox2D parses PostGIS box2d text like "BOX(1 2,3 4)".
+func parseBox2D(s string) (PostGISBox2D, error) {
+	var b PostGISBox2D
+	p := &wktParser{s: s, layoutKnown: true}
+	offset := p.peekOffset()
+	if p.word() != "BOX" {
+		p.pos = offset
+		return b, p.unexpected(`"BOX"`)
+	}
+
+	pointsOffset := p.peekOffset()
+	points, err := p.parsePositions()
+	if err != nil {
+		return b, err
+	}
+	if len(points) != 2 {
+		return b, p.errorf(pointsOffset, "expected 2 points, got %d", len(points))
+	}
+	p.skipSpace()
+	if p.pos != len(s) {
+		return b, p.errorf(p.pos, "unexpected %q after box", s[p.pos:])
+	}
+
+	b.Min, b.Max = points[0], points[1]
+	return b, nil
+}
+
+// check interfaces
+var (
+	_ error = &WKTError{}
+)
@@ -0,0 +1,191 @@
+package pq_types
+
+import (
+	"errors"
+	"math"
+
+	. "gopkg.in/check.v1"
+)
+
+func (s *TypesSuite) TestParsePostGISWKT(c *C) {
+	type testData struct {
+		wkt string
+		g   PostGISGeometry
+	}
+	for _, d := range []testData{
+		{"POINT(1 2)", PostGISPoint{Lon: 1, Lat: 2, SRID: PostGISUnknownSRID}},
+		{" point ( -1.5  2e3 ) ", PostGISPoint{Lon: -1.5, Lat: 2000, SRID: PostGISUnknownSRID}},
+		{"SRID=3857;POINT Z (1 2 3)", PostGISPoint{Lon: 1, Lat: 2, Z: 3, SRID: 3857, Layout: PostGISLayoutXYZ}},
+		{"srid=4326;pointm(1 2 3)", PostGISPoint{Lon: 1, Lat: 2, M: 3, Layout: PostGISLayoutXYM}},
+		{"POINT(1 2 3 4)", PostGISPoint{Lon: 1, Lat: 2, Z: 3, M: 4, SRID: PostGISUnknownSRID, Layout: PostGISLayoutXYZM}},
+		{"LINESTRING EMPTY", PostGISLineString{SRID: PostGISUnknownSRID}},
+		{"LINESTRING ZM EMPTY", PostGISLineString{SRID: PostGISUnknownSRID, Layout: PostGISLayoutXYZM}},
+		{"LINESTRING(0 0, 1 1)", PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}, SRID: PostGISUnknownSRID}},
+		{"POLYGON((0 0,10 0,10 10,0 0),(1 1,2 1,2 2,1 1))", PostGISPolygon{
+			Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 0}},
+			Holes:  [][]PostGISPoint{{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 1, Lat: 1}}},
+			SRID:   PostGISUnknownSRID,
+		}},
+		{"MULTIPOINT(1 2, (3 4))", PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}}, SRID: PostGISUnknownSRID}},
+		{"MULTILINESTRING((0 0,1 1),EMPTY)", PostGISMultiLineString{LineStrings: []PostGISLineString{
+			{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}},
+			{},
+		}, SRID: PostGISUnknownSRID}},
+		{"MULTIPOLYGON(((0 0,1 0,1 1,0 0)),EMPTY)", PostGISMultiPolygon{Polygons: []PostGISPolygon{
+			{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 0}}},
+			{},
+		}, SRID: PostGISUnknownSRID}},
+		{"GEOMETRYCOLLECTION Z (POINT Z (1 2 3),LINESTRING Z EMPTY)", PostGISGeometryCollection{
+			Geometries: []PostGISGeometry{PostGISPoint{Lon: 1, Lat: 2, Z: 3}, PostGISLineString{}},
+			SRID:       PostGISUnknownSRID,
+			Layout:     PostGISLayoutXYZ,
+		}},
+		{"GEOMETRYCOLLECTION(POINT(1 2),GEOMETRYCOLLECTION EMPTY)", PostGISGeometryCollection{
+			Geometries: []PostGISGeometry{PostGISPoint{Lon: 1, Lat: 2}, PostGISGeometryCollection{}},
+			SRID:       PostGISUnknownSRID,
+		}},
+	} {
+		g, err := ParsePostGISWKT(d.wkt)
+		c.Check(err, IsNil, Commentf("%s", d.wkt))
+		c.Check(g, DeepEquals, d.g, Commentf("%s", d.wkt))
+
+		// Value output can be parsed back
+		v, err := d.g.Value()
+		c.Check(err, IsNil)
+		g, err = ParsePostGISWKT(string(v.([]byte)))
+		c.Check(err, IsNil, Commentf("%s", v))
+		c.Check(g, DeepEquals, d.g, Commentf("%s", v))
+	}
+
+	// empty points have NaN coordinates, so they are compared by Value output
+	for wkt, value := range map[string]string{
+		"SRID=4326;POINT EMPTY":                "SRID=4326;POINT EMPTY",
+		"SRID=3857;POINT Z EMPTY":              "SRID=3857;POINT Z EMPTY",
+		"MULTIPOINT(EMPTY, 1 2)":               "SRID=0;MULTIPOINT(EMPTY,(1.00000000 2.00000000))",
+		"MULTIPOINT((1 2),EMPTY)":              "SRID=0;MULTIPOINT((1.00000000 2.00000000),EMPTY)",
+		"GEOMETRYCOLLECTION(POINT EMPTY)":      "SRID=0;GEOMETRYCOLLECTION(POINT EMPTY)",
+		"GEOMETRYCOLLECTION M (POINT M EMPTY)": "SRID=0;GEOMETRYCOLLECTION M (POINT M EMPTY)",
+	} {
+		g, err := ParsePostGISWKT(wkt)
+		c.Check(err, IsNil, Commentf("%s", wkt))
+		v, err := g.Value()
+		c.Check(err, IsNil, Commentf("%s", wkt))
+		c.Check(string(v.([]byte)), Equals, value, Commentf("%s", wkt))
+
+		g, err = ParsePostGISWKT(value)
+		c.Check(err, IsNil, Commentf("%s", value))
+		v, err = g.Value()
+		c.Check(err, IsNil, Commentf("%s", value))
+		c.Check(string(v.([]byte)), Equals, value, Commentf("%s", value))
+	}
+	g, err := ParsePostGISWKT("POINT ZM EMPTY")
+	c.Check(err, IsNil)
+	p := g.(PostGISPoint)
+	c.Check(math.IsNaN(p.Lon) && math.IsNaN(p.Lat) && math.IsNaN(p.Z) && math.IsNaN(p.M), Equals, true)
+	c.Check(p.Layout, Equals, PostGISLayoutXYZM)
+
+	for wkt, msg := range map[string]string{
+		"":                                       `invalid WKT at offset 0: expected geometry type, got end of text`,
+		"POINT(1 2":                              `invalid WKT at offset 9: expected '\)', got end of text`,
+		"POINT 1 2":                              `invalid WKT at offset 6: expected '\(', got "1"`,
+		"POINT Z M (1 2 3)":                      `invalid WKT at offset 8: expected "\(" or "EMPTY", got "M"`,
+		"CIRCLE(1 2)":                            `invalid WKT at offset 0: unknown geometry type "CIRCLE"`,
+		"POINT M (1 2)":                          `invalid WKT at offset 9: expected 3 coordinates for XYM layout, got 2`,
+		"POINT(1)":                               `invalid WKT at offset 6: expected 2, 3 or 4 coordinates, got 1`,
+		"POINT()":                                `invalid WKT at offset 6: expected number, got "\)"`,
+		"LINESTRING(1 2,1 2 3)":                  `invalid WKT at offset 15: expected 2 coordinates for XY layout, got 3`,
+		"GEOMETRYCOLLECTION Z (POINT M (1 2 3))": `invalid WKT at offset 22: mixed dimensions XYZ and XYM`,
+		"SRID=abc;POINT(1 2)":                    `invalid WKT at offset 5: invalid SRID ""`,
+		"SRID=4326 POINT(1 2)":                   `invalid WKT at offset 10: expected ';', got "POINT"`,
+		"POINT(1 2) x":                           `invalid WKT at offset 11: unexpected "x" after geometry`,
+		"POINT(1 2.3.4)":                         `invalid WKT at offset 8: invalid number "2.3.4"`,
+	} {
+		_, err := ParsePostGISWKT(wkt)
+		c.Check(err, ErrorMatches, msg, Commentf("%s", wkt))
+	}
+
+	_, err = ParsePostGISWKT("POINT(1 2,3 4)")
+	var wktErr *WKTError
+	c.Assert(errors.As(err, &wktErr), Equals, true)
+	c.Check(wktErr, DeepEquals, &WKTError{Offset: 9, Reason: `expected ')', got ","`})
+}
+
+func (s *TypesSuite) TestPostGISScanWKT(c *C) {
+	var p PostGISPoint
+	c.Check(p.Scan("POINT(1 2)"), IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: 1, Lat: 2, SRID: PostGISUnknownSRID})
+	c.Check(p.Scan("SRID=4326;POINT(1 2)"), IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: 1, Lat: 2})
+	c.Check(p.Scan([]byte("SRID=3857;POINT(1 2)")), IsNil)
+	c.Check(p, DeepEquals, PostGISPoint{Lon: 1, Lat: 2, SRID: 3857})
+	c.Check(p.Scan("LINESTRING EMPTY"), ErrorMatches, `PostGISPoint.Scan: invalid WKT at offset 0: expected Point, got LineString`)
+
+	var l PostGISLineString
+	c.Check(l.Scan([]byte("LINESTRING(0 0,1 1)")), IsNil)
+	c.Check(l, DeepEquals, PostGISLineString{Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}, SRID: PostGISUnknownSRID})
+
+	var g PostGISAnyGeometry
+	c.Check(g.Scan("MULTIPOINT EMPTY"), IsNil)
+	c.Check(g.Geometry, DeepEquals, PostGISMultiPoint{SRID: PostGISUnknownSRID})
+
+	var b PostGISBox2D
+	c.Check(b.Scan("BOX(1 2,3.5 4)"), IsNil)
+	c.Check(b, DeepEquals, PostGISBox2D{Min: PostGISPoint{Lon: 1, Lat: 2}, Max: PostGISPoint{Lon: 3.5, Lat: 4}})
+	for v, msg := range map[string]string{
+		"BOX(1 2)":         `PostGISBox2D.Scan: invalid WKT at offset 3: expected 2 points, got 1`,
+		"BOX(1 2 3,4 5 6)": `PostGISBox2D.Scan: invalid WKT at offset 4: expected 2 coordinates for XY layout, got 3`,
+		"POINT(1 2)":       `PostGISBox2D.Scan: invalid WKT at offset 0: expected "BOX", got "POINT"`,
+		"BOX(1 2,3 4)x":    `PostGISBox2D.Scan: invalid WKT at offset 12: unexpected "x" after box`,
+	} {
+		c.Check(b.Scan(v), ErrorMatches, msg, Commentf("%s", v))
+	}
+	c.Check(b.Scan(42), ErrorMatches, `PostGISBox2D.Scan: expected \[\]byte or string, got int \(42\)`)
+
+	if s.skipPostGIS {
+		return
+	}
+
+	for _, g := range []PostGISGeometry{
+		PostGISPoint{Lon: 37.60889, Lat: 55.821913},
+		PostGISPoint{Lon: 1, Lat: 2, M: 3, SRID: 3857, Layout: PostGISLayoutXYM},
+		PostGISPolygon{
+			Points: []PostGISPoint{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 0}},
+			Holes:  [][]PostGISPoint{{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}, {Lon: 2, Lat: 2}, {Lon: 1, Lat: 1}}},
+		},
+		PostGISMultiPoint{Points: []PostGISPoint{{Lon: 1, Lat: 2, Z: 3}}, Layout: PostGISLayoutXYZ},
+		PostGISGeometryCollection{Geometries: []PostGISGeometry{
+			PostGISPoint{Lon: 1, Lat: 2},
+			PostGISLineString{},
+			PostGISMultiPolygon{},
+		}},
+	} {
+		s.SetUpTest(c)
+
+		_, err := s.db.Exec("INSERT INTO pq_types (geometry) VALUES($1)", PostGISAnyGeometry{Geometry: g})
+		c.Assert(err, IsNil)
+
+		var g1 PostGISAnyGeometry
+		err = s.db.QueryRow("SELECT ST_AsEWKT(geometry) FROM pq_types").Scan(&g1)
+		c.Check(err, IsNil)
+		c.Check(g1.Geometry, DeepEquals, g)
+
+		err = s.db.QueryRow("SELECT ST_AsText(geometry) FROM pq_types").Scan(&g1)
+		c.Check(err, IsNil)
+		c.Check(g1.Geometry, DeepEquals, withSRID(g, PostGISUnknownSRID))
+	}
+
+	// empty points are the same in text and binary forms
+	empty := PostGISGeometryCollection{Geometries: []PostGISGeometry{PostGISPoint{Lon: math.NaN(), Lat: math.NaN()}}}
+	var text string
+	var fromText, fromBinary PostGISAnyGeometry
+	err := s.db.QueryRow("SELECT ST_AsText($1::geometry), ST_AsEWKT($1::geometry), $1::geometry",
+		PostGISAnyGeometry{Geometry: empty}).Scan(&text, &fromText, &fromBinary)
+	c.Check(err, IsNil)
+	c.Check(text, Equals, "GEOMETRYCOLLECTION(POINT EMPTY)")
+	v1, err := fromText.Value()
+	c.Check(err, IsNil)
+	v2, err := fromBinary.Value()
+	c.Check(err, IsNil)
+	c.Check(v1, DeepEquals, v2)
+	c.Check(string(v1.([]byte)), Equals, "SRID=4326;GEOMETRYCOLLECTION(POINT EMPTY)")
+}
@@ -90,6 +90,7 @@ func (s *TypesSuite) SetUpSuite(c *C) {
 		int32_array int[],
 		int64_array bigint[],
 		jsontext_varchar varchar,
+		compressed_json bytea,
 		null_str varchar,
 		null_int32 int4,
 		null_int64 int8,
@@ -117,7 +118,9 @@ func (s *TypesSuite) SetUpSuite(c *C) {
 		_, err = s.db.Exec(`ALTER TABLE pq_types
 			ADD COLUMN point geography(POINT, 4326),
 			ADD COLUMN box box2d,
-			ADD COLUMN polygon geography(POLYGON, 4326)
+			ADD COLUMN polygon geography(POLYGON, 4326),
+			ADD COLUMN linestring geometry(LINESTRING, 4326),
+			ADD COLUMN geometry geometry
 		`)
 		c.Assert(err, IsNil)
 	} else {